@@ -0,0 +1,143 @@
+package bitfield
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+var (
+	ErrEmptyBitlist     = errors.New("bitfield: bitlist has no bytes")
+	ErrMissingDelimiter = errors.New("bitfield: bitlist has no length delimiter")
+	ErrLengthMismatch   = errors.New("bitfield: lengths do not match")
+	ErrOutOfRange       = errors.New("bitfield: bit index out of range")
+)
+
+/*
+Bitlist is an SSZ Bitlist[N]. Bits are stored least significant bit first, so bit i lives in byte i/8 at
+position i%8, and a single delimiter bit set right after the last bit marks the length of the list
+*/
+type Bitlist []byte
+
+// NewBitlist returns a bitlist of length n with every bit cleared
+func NewBitlist(n uint64) Bitlist {
+	b := make(Bitlist, n/8+1)
+	b[n/8] = 1 << (n % 8)
+	return b
+}
+
+// BitlistFromHex decodes a 0x prefixed hex string as returned by the beacon API
+func BitlistFromHex(s string) (Bitlist, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	b := Bitlist(decoded)
+	if _, err = b.length(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Len returns the number of bits in the list, not counting the delimiter. Malformed lists have length 0
+func (b Bitlist) Len() uint64 {
+	n, _ := b.length()
+	return n
+}
+
+func (b Bitlist) length() (uint64, error) {
+	if len(b) == 0 {
+		return 0, ErrEmptyBitlist
+	}
+	last := b[len(b)-1]
+	if last == 0 {
+		return 0, ErrMissingDelimiter
+	}
+	return uint64(len(b)-1)*8 + uint64(bits.Len8(last)-1), nil
+}
+
+// BitAt reports whether bit i is set, bits past the end of the list are never set
+func (b Bitlist) BitAt(i uint64) bool {
+	if i >= b.Len() {
+		return false
+	}
+	return b[i/8]>>(i%8)&1 == 1
+}
+
+// SetBitAt sets or clears bit i, indexes past the end of the list are ignored
+func (b Bitlist) SetBitAt(i uint64, value bool) {
+	if i >= b.Len() {
+		return
+	}
+	if value {
+		b[i/8] |= 1 << (i % 8)
+	} else {
+		b[i/8] &^= 1 << (i % 8)
+	}
+}
+
+// Count returns the number of set bits, leaving out the delimiter
+func (b Bitlist) Count() uint64 {
+	if b.Len() == 0 {
+		return 0
+	}
+	count := 0
+	for _, v := range b {
+		count += bits.OnesCount8(v)
+	}
+	return uint64(count - 1)
+}
+
+// Or returns a new bitlist with every bit set that is set in either list. Both lists must have the same length
+func (b Bitlist) Or(c Bitlist) (Bitlist, error) {
+	n, err := b.length()
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.length()
+	if err != nil {
+		return nil, err
+	}
+	if n != m {
+		return nil, fmt.Errorf("%w: %d and %d", ErrLengthMismatch, n, m)
+	}
+	result := make(Bitlist, len(b))
+	for i := range b {
+		result[i] = b[i] | c[i]
+	}
+	return result, nil
+}
+
+/*
+Validate checks that the bitlist is well formed and holds exactly one bit per member of a committee of
+the given size. A well formed list has no trailing zero bytes and its delimiter is the highest set bit
+*/
+func (b Bitlist) Validate(committeeSize uint64) error {
+	n, err := b.length()
+	if err != nil {
+		return err
+	}
+	if n != committeeSize {
+		return fmt.Errorf("%w: bitlist has %d bits for a committee of %d", ErrLengthMismatch, n, committeeSize)
+	}
+	return nil
+}
+
+// Indices returns the positions of all set bits in increasing order
+func (b Bitlist) Indices() []uint64 {
+	n := b.Len()
+	indices := make([]uint64, 0, b.Count())
+	for i := uint64(0); i < n; i++ {
+		if b.BitAt(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Hex returns the 0x prefixed hex encoding used by the beacon API
+func (b Bitlist) Hex() string {
+	return "0x" + hex.EncodeToString(b)
+}
@@ -0,0 +1,197 @@
+package bitfield
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/quick"
+)
+
+func TestBitlistKnownValues(t *testing.T) {
+	// 0x0d = 0b00001101: bits 0 and 2 set, delimiter at bit 3
+	b, err := BitlistFromHex("0x0d")
+	if err != nil {
+		t.Fatalf("failed to decode bitlist: %v", err)
+	}
+	if b.Len() != 3 {
+		t.Errorf("expected length 3, got %d", b.Len())
+	}
+	if !b.BitAt(0) || b.BitAt(1) || !b.BitAt(2) {
+		t.Errorf("unexpected bits in %s", b.Hex())
+	}
+	if b.Count() != 2 {
+		t.Errorf("expected 2 set bits, got %d", b.Count())
+	}
+
+	// A committee of 8 needs a second byte for the delimiter
+	b, err = BitlistFromHex("0x8101")
+	if err != nil {
+		t.Fatalf("failed to decode bitlist: %v", err)
+	}
+	if b.Len() != 8 || !b.BitAt(0) || !b.BitAt(7) || b.Count() != 2 {
+		t.Errorf("unexpected bitlist %s: length %d, count %d", b.Hex(), b.Len(), b.Count())
+	}
+	if err = b.Validate(8); err != nil {
+		t.Errorf("expected bitlist to fit a committee of 8: %v", err)
+	}
+	if err = b.Validate(9); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("expected a length mismatch for a committee of 9, got %v", err)
+	}
+}
+
+func TestBitlistMalformed(t *testing.T) {
+	for _, input := range []string{"0x", "0x00", "0x0100"} {
+		_, err := BitlistFromHex(input)
+		if err == nil {
+			t.Errorf("expected %s to be rejected", input)
+		}
+	}
+}
+
+func TestBitlistOrLengthMismatch(t *testing.T) {
+	_, err := NewBitlist(8).Or(NewBitlist(9))
+	if !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("expected a length mismatch, got %v", err)
+	}
+}
+
+func TestBitlistProperties(t *testing.T) {
+	config := &quick.Config{MaxCount: 500}
+
+	// Setting bits from a set of positions yields a list of the requested length with exactly those bits set
+	setThenRead := func(size uint16, positions []uint16) bool {
+		n := uint64(size)
+		b := NewBitlist(n)
+		expected := make(map[uint64]bool)
+		for _, p := range positions {
+			if n == 0 {
+				break
+			}
+			i := uint64(p) % n
+			b.SetBitAt(i, true)
+			expected[i] = true
+		}
+		if b.Len() != n || b.Validate(n) != nil || b.Count() != uint64(len(expected)) {
+			return false
+		}
+		for i := uint64(0); i < n; i++ {
+			if b.BitAt(i) != expected[i] {
+				return false
+			}
+		}
+		return uint64(len(b.Indices())) == b.Count()
+	}
+	if err := quick.Check(setThenRead, config); err != nil {
+		t.Error(err)
+	}
+
+	// OR keeps the length, is commutative and never loses a bit of either side
+	orProperties := func(size uint16, left []uint16, right []uint16) bool {
+		n := uint64(size)
+		a, b := NewBitlist(n), NewBitlist(n)
+		for _, p := range left {
+			a.SetBitAt(uint64(p)%(n+1), true)
+		}
+		for _, p := range right {
+			b.SetBitAt(uint64(p)%(n+1), true)
+		}
+		ab, err := a.Or(b)
+		if err != nil {
+			return false
+		}
+		ba, err := b.Or(a)
+		if err != nil || !bytes.Equal(ab, ba) || ab.Len() != n {
+			return false
+		}
+		for i := uint64(0); i < n; i++ {
+			if ab.BitAt(i) != (a.BitAt(i) || b.BitAt(i)) {
+				return false
+			}
+		}
+		return ab.Count() >= a.Count() && ab.Count() >= b.Count() && ab.Count() <= a.Count()+b.Count()
+	}
+	if err := quick.Check(orProperties, config); err != nil {
+		t.Error(err)
+	}
+
+	// Hex encoding round trips
+	hexRoundTrip := func(size uint16, positions []uint16) bool {
+		n := uint64(size)
+		b := NewBitlist(n)
+		for _, p := range positions {
+			b.SetBitAt(uint64(p)%(n+1), true)
+		}
+		decoded, err := BitlistFromHex(b.Hex())
+		return err == nil && bytes.Equal(decoded, b)
+	}
+	if err := quick.Check(hexRoundTrip, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBitvectorProperties(t *testing.T) {
+	setThenRead := func(positions []uint8) bool {
+		b := NewBitvector(64)
+		expected := make(map[uint64]bool)
+		for _, p := range positions {
+			i := uint64(p) % 64
+			b.SetBitAt(i, true)
+			expected[i] = true
+		}
+		if b.Validate(64) != nil || b.Count() != uint64(len(expected)) {
+			return false
+		}
+		for i := uint64(0); i < 64; i++ {
+			if b.BitAt(i) != expected[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(setThenRead, nil); err != nil {
+		t.Error(err)
+	}
+
+	b := NewBitvector(12)
+	b[1] = 0x10
+	if err := b.Validate(12); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("expected bit 12 of a 12 bit vector to be rejected, got %v", err)
+	}
+}
+
+func FuzzBitlist(f *testing.F) {
+	f.Add([]byte{0x01}, []byte{0x01})
+	f.Add([]byte{0x0d}, []byte{0x0b})
+	f.Add([]byte{0xff, 0x01}, []byte{0x00, 0x01})
+	f.Add([]byte{0x00}, []byte{})
+	f.Fuzz(func(t *testing.T, left []byte, right []byte) {
+		a, b := Bitlist(left), Bitlist(right)
+		_, err := a.length()
+		if err != nil {
+			// Malformed input must never panic and always reads as empty
+			if a.Len() != 0 || a.Count() != 0 || a.BitAt(0) {
+				t.Fatalf("malformed bitlist %x reads as non empty", left)
+			}
+			return
+		}
+		n := a.Len()
+		if a.Validate(n) != nil {
+			t.Fatalf("bitlist %x does not validate against its own length %d", left, n)
+		}
+		if uint64(len(a.Indices())) != a.Count() {
+			t.Fatalf("indices and count disagree for %x", left)
+		}
+		merged, err := a.Or(b)
+		if err != nil {
+			if b.Len() == n && len(right) > 0 && right[len(right)-1] != 0 {
+				t.Fatalf("or of two lists of length %d failed: %v", n, err)
+			}
+			return
+		}
+		for i := uint64(0); i < n; i++ {
+			if merged.BitAt(i) != (a.BitAt(i) || b.BitAt(i)) {
+				t.Fatalf("or of %x and %x lost bit %d", left, right, i)
+			}
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package bitfield
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+/*
+Bitvector is an SSZ Bitvector[N]. Unlike a bitlist it has no delimiter, its length is fixed by the type,
+so the length has to be passed whenever it matters. Bits are stored least significant bit first
+*/
+type Bitvector []byte
+
+// NewBitvector returns a bitvector of length n with every bit cleared
+func NewBitvector(n uint64) Bitvector {
+	return make(Bitvector, (n+7)/8)
+}
+
+// BitvectorFromHex decodes a 0x prefixed hex string and validates it against the length n
+func BitvectorFromHex(s string, n uint64) (Bitvector, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	b := Bitvector(decoded)
+	if err = b.Validate(n); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Len returns the number of bits the vector can hold
+func (b Bitvector) Len() uint64 {
+	return uint64(len(b)) * 8
+}
+
+// BitAt reports whether bit i is set
+func (b Bitvector) BitAt(i uint64) bool {
+	if i >= b.Len() {
+		return false
+	}
+	return b[i/8]>>(i%8)&1 == 1
+}
+
+// SetBitAt sets or clears bit i, indexes past the end of the vector are ignored
+func (b Bitvector) SetBitAt(i uint64, value bool) {
+	if i >= b.Len() {
+		return
+	}
+	if value {
+		b[i/8] |= 1 << (i % 8)
+	} else {
+		b[i/8] &^= 1 << (i % 8)
+	}
+}
+
+// Count returns the number of set bits
+func (b Bitvector) Count() uint64 {
+	count := 0
+	for _, v := range b {
+		count += bits.OnesCount8(v)
+	}
+	return uint64(count)
+}
+
+// Or returns a new bitvector with every bit set that is set in either vector
+func (b Bitvector) Or(c Bitvector) (Bitvector, error) {
+	if len(b) != len(c) {
+		return nil, fmt.Errorf("%w: %d and %d bytes", ErrLengthMismatch, len(b), len(c))
+	}
+	result := make(Bitvector, len(b))
+	for i := range b {
+		result[i] = b[i] | c[i]
+	}
+	return result, nil
+}
+
+// Validate checks that the vector has the byte length of a Bitvector[n] and no bits set past n
+func (b Bitvector) Validate(n uint64) error {
+	if uint64(len(b)) != (n+7)/8 {
+		return fmt.Errorf("%w: %d bytes for a bitvector of %d bits", ErrLengthMismatch, len(b), n)
+	}
+	for i := n; i < b.Len(); i++ {
+		if b.BitAt(i) {
+			return fmt.Errorf("%w: bit %d is set in a bitvector of %d bits", ErrOutOfRange, i, n)
+		}
+	}
+	return nil
+}
+
+// Indices returns the positions of all set bits in increasing order
+func (b Bitvector) Indices() []uint64 {
+	indices := make([]uint64, 0, b.Count())
+	for i := uint64(0); i < b.Len(); i++ {
+		if b.BitAt(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Hex returns the 0x prefixed hex encoding used by the beacon API
+func (b Bitvector) Hex() string {
+	return "0x" + hex.EncodeToString(b)
+}
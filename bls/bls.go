@@ -0,0 +1,31 @@
+package bls
+
+import (
+	"errors"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// Ethereum signs with public keys in G1 and signatures in G2, using the proof of possession scheme
+var dst = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+var (
+	ErrInvalidPublicKey = errors.New("bls: invalid public key")
+	ErrInvalidSignature = errors.New("bls: invalid signature")
+)
+
+/*
+This function verifies a signature over a signing root. Keys and signatures that do not decode to points
+of the right subgroup are returned as errors, a well formed signature that does not match returns false
+*/
+func Verify(publicKey [48]byte, signingRoot [32]byte, signature [96]byte) (bool, error) {
+	pk := new(blst.P1Affine).Uncompress(publicKey[:])
+	if pk == nil || !pk.KeyValidate() {
+		return false, ErrInvalidPublicKey
+	}
+	sig := new(blst.P2Affine).Uncompress(signature[:])
+	if sig == nil || !sig.SigValidate(false) {
+		return false, ErrInvalidSignature
+	}
+	return sig.Verify(false, pk, false, signingRoot[:], dst), nil
+}
@@ -0,0 +1,46 @@
+package bls
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+func sign(t *testing.T, seed string, message [32]byte) ([48]byte, [96]byte) {
+	ikm := sha256.Sum256([]byte(seed))
+	sk := blst.KeyGen(ikm[:])
+	var publicKey [48]byte
+	var signature [96]byte
+	copy(publicKey[:], new(blst.P1Affine).From(sk).Compress())
+	copy(signature[:], new(blst.P2Affine).Sign(sk, message[:], dst).Compress())
+	return publicKey, signature
+}
+
+func TestVerify(t *testing.T) {
+	message := sha256.Sum256([]byte("signing root"))
+	publicKey, signature := sign(t, "proposer", message)
+
+	ok, err := Verify(publicKey, message, signature)
+	if err != nil || !ok {
+		t.Fatalf("expected the signature to verify, got %v, %v", ok, err)
+	}
+
+	tampered := message
+	tampered[0] ^= 1
+	if ok, err = Verify(publicKey, tampered, signature); err != nil || ok {
+		t.Errorf("expected a signature over another root to fail, got %v, %v", ok, err)
+	}
+
+	otherKey, _ := sign(t, "someone else", message)
+	if ok, err = Verify(otherKey, message, signature); err != nil || ok {
+		t.Errorf("expected a signature by another key to fail, got %v, %v", ok, err)
+	}
+
+	var garbage [96]byte
+	garbage[0] = 0xff
+	if _, err = Verify(publicKey, message, garbage); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected an invalid signature error, got %v", err)
+	}
+}
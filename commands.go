@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-beacon-chain-indexer/db"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/service"
+	"os"
+)
+
+/*
+This function runs a command passed on the command line instead of starting the server and returns the
+exit code. Commands are: verify, consistency, import, migrate, prune, repair, snapshot
+*/
+func runCommand(name string, args []string, s *service.Service, storage db.Storage) int {
+	switch name {
+	case "verify":
+		return runVerify(args, s)
+	case "consistency":
+		return runConsistency(args, s)
+	case "import":
+		return runImport(args, s)
+	case "migrate":
+		return runMigrate(args, storage)
+	case "prune":
+		return runPrune(s)
+	case "repair":
+		return runRepair(args, s)
+	case "snapshot":
+		return runSnapshot(args, storage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		return 2
+	}
+}
+
+/*
+This function verifies the indexed range, with --signatures also the proposer signatures, and prints
+the report as JSON. The exit code is 1 when an integrity error was found
+*/
+func runVerify(args []string, s *service.Service) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fromSlot := flags.Int64("from", -1, "first slot to verify, defaults to the first indexed slot")
+	toSlot := flags.Int64("to", -1, "last slot to verify, defaults to the last indexed slot")
+	signatures := flags.Bool("signatures", false, "also verify the proposer signature of every header")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	firstIndexed, lastIndexed, err := s.IndexedSlotRange()
+	if err != nil {
+		return 1
+	}
+	if *fromSlot < 0 {
+		*fromSlot = firstIndexed
+	}
+	if *toSlot < 0 {
+		*toSlot = lastIndexed
+	}
+	if *signatures {
+		// The state of the trusted checkpoint and the fork schedule are fetched from the beacon nodes
+		s.StartHealthChecks()
+	}
+	report, err := s.VerifyRange(*fromSlot, *toSlot, *signatures)
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	if len(report.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+/*
+This function compares every configured beacon node over the slot range and prints the discrepancy report as
+JSON. The exit code is 1 when the nodes disagree on anything
+*/
+func runConsistency(args []string, s *service.Service) int {
+	flags := flag.NewFlagSet("consistency", flag.ContinueOnError)
+	fromSlot := flags.Int64("from", -1, "first slot to compare")
+	toSlot := flags.Int64("to", -1, "last slot to compare, defaults to the first slot")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *fromSlot < 0 {
+		fmt.Fprintln(os.Stderr, "-from is required")
+		return 2
+	}
+	if *toSlot < 0 {
+		*toSlot = *fromSlot
+	}
+	s.StartHealthChecks()
+	report, err := s.CompareNodes(*fromSlot, *toSlot)
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	if len(report.Discrepancies) > 0 {
+		return 1
+	}
+	return 0
+}
+
+/*
+This function imports the era files of a directory and prints what was imported from each file as JSON. The
+exit code is 1 when a file failed to import
+*/
+func runImport(args []string, s *service.Service) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dir := flags.String("dir", "", "directory holding the .era files")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "-dir is required")
+		return 2
+	}
+	report, err := s.ImportEraDirectory(*dir)
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	for _, file := range report.Files {
+		if file.Status == "failed" {
+			return 1
+		}
+	}
+	return 0
+}
+
+/*
+This function applies the pending migrations with up, reverts the latest ones with down -steps N and only
+lists them with status. The status of every migration is printed as JSON afterwards
+*/
+func runMigrate(args []string, storage db.Storage) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|down [-steps N]|status")
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "up":
+		_, err = storage.MigrateUp()
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert, latest first")
+		if err = flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		_, err = storage.MigrateDown(*steps)
+	case "status":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action %q\n", args[0])
+		return 2
+	}
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	statuses, err := storage.MigrationStatus()
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(statuses); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	return 0
+}
+
+/*
+This function applies the retention and compression policy of the environment once and prints what was pruned as JSON
+*/
+func runPrune(s *service.Service) int {
+	report, err := s.Prune()
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	return 0
+}
+
+/*
+This function fetches the missing slots of a range again, with -dry-run it only finds them, and prints the
+completeness summary as JSON. The exit code is 1 when slots are still missing
+*/
+func runRepair(args []string, s *service.Service) int {
+	flags := flag.NewFlagSet("repair", flag.ContinueOnError)
+	fromSlot := flags.Int64("from", -1, "first slot to repair, defaults to the first indexed slot")
+	toSlot := flags.Int64("to", -1, "last slot to repair, defaults to the last indexed slot")
+	dryRun := flags.Bool("dry-run", false, "only report the missing slots")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !*dryRun {
+		s.StartHealthChecks()
+	}
+	report, err := s.Repair(*fromSlot, *toSlot, *dryRun)
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	if report.StillMissingCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+/*
+This function writes the stored epochs of a range to a snapshot file with export, or loads one with import,
+and prints what was exported or imported as JSON. An export is written next to the file and only renamed to
+it once complete, so a failed export never leaves a partial snapshot behind
+*/
+func runSnapshot(args []string, storage db.Storage) int {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintln(os.Stderr, "usage: snapshot export -file PATH [-from EPOCH] [-to EPOCH]|import -file PATH")
+		return 2
+	}
+	flags := flag.NewFlagSet("snapshot "+args[0], flag.ContinueOnError)
+	path := flags.String("file", "", "snapshot file")
+	fromEpoch := flags.Int64("from", -1, "first epoch to export, defaults to the first indexed epoch")
+	toEpoch := flags.Int64("to", -1, "last epoch to export, defaults to the indexing cursor")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		return 2
+	}
+
+	var report *db.SnapshotReport
+	var err error
+	if args[0] == "export" {
+		report, err = exportSnapshot(storage, *path, *fromEpoch, *toEpoch)
+	} else {
+		var file *os.File
+		file, err = os.Open(*path)
+		if err == nil {
+			report, err = db.ImportSnapshot(storage, file)
+			file.Close()
+		}
+	}
+	if err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		logger.LogError(err)
+		return 1
+	}
+	return 0
+}
+
+func exportSnapshot(storage db.Storage, path string, fromEpoch int64, toEpoch int64) (*db.SnapshotReport, error) {
+	file, err := os.Create(path + ".partial")
+	if err != nil {
+		return nil, err
+	}
+	report, err := db.ExportSnapshot(storage, file, fromEpoch, toEpoch)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".partial")
+		return nil, err
+	}
+	return report, os.Rename(path+".partial", path)
+}
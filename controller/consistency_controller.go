@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"go-beacon-chain-indexer/service"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+const defaultConsistencyMaxSlots = 64
+
+type ConsistencyController struct {
+	s *service.Service
+}
+
+func NewConsistencyController(service *service.Service) *ConsistencyController {
+	return &ConsistencyController{
+		s: service,
+	}
+}
+
+/*
+This handler compares the configured beacon nodes over the slots from and to, both inclusive, and sends back
+the discrepancy report in json. Every slot costs a few requests per node, so the range is capped by
+CONSISTENCY_MAX_SLOTS
+*/
+func (c *ConsistencyController) GetConsistencyReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fromSlot, fromErr := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	toSlot, toErr := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if fromErr != nil || toErr != nil || fromSlot < 0 || toSlot < fromSlot {
+		http.Error(w, "from and to query parameters are a must and they must be slots with from <= to: from=$slot&to=$slot", http.StatusBadRequest)
+		return
+	}
+	maxSlots, err := strconv.ParseInt(os.Getenv("CONSISTENCY_MAX_SLOTS"), 10, 64)
+	if err != nil || maxSlots <= 0 {
+		maxSlots = defaultConsistencyMaxSlots
+	}
+	if toSlot-fromSlot+1 > maxSlots {
+		http.Error(w, "At most "+strconv.FormatInt(maxSlots, 10)+" slots can be compared at once", http.StatusBadRequest)
+		return
+	}
+	report, err := c.s.CompareNodes(fromSlot, toSlot)
+	if errors.Is(err, service.ErrTooFewNodes) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		handleInternalServerError(err, w)
+		return
+	}
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		handleInternalServerError(err, w)
+	}
+}
@@ -1,24 +1,20 @@
 package controller
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	db "go-beacon-chain-indexer/db"
 	"go-beacon-chain-indexer/logger"
-	"go-beacon-chain-indexer/model"
 	"net/http"
-	"strconv"
-
-	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type EpochController struct {
-	db *db.Database
+	db db.Storage
 }
 
-func NewEpochController(Pool *pgxpool.Pool) *EpochController {
+func NewEpochController(storage db.Storage) *EpochController {
 	return &EpochController{
-		db: db.NewDatabase(Pool),
+		db: storage,
 	}
 }
 
@@ -43,47 +39,21 @@ func (c *EpochController) GetData(w http.ResponseWriter, r *http.Request) {
 		paramName = name
 	}
 
-	sqlQuery := "SELECT * FROM beacon_chain_data"
+	var filter *db.DataFilter
 	if paramValue != "" {
-		sqlQuery += " WHERE " + paramName + " = " + paramValue
-		sqlQuery += " ORDER BY " + paramName + " DESC"
-	} else {
-		sqlQuery += " ORDER BY slot DESC"
+		var err error
+		filter, err = db.NewDataFilter(paramName, paramValue)
+		if errors.Is(err, db.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
-
-	rows, err := c.db.Pool.Query(context.Background(), sqlQuery)
+	data, err := c.db.GetData(filter)
 	if err != nil {
 		logger.LogError(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var data []model.BeaconChainData
-	var slot int64
-	for rows.Next() {
-		var beaconData model.BeaconChainData
-		err = rows.Scan(
-			&slot,
-			&beaconData.Data.Root,
-			&beaconData.Data.Canonical,
-			&beaconData.Data.Header.Message.ProposerIndex,
-			&beaconData.Data.Header.Message.ParentRoot,
-			&beaconData.Data.Header.Message.StateRoot,
-			&beaconData.Data.Header.Message.BodyRoot,
-			&beaconData.Data.Header.Signature,
-			&beaconData.Data.UnixTime,
-			&beaconData.Epoch,
-		)
-		beaconData.Data.Header.Message.Slot = strconv.FormatInt(slot, 10)
-		if err != nil {
-			logger.LogError(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		data = append(data, beaconData)
-	}
 	w.Header().Set("Content-Type", "application/json")
 
 	//Add("Content-Type" : "application/json")
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	db "go-beacon-chain-indexer/db"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultStatsLimit = 100
+	maxStatsLimit     = 1000
+)
+
+type StatsController struct {
+	db db.Storage
+}
+
+func NewStatsController(storage db.Storage) *StatsController {
+	return &StatsController{
+		db: storage,
+	}
+}
+
+/*
+This handler sends back the blocks proposed, missed slots and average participation of the latest epoch, hour
+or day buckets as json, latest first. bucket defaults to epoch and limit to 100 buckets
+*/
+func (c *StatsController) GetStats(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "epoch"
+	}
+	limit := defaultStatsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxStatsLimit {
+			http.Error(w, "limit must be a number of buckets between 1 and "+strconv.Itoa(maxStatsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	stats, err := c.db.GetStats(bucket, limit)
+	if errors.Is(err, db.ErrInvalidBucket) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		handleInternalServerError(err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		handleInternalServerError(err, w)
+	}
+}
@@ -0,0 +1,304 @@
+package db
+
+import (
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"sort"
+	"sync"
+)
+
+/*
+Memory keeps everything in maps for the lifetime of the process. It needs no database at all, which makes
+it the backend of choice for tests and for trying the indexer out
+*/
+type Memory struct {
+	mutex         sync.RWMutex
+	data          map[int64]model.BeaconChainData
+	participation map[int64]model.EpochParticipation
+	committees    map[committeeKey]model.CommitteeParticipation
+	missed        map[int64]bool
+	cursor        int64
+}
+
+// Committees are keyed by slot and committee index like the committees and attestations tables
+type committeeKey struct {
+	slot  int64
+	index int64
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		data:          make(map[int64]model.BeaconChainData),
+		participation: make(map[int64]model.EpochParticipation),
+		committees:    make(map[committeeKey]model.CommitteeParticipation),
+		missed:        make(map[int64]bool),
+		cursor:        -1,
+	}
+}
+
+func (db *Memory) Close() {}
+
+/*
+This method upserts the header rows of an epoch and advances the indexing cursor once every slot of the epoch
+is stored and it follows the cursor. Rows are checked before any is stored, so an epoch with an invalid row stores none of them like
+the transactions of the other backends
+*/
+func (db *Memory) InsertEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error) {
+	return db.writeEpochData(epoch, rows, missedSlots, true)
+}
+
+func (db *Memory) ImportEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error) {
+	return db.writeEpochData(epoch, rows, missedSlots, false)
+}
+
+func (db *Memory) writeEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64, advanceCursor bool) (WriteCounts, error) {
+	counts := WriteCounts{Epoch: epoch}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	slots := make([]int64, len(rows))
+	for i := range rows {
+		// Rows are checked like they are converted for the columns of the other backends
+		values, err := beaconDataValues(&rows[i])
+		if err != nil {
+			return counts, err
+		}
+		slots[i] = values[0].(int64)
+	}
+	for i, slot := range slots {
+		row := rows[i]
+		row.ExecutionOptimistic, row.Finalized = false, false
+		stored, ok := db.data[slot]
+		counts.count(!ok, stored != row)
+		db.data[slot] = row
+		delete(db.missed, slot)
+	}
+	for _, slot := range missedSlots {
+		db.missed[slot] = true
+	}
+	if !advanceCursor {
+		return counts, nil
+	}
+	covered := int64(0)
+	for slot := epoch * spec.SlotsPerEpoch; slot < (epoch+1)*spec.SlotsPerEpoch; slot++ {
+		if _, ok := db.data[slot]; ok || db.missed[slot] {
+			covered++
+		}
+	}
+	if covered == spec.SlotsPerEpoch && (db.cursor < 0 || epoch == db.cursor+1) {
+		db.cursor = epoch
+	}
+	return counts, nil
+}
+
+func (db *Memory) GetIndexingCursor() (int64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	return db.cursor, nil
+}
+
+func (db *Memory) DeleteData() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.data = make(map[int64]model.BeaconChainData)
+	db.missed = make(map[int64]bool)
+	db.cursor = -1
+	return nil
+}
+
+func (db *Memory) GetData(filter *DataFilter) ([]model.BeaconChainData, error) {
+	rows := db.rows(func(slot int64, row *model.BeaconChainData) bool { return filter.matches(row) })
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	return rows, nil
+}
+
+func (db *Memory) GetIndexedData(fromSlot int64, toSlot int64) ([]model.BeaconChainData, error) {
+	return db.rows(func(slot int64, _ *model.BeaconChainData) bool { return slot >= fromSlot && slot <= toSlot }), nil
+}
+
+// rows returns the rows that pass the predicate in slot order
+func (db *Memory) rows(include func(slot int64, row *model.BeaconChainData) bool) []model.BeaconChainData {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	var rows []model.BeaconChainData
+	for _, slot := range db.sortedSlots() {
+		row := db.data[slot]
+		if include(slot, &row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (db *Memory) sortedSlots() []int64 {
+	slots := make([]int64, 0, len(db.data))
+	for slot := range db.data {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	return slots
+}
+
+func (db *Memory) GetIndexedSlotRange() (int64, int64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	fromSlot, toSlot := int64(-1), int64(-1)
+	for slot := range db.data {
+		if fromSlot == -1 || slot < fromSlot {
+			fromSlot = slot
+		}
+		if slot > toSlot {
+			toSlot = slot
+		}
+	}
+	return fromSlot, toSlot, nil
+}
+
+func (db *Memory) GetCanonicalRootBefore(slot int64) (string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	root, rootSlot := "", int64(-1)
+	for stored, row := range db.data {
+		if stored < slot && stored > rootSlot && row.Data.Canonical {
+			root, rootSlot = row.Data.Root, stored
+		}
+	}
+	return root, nil
+}
+
+func (db *Memory) GetProposers(epoch int64) (map[int64]string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	proposers := make(map[int64]string)
+	for slot, row := range db.data {
+		if row.Epoch == epoch {
+			proposers[slot] = row.Data.Header.Message.ProposerIndex
+		}
+	}
+	return proposers, nil
+}
+
+/*
+This method upserts the participation of an epoch, counting a committee with aggregation bits as two rows like
+the committees and attestations tables of the other backends
+*/
+func (db *Memory) InsertEpochParticipation(participation *model.EpochParticipation, committees []model.CommitteeParticipation) (WriteCounts, error) {
+	counts := WriteCounts{Epoch: participation.Epoch}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	for _, committee := range committees {
+		key := committeeKey{slot: committee.Slot, index: committee.CommitteeIndex}
+		stored, ok := db.committees[key]
+		counts.count(!ok, stored.Epoch != committee.Epoch || stored.CommitteeSize != committee.CommitteeSize)
+		if committee.AggregationBits != "" {
+			attested := ok && stored.AggregationBits != ""
+			counts.count(!attested, stored.AggregationBits != committee.AggregationBits || stored.Participants != committee.Participants || stored.InclusionSlot != committee.InclusionSlot)
+		} else if ok {
+			// Like the attestations table, a stored attestation is kept when a committee is written without one
+			committee.AggregationBits, committee.Participants, committee.InclusionSlot = stored.AggregationBits, stored.Participants, stored.InclusionSlot
+		}
+		db.committees[key] = committee
+	}
+	stored, ok := db.participation[participation.Epoch]
+	counts.count(!ok, stored != *participation)
+	db.participation[participation.Epoch] = *participation
+	return counts, nil
+}
+
+func (db *Memory) GetEpochParticipation(epoch int64) (*model.EpochParticipation, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	participation, ok := db.participation[epoch]
+	if !ok {
+		return nil, nil
+	}
+	return &participation, nil
+}
+
+func (db *Memory) GetCommittees(epoch int64) ([]model.CommitteeParticipation, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	committees := []model.CommitteeParticipation{}
+	for _, committee := range db.committees {
+		if committee.Epoch == epoch {
+			committees = append(committees, committee)
+		}
+	}
+	sort.Slice(committees, func(i, j int) bool {
+		if committees[i].Slot != committees[j].Slot {
+			return committees[i].Slot < committees[j].Slot
+		}
+		return committees[i].CommitteeIndex < committees[j].CommitteeIndex
+	})
+	return committees, nil
+}
+
+// The in memory backend has no schema, so there is never anything to migrate
+func (db *Memory) MigrateUp() ([]Migration, error) {
+	return nil, nil
+}
+
+func (db *Memory) MigrateDown(steps int) ([]Migration, error) {
+	return nil, nil
+}
+
+func (db *Memory) MigrationStatus() ([]MigrationStatus, error) {
+	return []MigrationStatus{}, nil
+}
+
+func (db *Memory) Prune(policy RetentionPolicy) (*RetentionReport, error) {
+	if _, err := policy.compressedTables(); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	report := &RetentionReport{Compressed: []string{}}
+	for key, committee := range db.committees {
+		if committee.AggregationBits != "" && committee.Epoch < policy.AttestationsBeforeEpoch {
+			committee.AggregationBits, committee.Participants, committee.InclusionSlot = "", 0, 0
+			db.committees[key] = committee
+			report.Attestations++
+		}
+		if committee.Epoch < policy.CommitteesBeforeEpoch {
+			delete(db.committees, key)
+			report.Committees++
+		}
+	}
+	for epoch, participation := range db.participation {
+		if participation.UnixTime < policy.AggregatesBefore {
+			delete(db.participation, epoch)
+			report.EpochParticipation++
+		}
+	}
+	return report, nil
+}
+
+func (db *Memory) GetSlotCoverage(fromSlot int64, toSlot int64) (*SlotCoverage, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	coverage := &SlotCoverage{Missing: []int64{}}
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		if _, ok := db.data[slot]; ok {
+			coverage.Indexed++
+		} else if db.missed[slot] {
+			coverage.Missed++
+		} else {
+			coverage.Missing = append(coverage.Missing, slot)
+		}
+	}
+	return coverage, nil
+}
+
+func (db *Memory) GetMissedSlots(fromSlot int64, toSlot int64) ([]int64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	slots := []int64{}
+	for slot := range db.missed {
+		if slot >= fromSlot && slot <= toSlot {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	return slots, nil
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migrations live in migrations/<dialect>/<version>_<name>.up.sql and .down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+/*
+Migration is one versioned change to the schema, with the SQL that applies it and the SQL that reverts it
+*/
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+/*
+MigrationStatus tells whether a migration has been applied to the database, and when as unix seconds
+*/
+type MigrationStatus struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt int64  `json:"applied_at,omitempty"`
+}
+
+/*
+migrationTarget is a backend with a schema. It keeps the applied versions in the schema_migrations table
+and applies or reverts a migration in one transaction with the change to that table
+*/
+type migrationTarget interface {
+	dialect() string
+	appliedMigrations() (map[int]int64, error)
+	runMigration(migration Migration, up bool) error
+}
+
+/*
+This function loads the migrations of a dialect in version order. Every migration must have both an up
+and a down file and versions must be unique
+*/
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		direction := ""
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		separator := strings.Index(base, "_")
+		if separator < 0 {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>", name)
+		}
+		version, err := strconv.Atoi(base[:separator])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no numeric version", name)
+		}
+		body, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: base[separator+1:]}
+			byVersion[version] = migration
+		} else if migration.Name != base[separator+1:] {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, base[separator+1:])
+		}
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+/*
+This function applies every migration that has not been applied yet, in version order, and returns them
+*/
+func migrateUp(target migrationTarget) ([]Migration, error) {
+	migrations, err := loadMigrations(target.dialect())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	applied, err := target.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var run []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = target.runMigration(migration, true)
+		if err != nil {
+			err = fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			logger.LogError(err)
+			return run, err
+		}
+		logger.LogInfo("Applied migration", migration.Version, migration.Name)
+		run = append(run, migration)
+	}
+	return run, nil
+}
+
+/*
+This function reverts the given number of applied migrations, latest first, and returns them
+*/
+func migrateDown(target migrationTarget, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations(target.dialect())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	applied, err := target.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var run []Migration
+	for i := len(migrations) - 1; i >= 0 && len(run) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err = target.runMigration(migration, false)
+		if err != nil {
+			err = fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			logger.LogError(err)
+			return run, err
+		}
+		logger.LogInfo("Reverted migration", migration.Version, migration.Name)
+		run = append(run, migration)
+	}
+	return run, nil
+}
+
+func migrationStatus(target migrationTarget) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(target.dialect())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	applied, err := target.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{Version: migration.Version, Name: migration.Name, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+const createMigrationsTable = "CREATE TABLE IF NOT EXISTS schema_migrations ( version BIGINT NOT NULL, name TEXT NOT NULL, applied_at BIGINT NOT NULL, PRIMARY KEY (version))"
@@ -0,0 +1,10 @@
+SELECT set_number_partitions('beacon_chain_data', 32, 'slot');
+SELECT set_chunk_time_interval('beacon_chain_data', 384);
+
+ALTER TABLE beacon_chain_data
+    ALTER COLUMN proposer_index TYPE TEXT USING proposer_index::text,
+    ALTER COLUMN root TYPE TEXT USING '0x' || encode(root, 'hex'),
+    ALTER COLUMN parent_root TYPE TEXT USING '0x' || encode(parent_root, 'hex'),
+    ALTER COLUMN state_root TYPE TEXT USING '0x' || encode(state_root, 'hex'),
+    ALTER COLUMN body_root TYPE TEXT USING '0x' || encode(body_root, 'hex'),
+    ALTER COLUMN signature TYPE TEXT USING '0x' || encode(signature, 'hex');
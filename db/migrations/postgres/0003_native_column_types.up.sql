@@ -0,0 +1,12 @@
+ALTER TABLE beacon_chain_data
+    ALTER COLUMN proposer_index TYPE BIGINT USING proposer_index::bigint,
+    ALTER COLUMN root TYPE BYTEA USING decode(substring(root from 3), 'hex'),
+    ALTER COLUMN parent_root TYPE BYTEA USING decode(substring(parent_root from 3), 'hex'),
+    ALTER COLUMN state_root TYPE BYTEA USING decode(substring(state_root from 3), 'hex'),
+    ALTER COLUMN body_root TYPE BYTEA USING decode(substring(body_root from 3), 'hex'),
+    ALTER COLUMN signature TYPE BYTEA USING decode(substring(signature from 3), 'hex');
+
+-- A week of slots per chunk instead of 32 slots, and no space partitioning of new chunks on slot, which
+-- only multiplied the number of chunks
+SELECT set_chunk_time_interval('beacon_chain_data', 604800);
+SELECT set_number_partitions('beacon_chain_data', 1, 'slot');
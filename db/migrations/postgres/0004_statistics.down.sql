@@ -0,0 +1,14 @@
+DROP MATERIALIZED VIEW IF EXISTS participation_stats_daily;
+DROP MATERIALIZED VIEW IF EXISTS participation_stats_hourly;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_daily;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_hourly;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_epoch;
+
+-- A hypertable can not be turned back into a table, so its rows are copied to a plain one
+CREATE TABLE epoch_participation_plain ( epoch BIGINT NOT NULL, participating BIGINT NOT NULL, expected BIGINT NOT NULL, participation_rate DOUBLE PRECISION NOT NULL,
+PRIMARY KEY (epoch));
+INSERT INTO epoch_participation_plain (epoch, participating, expected, participation_rate)
+SELECT DISTINCT ON (epoch) epoch, participating, expected, participation_rate FROM epoch_participation ORDER BY epoch, unix_time DESC;
+DROP TABLE epoch_participation;
+ALTER TABLE epoch_participation_plain RENAME TO epoch_participation;
+ALTER INDEX epoch_participation_plain_pkey RENAME TO epoch_participation_pkey;
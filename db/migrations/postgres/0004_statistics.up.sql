@@ -0,0 +1,41 @@
+-- Continuous aggregates on integer time need a function giving the current time in the unit of the column
+CREATE OR REPLACE FUNCTION unix_now() RETURNS BIGINT LANGUAGE SQL STABLE AS $$ SELECT extract(epoch from now())::BIGINT $$;
+SELECT set_integer_now_func('beacon_chain_data', 'unix_now', replace_if_exists => TRUE);
+
+-- Participation is bucketed by the time of the first slot of its epoch. Rows stored before have it computed
+-- with the genesis time and 384 second epochs the indexer uses
+ALTER TABLE epoch_participation ADD COLUMN IF NOT EXISTS unix_time BIGINT;
+UPDATE epoch_participation SET unix_time = 1606804223 + epoch * 384 WHERE unix_time IS NULL;
+ALTER TABLE epoch_participation ALTER COLUMN unix_time SET NOT NULL;
+ALTER TABLE epoch_participation DROP CONSTRAINT IF EXISTS epoch_participation_pkey;
+ALTER TABLE epoch_participation ADD PRIMARY KEY (epoch, unix_time);
+SELECT create_hypertable('epoch_participation', 'unix_time', chunk_time_interval => 2592000, migrate_data => TRUE, if_not_exists => TRUE);
+SELECT set_integer_now_func('epoch_participation', 'unix_now', replace_if_exists => TRUE);
+
+-- Blocks are grouped by epoch within 384 second buckets, an epoch spans at most two of them
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_epoch WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(384, unix_time) AS bucket, epoch, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, MIN(slot) AS first_slot, MAX(slot) AS last_slot
+FROM beacon_chain_data GROUP BY time_bucket(384, unix_time), epoch WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_hourly WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(3600, unix_time) AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, MIN(slot) AS first_slot, MAX(slot) AS last_slot
+FROM beacon_chain_data GROUP BY time_bucket(3600, unix_time) WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_daily WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(86400, unix_time) AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, MIN(slot) AS first_slot, MAX(slot) AS last_slot
+FROM beacon_chain_data GROUP BY time_bucket(86400, unix_time) WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS participation_stats_hourly WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(3600, unix_time) AS bucket, AVG(participation_rate) AS participation_rate
+FROM epoch_participation GROUP BY time_bucket(3600, unix_time) WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS participation_stats_daily WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(86400, unix_time) AS bucket, AVG(participation_rate) AS participation_rate
+FROM epoch_participation GROUP BY time_bucket(86400, unix_time) WITH NO DATA;
+
+-- Everything older than an epoch is materialized in the background, newer rows are aggregated at query time
+SELECT add_continuous_aggregate_policy('block_stats_epoch', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '10 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('block_stats_hourly', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '30 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('block_stats_daily', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '1 hour', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('participation_stats_hourly', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '30 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('participation_stats_daily', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '1 hour', if_not_exists => TRUE);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS missed_slots;
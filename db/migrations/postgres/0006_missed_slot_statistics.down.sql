@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS missed_slots_epoch;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_daily;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_hourly;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_epoch;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_epoch WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(384, unix_time) AS bucket, epoch, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, MIN(slot) AS first_slot, MAX(slot) AS last_slot
+FROM beacon_chain_data GROUP BY time_bucket(384, unix_time), epoch WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_hourly WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(3600, unix_time) AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, MIN(slot) AS first_slot, MAX(slot) AS last_slot
+FROM beacon_chain_data GROUP BY time_bucket(3600, unix_time) WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_daily WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(86400, unix_time) AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, MIN(slot) AS first_slot, MAX(slot) AS last_slot
+FROM beacon_chain_data GROUP BY time_bucket(86400, unix_time) WITH NO DATA;
+
+SELECT add_continuous_aggregate_policy('block_stats_epoch', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '10 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('block_stats_hourly', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '30 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('block_stats_daily', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '1 hour', if_not_exists => TRUE);
@@ -0,0 +1,23 @@
+-- Missed slots are counted from missed_slots, so the block aggregates count the slots with a row instead of
+-- keeping their first and last slot, which missed the slots at the edges of a bucket
+DROP MATERIALIZED VIEW IF EXISTS block_stats_daily;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_hourly;
+DROP MATERIALIZED VIEW IF EXISTS block_stats_epoch;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_epoch WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(384, unix_time) AS bucket, epoch, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, COUNT(*) AS slots
+FROM beacon_chain_data GROUP BY time_bucket(384, unix_time), epoch WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_hourly WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(3600, unix_time) AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, COUNT(*) AS slots
+FROM beacon_chain_data GROUP BY time_bucket(3600, unix_time) WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS block_stats_daily WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(86400, unix_time) AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, COUNT(*) AS slots
+FROM beacon_chain_data GROUP BY time_bucket(86400, unix_time) WITH NO DATA;
+
+SELECT add_continuous_aggregate_policy('block_stats_epoch', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '10 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('block_stats_hourly', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '30 minutes', if_not_exists => TRUE);
+SELECT add_continuous_aggregate_policy('block_stats_daily', start_offset => NULL, end_offset => 384, schedule_interval => INTERVAL '1 hour', if_not_exists => TRUE);
+
+CREATE INDEX IF NOT EXISTS missed_slots_epoch ON missed_slots (epoch);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS epoch_participation;
+DROP TABLE IF EXISTS attestations;
+DROP TABLE IF EXISTS committees;
+DROP TABLE IF EXISTS beacon_chain_data;
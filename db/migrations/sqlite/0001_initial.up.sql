@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS beacon_chain_data ( slot BIGINT NOT NULL, root TEXT NOT NULL, canonical BOOLEAN NOT NULL, proposer_index TEXT NOT NULL, parent_root TEXT NOT NULL, state_root TEXT NOT NULL, body_root TEXT NOT NULL, signature TEXT NOT NULL, unix_time BIGINT NOT NULL, epoch BIGINT NOT NULL,
+PRIMARY KEY (slot, unix_time));
+
+CREATE TABLE IF NOT EXISTS committees ( epoch BIGINT NOT NULL, slot BIGINT NOT NULL, committee_index BIGINT NOT NULL, committee_size INT NOT NULL,
+PRIMARY KEY (slot, committee_index));
+
+CREATE TABLE IF NOT EXISTS attestations ( epoch BIGINT NOT NULL, slot BIGINT NOT NULL, committee_index BIGINT NOT NULL, aggregation_bits TEXT NOT NULL, participants INT NOT NULL, inclusion_slot BIGINT NOT NULL,
+PRIMARY KEY (slot, committee_index));
+
+CREATE TABLE IF NOT EXISTS epoch_participation ( epoch BIGINT NOT NULL, participating BIGINT NOT NULL, expected BIGINT NOT NULL, participation_rate DOUBLE PRECISION NOT NULL,
+PRIMARY KEY (epoch));
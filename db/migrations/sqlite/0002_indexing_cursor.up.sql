@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS indexing_cursor ( name TEXT NOT NULL, epoch BIGINT NOT NULL, updated_at BIGINT NOT NULL,
+PRIMARY KEY (name));
@@ -0,0 +1,8 @@
+CREATE TABLE beacon_chain_data_text ( slot BIGINT NOT NULL, root TEXT NOT NULL, canonical BOOLEAN NOT NULL, proposer_index TEXT NOT NULL, parent_root TEXT NOT NULL, state_root TEXT NOT NULL, body_root TEXT NOT NULL, signature TEXT NOT NULL, unix_time BIGINT NOT NULL, epoch BIGINT NOT NULL,
+PRIMARY KEY (slot, unix_time));
+
+INSERT INTO beacon_chain_data_text (slot, root, canonical, proposer_index, parent_root, state_root, body_root, signature, unix_time, epoch)
+SELECT slot, '0x' || lower(hex(root)), canonical, CAST(proposer_index AS TEXT), '0x' || lower(hex(parent_root)), '0x' || lower(hex(state_root)), '0x' || lower(hex(body_root)), '0x' || lower(hex(signature)), unix_time, epoch FROM beacon_chain_data;
+
+DROP TABLE beacon_chain_data;
+ALTER TABLE beacon_chain_data_text RENAME TO beacon_chain_data;
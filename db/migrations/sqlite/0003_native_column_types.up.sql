@@ -0,0 +1,10 @@
+-- SQLite can not change the type of a column, so the table is copied. unhex returns NULL for anything
+-- that is not hex, which fails the copy instead of losing a row
+CREATE TABLE beacon_chain_data_native ( slot BIGINT NOT NULL, root BLOB NOT NULL, canonical BOOLEAN NOT NULL, proposer_index BIGINT NOT NULL, parent_root BLOB NOT NULL, state_root BLOB NOT NULL, body_root BLOB NOT NULL, signature BLOB NOT NULL, unix_time BIGINT NOT NULL, epoch BIGINT NOT NULL,
+PRIMARY KEY (slot, unix_time));
+
+INSERT INTO beacon_chain_data_native (slot, root, canonical, proposer_index, parent_root, state_root, body_root, signature, unix_time, epoch)
+SELECT slot, unhex(substr(root, 3)), canonical, CAST(proposer_index AS INTEGER), unhex(substr(parent_root, 3)), unhex(substr(state_root, 3)), unhex(substr(body_root, 3)), unhex(substr(signature, 3)), unix_time, epoch FROM beacon_chain_data;
+
+DROP TABLE beacon_chain_data;
+ALTER TABLE beacon_chain_data_native RENAME TO beacon_chain_data;
@@ -0,0 +1 @@
+ALTER TABLE epoch_participation DROP COLUMN unix_time;
@@ -0,0 +1,4 @@
+-- Rows stored before get the time of the first slot of their epoch from the genesis time and 384 second
+-- epochs the indexer uses
+ALTER TABLE epoch_participation ADD COLUMN unix_time BIGINT NOT NULL DEFAULT 0;
+UPDATE epoch_participation SET unix_time = 1606804223 + epoch * 384;
@@ -0,0 +1,3 @@
+-- Slots whose proposer did not produce a block, so a slot without a header row is only missing when it is not here
+CREATE TABLE IF NOT EXISTS missed_slots ( slot BIGINT NOT NULL, epoch BIGINT NOT NULL,
+PRIMARY KEY (slot));
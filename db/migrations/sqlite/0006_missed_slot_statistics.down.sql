@@ -0,0 +1 @@
+DROP INDEX IF EXISTS missed_slots_epoch;
@@ -0,0 +1,2 @@
+-- Statistics count the missed slots of every epoch from missed_slots
+CREATE INDEX IF NOT EXISTS missed_slots_epoch ON missed_slots (epoch);
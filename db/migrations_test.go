@@ -0,0 +1,91 @@
+package db
+
+import "testing"
+
+func TestMigrationsLoad(t *testing.T) {
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		migrations, err := loadMigrations(dialect)
+		if err != nil || len(migrations) == 0 || migrations[0].Version != 1 {
+			t.Errorf("unexpected %s migrations %+v: %v", dialect, migrations, err)
+		}
+	}
+	postgres, _ := loadMigrations("postgres")
+	sqlite, _ := loadMigrations("sqlite")
+	if len(postgres) != len(sqlite) {
+		t.Errorf("every dialect must have the same migrations, postgres has %d and sqlite %d", len(postgres), len(sqlite))
+	}
+}
+
+func TestMigrateUpDownStatus(t *testing.T) {
+	storage, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer storage.Close()
+	migrations, _ := loadMigrations("sqlite")
+
+	applied, err := storage.MigrateUp()
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("expected every migration to be applied, got %d: %v", len(applied), err)
+	}
+	insertRows(t, storage)
+	// Applying again changes nothing and keeps the indexed data
+	applied, err = storage.MigrateUp()
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected nothing left to apply, got %d: %v", len(applied), err)
+	}
+	if from, to, err := storage.GetIndexedSlotRange(); err != nil || from != 320 || to != 352 {
+		t.Errorf("indexed data changed by migrating again: %v to %v: %v", from, to, err)
+	}
+
+	statuses, err := storage.MigrationStatus()
+	if err != nil || len(statuses) != len(migrations) || !statuses[len(statuses)-1].Applied || statuses[0].AppliedAt == 0 {
+		t.Errorf("unexpected status %+v: %v", statuses, err)
+	}
+	reverted, err := storage.MigrateDown(1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != migrations[len(migrations)-1].Version {
+		t.Fatalf("expected the latest migration to be reverted, got %+v: %v", reverted, err)
+	}
+	statuses, _ = storage.MigrationStatus()
+	if statuses[len(statuses)-1].Applied {
+		t.Errorf("latest migration still applied after reverting it: %+v", statuses)
+	}
+}
+
+func TestNativeColumnTypesMigrationKeepsRows(t *testing.T) {
+	storage, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer storage.Close()
+	if _, err = storage.MigrateUp(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// Revert everything from the native column types on
+	migrations, _ := loadMigrations("sqlite")
+	steps := 0
+	for _, migration := range migrations {
+		if migration.Version >= 3 {
+			steps++
+		}
+	}
+	if _, err = storage.MigrateDown(steps); err != nil {
+		t.Fatalf("failed to revert the native column types: %v", err)
+	}
+	_, err = storage.db.Exec("INSERT INTO beacon_chain_data (slot, root, canonical, proposer_index, parent_root, state_root, body_root, signature, unix_time, epoch) VALUES (320, ?, 1, '11', ?, '0x5a', '0xb0', ?, 1606808063, 10)",
+		testRoot(320), testRoot(319), testSignature)
+	if err != nil {
+		t.Fatalf("failed to insert a text row: %v", err)
+	}
+	if _, err = storage.MigrateUp(); err != nil {
+		t.Fatalf("failed to migrate to native column types: %v", err)
+	}
+	rows, err := storage.GetIndexedData(320, 320)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("expected the row to be kept, got %+v: %v", rows, err)
+	}
+	message := rows[0].Data.Header.Message
+	if rows[0].Data.Root != testRoot(320) || message.ParentRoot != testRoot(319) || message.ProposerIndex != "11" || message.StateRoot != "0x5a" || rows[0].Data.Header.Signature != testSignature {
+		t.Errorf("row changed by the migration: %+v", rows[0])
+	}
+}
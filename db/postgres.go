@@ -0,0 +1,602 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"strconv"
+	"strings"
+)
+
+/*
+Postgres is the production storage, a Postgres database with the TimescaleDB extension
+*/
+type Postgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) *Postgres {
+	return &Postgres{
+		pool: pool,
+	}
+}
+
+func (db *Postgres) Close() {
+	db.pool.Close()
+}
+
+/*
+This method stores the header rows and missed slots of an epoch and, once every slot of the epoch is stored and
+it follows the indexing cursor, advances the cursor to it, all in one transaction so an epoch is never half written. The rows are loaded with COPY into a staging table
+and upserted from there, since COPY itself can not update rows that are already stored
+*/
+func (db *Postgres) InsertEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error) {
+	return db.writeEpochData(epoch, rows, missedSlots, true)
+}
+
+func (db *Postgres) ImportEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error) {
+	return db.writeEpochData(epoch, rows, missedSlots, false)
+}
+
+func (db *Postgres) writeEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64, advanceCursor bool) (WriteCounts, error) {
+	counts := WriteCounts{Epoch: epoch}
+	ctx := context.Background()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "CREATE TEMP TABLE beacon_chain_data_staging (LIKE beacon_chain_data) ON COMMIT DROP")
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"beacon_chain_data_staging"}, insertColumns, pgx.CopyFromSlice(len(rows), func(i int) ([]interface{}, error) {
+		return beaconDataValues(&rows[i])
+	}))
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	// WHERE true keeps ON CONFLICT from being parsed as part of the SELECT
+	columns := strings.Join(insertColumns, ", ")
+	written, err := tx.Query(ctx, "INSERT INTO beacon_chain_data ("+columns+") SELECT "+columns+" FROM beacon_chain_data_staging WHERE true "+
+		postgresConflictClause("beacon_chain_data", insertColumns[:2], insertColumns[2:]))
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	for written.Next() {
+		var inserted bool
+		if err = written.Scan(&inserted); err != nil {
+			break
+		}
+		counts.count(inserted, true)
+	}
+	written.Close()
+	if err == nil {
+		err = written.Err()
+	}
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	counts.Unchanged = len(rows) - counts.Inserted - counts.Updated
+
+	// A slot that has a block now is no longer missed
+	_, err = tx.Exec(ctx, "DELETE FROM missed_slots WHERE slot IN (SELECT slot FROM beacon_chain_data_staging)")
+	if err == nil && len(missedSlots) > 0 {
+		_, err = tx.Exec(ctx, "INSERT INTO missed_slots (slot, epoch) SELECT unnest($1::bigint[]), $2 ON CONFLICT (slot) DO NOTHING", missedSlots, epoch)
+	}
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+
+	var covered int64
+	if advanceCursor {
+		err = tx.QueryRow(ctx, fmt.Sprintf(epochCoveredSlotsQuery, "$1", "$2"), epoch*spec.SlotsPerEpoch, (epoch+1)*spec.SlotsPerEpoch-1).Scan(&covered)
+	}
+	if err == nil && covered == spec.SlotsPerEpoch {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO indexing_cursor (name, epoch, updated_at) VALUES ($1, $2, extract(epoch from now())::bigint) ON CONFLICT (name) DO UPDATE SET epoch = excluded.epoch, updated_at = excluded.updated_at WHERE indexing_cursor.epoch = excluded.epoch - 1",
+			headersCursor, epoch,
+		)
+	}
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	return counts, nil
+}
+
+/*
+This method returns the latest epoch written by InsertEpochData, -1 when nothing has been written
+*/
+func (db *Postgres) GetIndexingCursor() (int64, error) {
+	var epoch int64
+	err := db.pool.QueryRow(context.Background(), "SELECT epoch FROM indexing_cursor WHERE name = $1", headersCursor).Scan(&epoch)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return 0, err
+	}
+	return epoch, nil
+}
+
+/*
+This method deletes every header row and missed slot and resets the indexing cursor
+*/
+func (db *Postgres) DeleteData() error {
+	ctx := context.Background()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for _, table := range []string{"beacon_chain_data", "missed_slots", "indexing_cursor"} {
+		_, err = tx.Exec(ctx, "DELETE FROM "+table)
+		if err != nil {
+			logger.LogError(err)
+			return err
+		}
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return err
+	}
+	return nil
+}
+
+/*
+This method stores the participation of a finalized epoch: committee sizes, the merged aggregation bits of
+every committee and the epoch totals. Every row is upserted in one transaction
+*/
+func (db *Postgres) InsertEpochParticipation(participation *model.EpochParticipation, committees []model.CommitteeParticipation) (WriteCounts, error) {
+	counts := WriteCounts{Epoch: participation.Epoch}
+	ctx := context.Background()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	defer tx.Rollback(ctx)
+
+	upserts := participationUpserts(participation, committees)
+	batch := &pgx.Batch{}
+	for _, upsert := range upserts {
+		batch.Queue(upsert.postgres(), upsert.values...)
+	}
+	results := tx.SendBatch(ctx, batch)
+	for range upserts {
+		var inserted bool
+		err = results.QueryRow().Scan(&inserted)
+		if errors.Is(err, pgx.ErrNoRows) {
+			counts.count(false, false)
+			continue
+		}
+		if err != nil {
+			break
+		}
+		counts.count(inserted, true)
+	}
+	if closeErr := results.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	return counts, nil
+}
+
+/*
+This method returns the stored participation of an epoch, or nil when it has not been computed yet
+*/
+func (db *Postgres) GetEpochParticipation(epoch int64) (*model.EpochParticipation, error) {
+	var participation model.EpochParticipation
+	err := db.pool.QueryRow(context.Background(),
+		"SELECT epoch, participating, expected, participation_rate, unix_time FROM epoch_participation WHERE epoch = $1", epoch,
+	).Scan(&participation.Epoch, &participation.Participating, &participation.Expected, &participation.ParticipationRate, &participation.UnixTime)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return &participation, nil
+}
+
+/*
+This method returns the committees of an epoch with their attestation, when one is stored, in slot order
+*/
+func (db *Postgres) GetCommittees(epoch int64) ([]model.CommitteeParticipation, error) {
+	rows, err := db.pool.Query(context.Background(), committeesQuery("$1"), epoch)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	committees, err := scanCommittees(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return committees, nil
+}
+
+/*
+This method returns the stored proposer index of every indexed slot of an epoch, keyed by slot
+*/
+func (db *Postgres) GetProposers(epoch int64) (map[int64]string, error) {
+	rows, err := db.pool.Query(context.Background(), "SELECT slot, proposer_index FROM beacon_chain_data WHERE epoch = $1", epoch)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	proposers := make(map[int64]string)
+	for rows.Next() {
+		var slot, proposerIndex int64
+		err = rows.Scan(&slot, &proposerIndex)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		proposers[slot] = strconv.FormatInt(proposerIndex, 10)
+	}
+	if err = rows.Err(); err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return proposers, nil
+}
+
+/*
+This method returns the indexed rows of beacon_chain_data between the two slots, inclusive, in slot order
+*/
+func (db *Postgres) GetIndexedData(fromSlot int64, toSlot int64) ([]model.BeaconChainData, error) {
+	return db.queryBeaconData("SELECT "+beaconDataColumns+" FROM beacon_chain_data WHERE slot BETWEEN $1 AND $2 ORDER BY slot", fromSlot, toSlot)
+}
+
+/*
+This method returns the rows of beacon_chain_data that pass the filter, all of them when it is nil, latest slot first
+*/
+func (db *Postgres) GetData(filter *DataFilter) ([]model.BeaconChainData, error) {
+	if filter == nil {
+		return db.queryBeaconData("SELECT " + beaconDataColumns + " FROM beacon_chain_data ORDER BY slot DESC")
+	}
+	// The column is one of filterColumns, never user input
+	return db.queryBeaconData("SELECT "+beaconDataColumns+" FROM beacon_chain_data WHERE "+filter.Column+" = $1 ORDER BY slot DESC", filter.Value)
+}
+
+func (db *Postgres) queryBeaconData(query string, args ...interface{}) ([]model.BeaconChainData, error) {
+	rows, err := db.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	var data []model.BeaconChainData
+	for rows.Next() {
+		beaconData, err := scanBeaconData(rows)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		data = append(data, beaconData)
+	}
+	if err = rows.Err(); err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return data, nil
+}
+
+/*
+This method returns the lowest and highest indexed slot, both are -1 when nothing is indexed
+*/
+func (db *Postgres) GetIndexedSlotRange() (int64, int64, error) {
+	var fromSlot, toSlot int64
+	err := db.pool.QueryRow(context.Background(), "SELECT COALESCE(MIN(slot), -1), COALESCE(MAX(slot), -1) FROM beacon_chain_data").Scan(&fromSlot, &toSlot)
+	if err != nil {
+		logger.LogError(err)
+		return 0, 0, err
+	}
+	return fromSlot, toSlot, nil
+}
+
+/*
+This method returns the root of the last canonical row before the slot, or an empty string when there is none
+*/
+func (db *Postgres) GetCanonicalRootBefore(slot int64) (string, error) {
+	var root []byte
+	err := db.pool.QueryRow(context.Background(),
+		"SELECT root FROM beacon_chain_data WHERE slot < $1 AND canonical ORDER BY slot DESC LIMIT 1", slot,
+	).Scan(&root)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return "", err
+	}
+	return encodeHex(root), nil
+}
+
+// Held for the duration of a migration so two instances starting together do not both apply it
+const migrationLockID = 4242
+
+func (db *Postgres) MigrateUp() ([]Migration, error) {
+	return migrateUp(db)
+}
+
+func (db *Postgres) MigrateDown(steps int) ([]Migration, error) {
+	return migrateDown(db, steps)
+}
+
+func (db *Postgres) MigrationStatus() ([]MigrationStatus, error) {
+	return migrationStatus(db)
+}
+
+func (db *Postgres) dialect() string {
+	return "postgres"
+}
+
+func (db *Postgres) appliedMigrations() (map[int]int64, error) {
+	ctx := context.Background()
+	_, err := db.pool.Exec(ctx, createMigrationsTable)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	rows, err := db.pool.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]int64)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (db *Postgres) runMigration(migration Migration, up bool) error {
+	ctx := context.Background()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID)
+	if err != nil {
+		return err
+	}
+	// Another instance may have run the migration while this one waited for the lock
+	var recorded bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", migration.Version).Scan(&recorded)
+	if err != nil || recorded == up {
+		return err
+	}
+	if up {
+		_, err = tx.Exec(ctx, migration.Up)
+		if err == nil {
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, EXTRACT(EPOCH FROM NOW())::BIGINT)", migration.Version, migration.Name)
+		}
+	} else {
+		_, err = tx.Exec(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+/*
+Postgres reads the blocks and participation from the continuous aggregates of the 0004_statistics and
+0006_missed_slot_statistics migrations. Missed slots are a small share of all slots and are counted from
+missed_slots at query time
+*/
+var postgresStatsQueries = map[string]string{
+	"epoch": statsQuery(
+		"SELECT epoch AS bucket, SUM(blocks) AS blocks, SUM(slots) AS slots FROM block_stats_epoch GROUP BY epoch",
+		"SELECT epoch AS bucket, COUNT(*) AS missed FROM missed_slots GROUP BY epoch",
+		"SELECT epoch AS bucket, participation_rate FROM epoch_participation", "$1"),
+	"hour": statsQuery("SELECT bucket, blocks, slots FROM block_stats_hourly",
+		"SELECT time_bucket(3600, "+missedSlotTime+") AS bucket, COUNT(*) AS missed FROM missed_slots GROUP BY 1",
+		"SELECT bucket, participation_rate FROM participation_stats_hourly", "$1"),
+	"day": statsQuery("SELECT bucket, blocks, slots FROM block_stats_daily",
+		"SELECT time_bucket(86400, "+missedSlotTime+") AS bucket, COUNT(*) AS missed FROM missed_slots GROUP BY 1",
+		"SELECT bucket, participation_rate FROM participation_stats_daily", "$1"),
+}
+
+/*
+This method returns the blocks, missed slots and average participation of the latest buckets, latest first
+*/
+func (db *Postgres) GetStats(bucket string, limit int) ([]model.BucketStats, error) {
+	query, ok := postgresStatsQueries[bucket]
+	if !ok {
+		return nil, ErrInvalidBucket
+	}
+	rows, err := db.pool.Query(context.Background(), query, limit)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	stats, err := scanStats(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+/*
+This method applies a retention policy. Compression is enabled on the hypertables of the policy and their
+compression policy replaced by one for the configured age, which TimescaleDB then runs in the background.
+The compression policy of a hypertable left out of the policy is removed, chunks it already compressed stay
+compressed. Expired rows are deleted and expired chunks of the statistics aggregates dropped in one transaction
+*/
+func (db *Postgres) Prune(policy RetentionPolicy) (*RetentionReport, error) {
+	tables, err := policy.compressedTables()
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	ctx := context.Background()
+	report := &RetentionReport{Compressed: tables}
+	for table := range compressibleTables {
+		if _, ok := policy.CompressAfter[table]; ok {
+			continue
+		}
+		if _, err = db.pool.Exec(ctx, "SELECT remove_compression_policy($1, if_exists => TRUE)", table); err != nil {
+			logger.LogError(fmt.Errorf("removing the compression policy of %s: %w", table, err))
+			return nil, err
+		}
+	}
+	for _, table := range tables {
+		var enabled bool
+		err = db.pool.QueryRow(ctx, "SELECT compression_enabled FROM timescaledb_information.hypertables WHERE hypertable_schema = current_schema() AND hypertable_name = $1", table).Scan(&enabled)
+		if err == nil && !enabled {
+			// The table is one of compressibleTables, never user input
+			_, err = db.pool.Exec(ctx, "ALTER TABLE "+table+" SET (timescaledb.compress, timescaledb.compress_orderby = '"+compressibleTables[table]+"')")
+		}
+		if err == nil {
+			_, err = db.pool.Exec(ctx, "SELECT remove_compression_policy($1, if_exists => TRUE)", table)
+		}
+		if err == nil {
+			_, err = db.pool.Exec(ctx, "SELECT add_compression_policy($1, compress_after => $2::bigint)", table, policy.CompressAfter[table])
+		}
+		if err != nil {
+			logger.LogError(fmt.Errorf("compressing %s: %w", table, err))
+			return nil, err
+		}
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	for _, deletion := range []struct {
+		query   string
+		cutoff  int64
+		deleted *int64
+	}{
+		{"DELETE FROM committees WHERE epoch < $1", policy.CommitteesBeforeEpoch, &report.Committees},
+		{"DELETE FROM attestations WHERE epoch < $1", policy.AttestationsBeforeEpoch, &report.Attestations},
+		{"DELETE FROM epoch_participation WHERE unix_time < $1", policy.AggregatesBefore, &report.EpochParticipation},
+	} {
+		if deletion.cutoff <= 0 {
+			continue
+		}
+		tag, err := tx.Exec(ctx, deletion.query, deletion.cutoff)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		*deletion.deleted = tag.RowsAffected()
+	}
+	if policy.AggregatesBefore > 0 {
+		for _, aggregate := range statsAggregates {
+			var dropped int64
+			err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM drop_chunks($1, older_than => $2::bigint)", aggregate, policy.AggregatesBefore).Scan(&dropped)
+			if err != nil {
+				logger.LogError(fmt.Errorf("dropping chunks of %s: %w", aggregate, err))
+				return nil, err
+			}
+			report.AggregateChunks += dropped
+		}
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return report, nil
+}
+
+/*
+This method counts the slots of a range that have a header row or are known to be missed, and lists the
+missing ones, which have neither
+*/
+func (db *Postgres) GetSlotCoverage(fromSlot int64, toSlot int64) (*SlotCoverage, error) {
+	ctx := context.Background()
+	coverage := &SlotCoverage{Missing: []int64{}}
+	err := db.pool.QueryRow(ctx,
+		"SELECT (SELECT COUNT(DISTINCT slot) FROM beacon_chain_data WHERE slot BETWEEN $1 AND $2), (SELECT COUNT(*) FROM missed_slots WHERE slot BETWEEN $1 AND $2)",
+		fromSlot, toSlot,
+	).Scan(&coverage.Indexed, &coverage.Missed)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	rows, err := db.pool.Query(ctx,
+		"SELECT s FROM generate_series($1::bigint, $2::bigint) s WHERE NOT EXISTS (SELECT 1 FROM beacon_chain_data WHERE slot = s) AND NOT EXISTS (SELECT 1 FROM missed_slots WHERE slot = s) ORDER BY s",
+		fromSlot, toSlot,
+	)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	coverage.Missing, err = scanSlots(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return coverage, nil
+}
+
+/*
+This method returns the slots between the two slots, inclusive, that are known to be missed by their proposer
+*/
+func (db *Postgres) GetMissedSlots(fromSlot int64, toSlot int64) ([]int64, error) {
+	rows, err := db.pool.Query(context.Background(), "SELECT slot FROM missed_slots WHERE slot BETWEEN $1 AND $2 ORDER BY slot", fromSlot, toSlot)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	slots, err := scanSlots(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return slots, nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+/*
+openTestPostgres opens the database of TEST_DATABASE_URL, which needs the TimescaleDB extension, and migrates
+a schema created for the test alone, so tests never see each other's rows. The schema is dropped when the
+test ends. Without TEST_DATABASE_URL the test is skipped
+*/
+func openTestPostgres(t *testing.T) *Postgres {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	schema := fmt.Sprintf("indexer_test_%d", time.Now().UnixNano())
+	// The extension is created outside the schema, or dropping the schema would drop it too
+	_, err = admin.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS timescaledb")
+	if err == nil {
+		_, err = admin.Exec(ctx, "CREATE SCHEMA "+schema)
+	}
+	if err != nil {
+		admin.Close(ctx)
+		t.Fatalf("failed to create the test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("failed to drop the test schema %s: %v", schema, err)
+		}
+		admin.Close(ctx)
+	})
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	config.MaxConns = 4
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	storage := NewPostgres(pool)
+	// Cleanups run last in first out, the pool is closed before the schema is dropped
+	t.Cleanup(storage.Close)
+	if _, err = storage.MigrateUp(); err != nil {
+		t.Fatalf("failed to migrate postgres: %v", err)
+	}
+	return storage
+}
+
+func TestPostgresMigrateDownAndUp(t *testing.T) {
+	storage := openTestPostgres(t)
+	migrations, _ := loadMigrations("postgres")
+	insertRows(t, storage)
+
+	reverted, err := storage.MigrateDown(len(migrations))
+	if err != nil || len(reverted) != len(migrations) {
+		t.Fatalf("expected every migration to be reverted, got %d: %v", len(reverted), err)
+	}
+	statuses, err := storage.MigrationStatus()
+	if err != nil || statuses[0].Applied {
+		t.Errorf("expected nothing applied, got %+v: %v", statuses, err)
+	}
+	applied, err := storage.MigrateUp()
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("expected every migration to be applied again, got %d: %v", len(applied), err)
+	}
+	insertRows(t, storage)
+	if from, to, err := storage.GetIndexedSlotRange(); err != nil || from != 320 || to != 352 {
+		t.Errorf("unexpected indexed range %v to %v after migrating again: %v", from, to, err)
+	}
+}
+
+func TestPostgresCompressionPolicy(t *testing.T) {
+	storage := openTestPostgres(t)
+	ctx := context.Background()
+	policy := func(table string) (enabled bool, compressAfter string) {
+		t.Helper()
+		err := storage.pool.QueryRow(ctx, "SELECT compression_enabled FROM timescaledb_information.hypertables WHERE hypertable_schema = current_schema() AND hypertable_name = $1", table).Scan(&enabled)
+		if err != nil {
+			t.Fatalf("failed to read the hypertable %s: %v", table, err)
+		}
+		err = storage.pool.QueryRow(ctx, "SELECT config->>'compress_after' FROM timescaledb_information.jobs WHERE proc_name = 'policy_compression' AND hypertable_schema = current_schema() AND hypertable_name = $1", table).Scan(&compressAfter)
+		if err != nil && err != pgx.ErrNoRows {
+			t.Fatalf("failed to read the compression policy of %s: %v", table, err)
+		}
+		return enabled, compressAfter
+	}
+
+	report, err := storage.Prune(RetentionPolicy{CompressAfter: map[string]int64{"beacon_chain_data": 86400, "epoch_participation": 172800}})
+	if err != nil || len(report.Compressed) != 2 {
+		t.Fatalf("expected both hypertables to be compressed, got %+v: %v", report, err)
+	}
+	if enabled, after := policy("beacon_chain_data"); !enabled || after != "86400" {
+		t.Errorf("unexpected compression of beacon_chain_data: %v after %s", enabled, after)
+	}
+	if enabled, after := policy("epoch_participation"); !enabled || after != "172800" {
+		t.Errorf("unexpected compression of epoch_participation: %v after %s", enabled, after)
+	}
+	// Pruning again replaces the policy instead of failing on the existing one
+	if _, err = storage.Prune(RetentionPolicy{CompressAfter: map[string]int64{"beacon_chain_data": 3600}}); err != nil {
+		t.Fatalf("failed to replace the compression policy: %v", err)
+	}
+	if _, after := policy("beacon_chain_data"); after != "3600" {
+		t.Errorf("expected the policy to compress after 3600 seconds, got %s", after)
+	}
+	// A table left out of the policy is no longer compressed
+	if _, after := policy("epoch_participation"); after != "" {
+		t.Errorf("expected the compression policy of epoch_participation to be removed, got %s", after)
+	}
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"sync"
+	"time"
+)
+
+// How long the replica is trusted to be caught up, or known to lag, before the indexing cursors are compared again
+const replicaLagCheckInterval = 5 * time.Second
+
+/*
+Replicated sends the reads of the API to a read replica with a pool of its own, so heavy dashboard queries do
+not take connections from ingestion. Reads go to the primary while the indexing cursor of the replica is behind
+the one of the primary, and a read that fails on the replica is retried on the primary. Everything else,
+writes and the indexing cursor included, goes to the primary
+*/
+type Replicated struct {
+	Storage
+	replica          Storage
+	lagCheckInterval time.Duration
+	lagMutex         sync.Mutex
+	lagCheckedAt     time.Time
+	lagging          bool
+}
+
+/*
+This function opens the read replica at url with at most maxConnections connections. Without a url, or when
+the replica can not be opened, every read goes to the primary
+*/
+func WithReadReplica(primary Storage, url string, maxConnections int32) *Replicated {
+	replicated := &Replicated{Storage: primary}
+	if url == "" {
+		return replicated
+	}
+	replica, err := Open(url, maxConnections)
+	if err != nil {
+		logger.LogError(fmt.Errorf("reading from the primary, the read replica could not be opened: %w", err))
+		return replicated
+	}
+	replicated.replica = replica
+	replicated.lagCheckInterval = replicaLagCheckInterval
+	return replicated
+}
+
+// Close closes the replica only, the primary is closed by whoever opened it
+func (r *Replicated) Close() {
+	if r.replica != nil {
+		r.replica.Close()
+	}
+}
+
+/*
+This method tells whether the replica has not yet replayed epochs the primary indexed, comparing their indexing
+cursors at most once per lagCheckInterval. A replica whose cursor can not be read counts as lagging
+*/
+func (r *Replicated) replicaLagging() bool {
+	r.lagMutex.Lock()
+	defer r.lagMutex.Unlock()
+	if !r.lagCheckedAt.IsZero() && time.Since(r.lagCheckedAt) < r.lagCheckInterval {
+		return r.lagging
+	}
+	primaryCursor, err := r.Storage.GetIndexingCursor()
+	if err != nil {
+		logger.LogError(err)
+		return true
+	}
+	replicaCursor, err := r.replica.GetIndexingCursor()
+	if err != nil {
+		logger.LogError(err)
+	}
+	lagging := err != nil || replicaCursor < primaryCursor
+	if lagging != r.lagging {
+		if lagging {
+			logger.LogInfo("Reading from the primary, the read replica is indexed up to epoch", replicaCursor, "and the primary up to", primaryCursor)
+		} else {
+			logger.LogInfo("Reading from the read replica again, it is indexed up to epoch", replicaCursor)
+		}
+	}
+	r.lagging, r.lagCheckedAt = lagging, time.Now()
+	return lagging
+}
+
+func (r *Replicated) read(name string, query func(storage Storage) error) error {
+	if r.replica == nil || r.replicaLagging() {
+		return query(r.Storage)
+	}
+	err := query(r.replica)
+	if err == nil || errors.Is(err, ErrInvalidBucket) {
+		return err
+	}
+	logger.LogError(fmt.Errorf("%s failed on the read replica, reading from the primary: %w", name, err))
+	return query(r.Storage)
+}
+
+func (r *Replicated) GetData(filter *DataFilter) ([]model.BeaconChainData, error) {
+	var data []model.BeaconChainData
+	err := r.read("GetData", func(storage Storage) (err error) {
+		data, err = storage.GetData(filter)
+		return err
+	})
+	return data, err
+}
+
+func (r *Replicated) GetIndexedData(fromSlot int64, toSlot int64) ([]model.BeaconChainData, error) {
+	var data []model.BeaconChainData
+	err := r.read("GetIndexedData", func(storage Storage) (err error) {
+		data, err = storage.GetIndexedData(fromSlot, toSlot)
+		return err
+	})
+	return data, err
+}
+
+func (r *Replicated) GetIndexedSlotRange() (int64, int64, error) {
+	var fromSlot, toSlot int64
+	err := r.read("GetIndexedSlotRange", func(storage Storage) (err error) {
+		fromSlot, toSlot, err = storage.GetIndexedSlotRange()
+		return err
+	})
+	return fromSlot, toSlot, err
+}
+
+func (r *Replicated) GetCanonicalRootBefore(slot int64) (string, error) {
+	var root string
+	err := r.read("GetCanonicalRootBefore", func(storage Storage) (err error) {
+		root, err = storage.GetCanonicalRootBefore(slot)
+		return err
+	})
+	return root, err
+}
+
+func (r *Replicated) GetProposers(epoch int64) (map[int64]string, error) {
+	var proposers map[int64]string
+	err := r.read("GetProposers", func(storage Storage) (err error) {
+		proposers, err = storage.GetProposers(epoch)
+		return err
+	})
+	return proposers, err
+}
+
+func (r *Replicated) GetEpochParticipation(epoch int64) (*model.EpochParticipation, error) {
+	var participation *model.EpochParticipation
+	err := r.read("GetEpochParticipation", func(storage Storage) (err error) {
+		participation, err = storage.GetEpochParticipation(epoch)
+		return err
+	})
+	return participation, err
+}
+
+func (r *Replicated) GetStats(bucket string, limit int) ([]model.BucketStats, error) {
+	var stats []model.BucketStats
+	err := r.read("GetStats", func(storage Storage) (err error) {
+		stats, err = storage.GetStats(bucket, limit)
+		return err
+	})
+	return stats, err
+}
+
+func (r *Replicated) GetCommittees(epoch int64) ([]model.CommitteeParticipation, error) {
+	var committees []model.CommitteeParticipation
+	err := r.read("GetCommittees", func(storage Storage) (err error) {
+		committees, err = storage.GetCommittees(epoch)
+		return err
+	})
+	return committees, err
+}
+
+func (r *Replicated) GetMissedSlots(fromSlot int64, toSlot int64) ([]int64, error) {
+	var missed []int64
+	err := r.read("GetMissedSlots", func(storage Storage) (err error) {
+		missed, err = storage.GetMissedSlots(fromSlot, toSlot)
+		return err
+	})
+	return missed, err
+}
+
+func (r *Replicated) GetSlotCoverage(fromSlot int64, toSlot int64) (*SlotCoverage, error) {
+	var coverage *SlotCoverage
+	err := r.read("GetSlotCoverage", func(storage Storage) (err error) {
+		coverage, err = storage.GetSlotCoverage(fromSlot, toSlot)
+		return err
+	})
+	return coverage, err
+}
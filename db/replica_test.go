@@ -0,0 +1,72 @@
+package db
+
+import (
+	"go-beacon-chain-indexer/model"
+	"testing"
+	"time"
+)
+
+func TestReadReplicaFallsBackToPrimary(t *testing.T) {
+	primary := NewMemory()
+	insertRows(t, primary)
+	// Every slot of epoch 12 is missed, which moves the cursor of the primary to it
+	if _, err := primary.InsertEpochData(12, nil, missedFrom(384)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replica has no tables, so every read fails on it
+	replica, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	replicated := &Replicated{Storage: primary, replica: replica}
+	defer replicated.Close()
+	data, err := replicated.GetData(nil)
+	if err != nil || len(data) != 4 {
+		t.Errorf("expected the rows of the primary, got %d: %v", len(data), err)
+	}
+
+	// Once migrated the replica answers, but it has not replayed what the primary indexed yet
+	if _, err = replica.MigrateUp(); err != nil {
+		t.Fatalf("failed to migrate the replica: %v", err)
+	}
+	data, err = replicated.GetData(nil)
+	if err != nil || len(data) != 4 {
+		t.Errorf("expected the rows of the primary while the replica lags, got %d: %v", len(data), err)
+	}
+
+	// A replica indexed up to the cursor of the primary is read, here it only holds epoch 12
+	if _, err = replica.InsertEpochData(12, nil, missedFrom(384)); err != nil {
+		t.Fatal(err)
+	}
+	data, err = replicated.GetData(nil)
+	if err != nil || len(data) != 0 {
+		t.Errorf("expected the replica to be read once it caught up, got %d rows: %v", len(data), err)
+	}
+	if missed, err := replicated.GetMissedSlots(0, 1000); err != nil || len(missed) != 32 {
+		t.Errorf("expected the missed slots of the replica, got %v: %v", missed, err)
+	}
+	if _, err = primary.InsertEpochParticipation(&model.EpochParticipation{Epoch: 10}, []model.CommitteeParticipation{{Epoch: 10, Slot: 320}}); err != nil {
+		t.Fatal(err)
+	}
+	if committees, err := replicated.GetCommittees(10); err != nil || len(committees) != 0 {
+		t.Errorf("expected the committees of the replica, got %v: %v", committees, err)
+	}
+
+	// The lag is only checked again after the interval
+	replicated.lagCheckInterval = time.Hour
+	if _, err = primary.InsertEpochData(13, nil, missedFrom(416)); err != nil {
+		t.Fatal(err)
+	}
+	if committees, _ := replicated.GetCommittees(10); len(committees) != 0 {
+		t.Errorf("expected the replica to be read until the lag is checked again, got %v", committees)
+	}
+	replicated.lagCheckInterval = 0
+	if committees, _ := replicated.GetCommittees(10); len(committees) != 1 {
+		t.Errorf("expected the committees of the primary once the replica lags, got %v", committees)
+	}
+
+	if primaryOnly := WithReadReplica(primary, "", 0); primaryOnly.replica != nil {
+		t.Error("expected every read to go to the primary without a replica url")
+	}
+}
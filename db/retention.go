@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+RetentionPolicy says what Prune deletes and which hypertables are compressed. Committee and attestation rows
+of epochs before their cutoff are deleted, and epoch_participation rows and statistics buckets that start
+before AggregatesBefore, a unix time. A cutoff of 0 deletes nothing, which keeps those rows forever
+*/
+type RetentionPolicy struct {
+	CommitteesBeforeEpoch   int64
+	AttestationsBeforeEpoch int64
+	AggregatesBefore        int64
+	// Chunks of these hypertables are compressed once their data is older than the number of seconds
+	CompressAfter map[string]int64
+}
+
+/*
+RetentionReport counts what a prune deleted and lists the hypertables that have a compression policy
+*/
+type RetentionReport struct {
+	Committees         int64    `json:"committees_deleted"`
+	Attestations       int64    `json:"attestations_deleted"`
+	EpochParticipation int64    `json:"epoch_participation_deleted"`
+	AggregateChunks    int64    `json:"aggregate_chunks_dropped"`
+	Compressed         []string `json:"compressed_tables"`
+}
+
+// The hypertables that can be compressed, with the order their rows are compressed in
+var compressibleTables = map[string]string{
+	"beacon_chain_data":   "slot DESC",
+	"epoch_participation": "epoch DESC",
+}
+
+// The continuous aggregates of the 0004_statistics migration, their old buckets are dropped with aggregates
+var statsAggregates = []string{"block_stats_epoch", "block_stats_hourly", "block_stats_daily", "participation_stats_hourly", "participation_stats_daily"}
+
+/*
+This function checks that every table of a policy can be compressed and returns them in name order
+*/
+func (p RetentionPolicy) compressedTables() ([]string, error) {
+	tables := make([]string, 0, len(p.CompressAfter))
+	for table := range p.CompressAfter {
+		if _, ok := compressibleTables[table]; !ok {
+			return nil, fmt.Errorf("%s is not a hypertable that can be compressed", table)
+		}
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const snapshotFormat = "go-beacon-chain-indexer-snapshot"
+
+/*
+SnapshotVersion is the version of the snapshot archive written by ExportSnapshot. Snapshots of a later version
+are refused by ImportSnapshot, which reads every version up to this one
+*/
+const SnapshotVersion = 1
+
+/*
+SnapshotHeader is the first record of a snapshot: the format, its version and the epochs it holds
+*/
+type SnapshotHeader struct {
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	FromEpoch int64  `json:"from_epoch"`
+	ToEpoch   int64  `json:"to_epoch"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+/*
+snapshotEpoch holds everything stored for an epoch: the rows of beacon_chain_data and missed_slots, and the
+rows of epoch_participation, committees and attestations when its participation was computed
+*/
+type snapshotEpoch struct {
+	Epoch         int64                          `json:"epoch"`
+	Headers       []model.BeaconChainData        `json:"headers,omitempty"`
+	MissedSlots   []int64                        `json:"missed_slots,omitempty"`
+	Participation *model.EpochParticipation      `json:"participation,omitempty"`
+	Committees    []model.CommitteeParticipation `json:"committees,omitempty"`
+}
+
+/*
+SnapshotReport counts what a snapshot export wrote or an import read. Imports also count the rows that were
+inserted, updated and unchanged, over all epochs and tables
+*/
+type SnapshotReport struct {
+	Snapshot      SnapshotHeader `json:"snapshot"`
+	Epochs        int64          `json:"epochs"`
+	Headers       int64          `json:"headers"`
+	MissedSlots   int64          `json:"missed_slots"`
+	Participation int64          `json:"participation"`
+	Committees    int64          `json:"committees"`
+	Inserted      int            `json:"inserted,omitempty"`
+	Updated       int            `json:"updated,omitempty"`
+	Unchanged     int            `json:"unchanged,omitempty"`
+}
+
+func (r *SnapshotReport) add(epoch *snapshotEpoch) {
+	r.Epochs++
+	r.Headers += int64(len(epoch.Headers))
+	r.MissedSlots += int64(len(epoch.MissedSlots))
+	if epoch.Participation != nil {
+		r.Participation++
+	}
+	r.Committees += int64(len(epoch.Committees))
+}
+
+func (r *SnapshotReport) written(counts WriteCounts) {
+	r.Inserted += counts.Inserted
+	r.Updated += counts.Updated
+	r.Unchanged += counts.Unchanged
+}
+
+// The committees of an epoch joined with their attestation, the placeholder is the epoch
+func committeesQuery(placeholder string) string {
+	return "SELECT c.epoch, c.slot, c.committee_index, c.committee_size, COALESCE(a.aggregation_bits, ''), COALESCE(a.participants, 0), COALESCE(a.inclusion_slot, 0)" +
+		" FROM committees c LEFT JOIN attestations a ON a.slot = c.slot AND a.committee_index = c.committee_index WHERE c.epoch = " + placeholder +
+		" ORDER BY c.slot, c.committee_index"
+}
+
+func scanCommittees(rows interface {
+	Next() bool
+	Err() error
+	rowScanner
+}) ([]model.CommitteeParticipation, error) {
+	committees := []model.CommitteeParticipation{}
+	for rows.Next() {
+		var committee model.CommitteeParticipation
+		err := rows.Scan(&committee.Epoch, &committee.Slot, &committee.CommitteeIndex, &committee.CommitteeSize,
+			&committee.AggregationBits, &committee.Participants, &committee.InclusionSlot)
+		if err != nil {
+			return nil, err
+		}
+		committees = append(committees, committee)
+	}
+	return committees, rows.Err()
+}
+
+/*
+This function writes everything stored for the epochs between the two epochs, inclusive, to w as a zstd
+compressed snapshot: a SnapshotHeader followed by one JSON line per epoch that has any rows. A negative epoch
+defaults to the epoch of the first indexed slot or to the indexing cursor, or to the epoch of the last indexed
+slot when nothing was indexed but imported
+*/
+func ExportSnapshot(storage Storage, w io.Writer, fromEpoch int64, toEpoch int64) (*SnapshotReport, error) {
+	firstIndexed, lastIndexed, err := storage.GetIndexedSlotRange()
+	if err != nil {
+		return nil, err
+	}
+	if fromEpoch < 0 {
+		fromEpoch = firstIndexed / spec.SlotsPerEpoch
+	}
+	if toEpoch < 0 {
+		toEpoch, err = storage.GetIndexingCursor()
+		if err != nil {
+			return nil, err
+		}
+	}
+	// History that was only imported has no cursor, it is exported up to the last indexed slot
+	if toEpoch < 0 && lastIndexed >= 0 {
+		toEpoch = lastIndexed / spec.SlotsPerEpoch
+	}
+	report := &SnapshotReport{Snapshot: SnapshotHeader{
+		Format:    snapshotFormat,
+		Version:   SnapshotVersion,
+		FromEpoch: fromEpoch,
+		ToEpoch:   toEpoch,
+		CreatedAt: time.Now().Unix(),
+	}}
+
+	compressor, err := zstd.NewWriter(w)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	encoder := json.NewEncoder(compressor)
+	if err = encoder.Encode(report.Snapshot); err != nil {
+		compressor.Close()
+		logger.LogError(err)
+		return nil, err
+	}
+	for epoch := fromEpoch; epoch <= toEpoch; epoch++ {
+		record, err := readSnapshotEpoch(storage, epoch)
+		if err == nil && (len(record.Headers) > 0 || len(record.MissedSlots) > 0 || record.Participation != nil) {
+			report.add(record)
+			err = encoder.Encode(record)
+		}
+		if err != nil {
+			compressor.Close()
+			logger.LogError(fmt.Errorf("failed to export epoch %v: %w", epoch, err))
+			return nil, err
+		}
+	}
+	if err = compressor.Close(); err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return report, nil
+}
+
+func readSnapshotEpoch(storage Storage, epoch int64) (*snapshotEpoch, error) {
+	fromSlot, toSlot := epoch*spec.SlotsPerEpoch, (epoch+1)*spec.SlotsPerEpoch-1
+	record := &snapshotEpoch{Epoch: epoch}
+	var err error
+	if record.Headers, err = storage.GetIndexedData(fromSlot, toSlot); err != nil {
+		return nil, err
+	}
+	if record.MissedSlots, err = storage.GetMissedSlots(fromSlot, toSlot); err != nil {
+		return nil, err
+	}
+	if record.Participation, err = storage.GetEpochParticipation(epoch); err != nil {
+		return nil, err
+	}
+	if record.Committees, err = storage.GetCommittees(epoch); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+/*
+This function loads a snapshot written by ExportSnapshot. Every epoch is written like the indexer writes it,
+headers with their missed slots and participation with its committees, each in a transaction of its own.
+Writes are upserts, so a snapshot can be imported over rows that are already stored
+*/
+func ImportSnapshot(storage Storage, r io.Reader) (*SnapshotReport, error) {
+	decompressor, err := zstd.NewReader(r)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer decompressor.Close()
+	decoder := json.NewDecoder(bufio.NewReader(decompressor))
+
+	report := &SnapshotReport{}
+	if err = decoder.Decode(&report.Snapshot); err != nil {
+		err = fmt.Errorf("not a snapshot: %w", err)
+		logger.LogError(err)
+		return nil, err
+	}
+	if report.Snapshot.Format != snapshotFormat || report.Snapshot.Version < 1 {
+		err = errors.New("not a snapshot of this indexer")
+		logger.LogError(err)
+		return nil, err
+	}
+	if report.Snapshot.Version > SnapshotVersion {
+		err = fmt.Errorf("snapshot version %v is newer than version %v this build reads", report.Snapshot.Version, SnapshotVersion)
+		logger.LogError(err)
+		return nil, err
+	}
+
+	for {
+		var record snapshotEpoch
+		err = decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err == nil && (record.Epoch < report.Snapshot.FromEpoch || record.Epoch > report.Snapshot.ToEpoch) {
+			err = fmt.Errorf("epoch %v is outside the snapshot range", record.Epoch)
+		}
+		if err == nil {
+			err = importSnapshotEpoch(storage, &record, report)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to import the snapshot after %v epochs: %w", report.Epochs, err)
+			logger.LogError(err)
+			return report, err
+		}
+	}
+}
+
+func importSnapshotEpoch(storage Storage, record *snapshotEpoch, report *SnapshotReport) error {
+	if len(record.Headers) > 0 || len(record.MissedSlots) > 0 {
+		counts, err := storage.ImportEpochData(record.Epoch, record.Headers, record.MissedSlots)
+		if err != nil {
+			return err
+		}
+		report.written(counts)
+	}
+	if record.Participation != nil {
+		counts, err := storage.InsertEpochParticipation(record.Participation, record.Committees)
+		if err != nil {
+			return err
+		}
+		report.written(counts)
+	}
+	report.add(record)
+	return nil
+}
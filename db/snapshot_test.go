@@ -0,0 +1,94 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-beacon-chain-indexer/model"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	source := NewMemory()
+	insertRows(t, source)
+	// Only epochs with every slot stored are below the cursor, which is where an export ends by default
+	if _, err := source.InsertEpochData(10, nil, missedFrom(324)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.InsertEpochData(11, nil, missedFrom(353)); err != nil {
+		t.Fatal(err)
+	}
+	committees := []model.CommitteeParticipation{
+		{Epoch: 10, Slot: 320, CommitteeIndex: 0, CommitteeSize: 4, AggregationBits: "0x1b", Participants: 3, InclusionSlot: 321},
+		{Epoch: 10, Slot: 320, CommitteeIndex: 1, CommitteeSize: 4},
+	}
+	participation := &model.EpochParticipation{Epoch: 10, Participating: 3, Expected: 8, ParticipationRate: 0.375, UnixTime: 1606804223 + 320*12}
+	if _, err := source.InsertEpochParticipation(participation, committees); err != nil {
+		t.Fatalf("failed to store participation: %v", err)
+	}
+	var archive bytes.Buffer
+	exported, err := ExportSnapshot(source, &archive, -1, -1)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if exported.Snapshot.FromEpoch != 10 || exported.Snapshot.ToEpoch != 11 || exported.Epochs != 2 || exported.Headers != 4 ||
+		exported.MissedSlots != 60 || exported.Participation != 1 || exported.Committees != 2 {
+		t.Errorf("unexpected export %+v", exported)
+	}
+
+	testBackends(t, func(t *testing.T, storage Storage) {
+		imported, err := ImportSnapshot(storage, bytes.NewReader(archive.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+		if imported.Epochs != 2 || imported.Headers != 4 || imported.Inserted != 8 || imported.Updated != 0 {
+			t.Errorf("unexpected import %+v", imported)
+		}
+		headers, _ := storage.GetIndexedData(0, 1000)
+		expected, _ := source.GetIndexedData(0, 1000)
+		if !reflect.DeepEqual(headers, expected) {
+			t.Errorf("expected headers %+v, got %+v", expected, headers)
+		}
+		expectedMissed, _ := source.GetMissedSlots(0, 1000)
+		if missed, err := storage.GetMissedSlots(0, 1000); err != nil || len(missed) != 60 || !reflect.DeepEqual(missed, expectedMissed) {
+			t.Errorf("expected slots %v missed, got %v: %v", expectedMissed, missed, err)
+		}
+		if stored, err := storage.GetCommittees(10); err != nil || !reflect.DeepEqual(stored, committees) {
+			t.Errorf("expected committees %+v, got %+v: %v", committees, stored, err)
+		}
+		if stored, err := storage.GetEpochParticipation(10); err != nil || stored == nil || *stored != *participation {
+			t.Errorf("expected participation %+v, got %+v: %v", participation, stored, err)
+		}
+		// Imported epochs were not fetched by the indexer, so they leave its cursor alone
+		if cursor, _ := storage.GetIndexingCursor(); cursor != -1 {
+			t.Errorf("expected the cursor to stay unset, got %v", cursor)
+		}
+		// and an export of the imported history ends at the last indexed slot instead
+		if exported, err := ExportSnapshot(storage, &bytes.Buffer{}, -1, -1); err != nil || exported.Snapshot.ToEpoch != 11 || exported.Epochs != 2 {
+			t.Errorf("unexpected export of the imported epochs %+v: %v", exported, err)
+		}
+
+		// Importing the same snapshot again changes nothing
+		imported, err = ImportSnapshot(storage, bytes.NewReader(archive.Bytes()))
+		if err != nil || imported.Inserted != 0 || imported.Updated != 0 || imported.Unchanged != 8 {
+			t.Errorf("unexpected second import %+v: %v", imported, err)
+		}
+	})
+}
+
+func TestImportSnapshotRejectsNewerVersions(t *testing.T) {
+	var archive bytes.Buffer
+	compressor, _ := zstd.NewWriter(&archive)
+	json.NewEncoder(compressor).Encode(SnapshotHeader{Format: snapshotFormat, Version: SnapshotVersion + 1})
+	compressor.Close()
+	_, err := ImportSnapshot(NewMemory(), &archive)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a newer snapshot to be refused, got %v", err)
+	}
+	if _, err = ImportSnapshot(NewMemory(), strings.NewReader("not a snapshot")); err == nil {
+		t.Error("expected a file that is not a snapshot to be refused")
+	}
+}
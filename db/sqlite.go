@@ -0,0 +1,487 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+/*
+SQLite stores everything in a single local file with the same tables as Postgres
+*/
+type SQLite struct {
+	db *sql.DB
+}
+
+/*
+This function opens or creates the SQLite database at the path, its schema is created by the migrations.
+":memory:" gives a database that lives as long as the storage
+*/
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	// SQLite allows a single writer, and every connection to :memory: would be a database of its own
+	db.SetMaxOpenConns(1)
+	return &SQLite{db: db}, nil
+}
+
+func (db *SQLite) Close() {
+	db.db.Close()
+}
+
+/*
+This method upserts the header rows and missed slots of an epoch and, once every slot of the epoch is stored and
+it follows the indexing cursor, advances the cursor to it, in one transaction
+*/
+func (db *SQLite) InsertEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error) {
+	return db.writeEpochData(epoch, rows, missedSlots, true)
+}
+
+func (db *SQLite) ImportEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error) {
+	return db.writeEpochData(epoch, rows, missedSlots, false)
+}
+
+func (db *SQLite) writeEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64, advanceCursor bool) (WriteCounts, error) {
+	counts := WriteCounts{Epoch: epoch}
+	tx, err := db.db.Begin()
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	defer tx.Rollback()
+	for i := range rows {
+		upsert, err := headerUpsert(&rows[i])
+		if err == nil {
+			err = runUpsert(tx, upsert, &counts)
+		}
+		if err == nil {
+			// A slot that has a block now is no longer missed
+			_, err = tx.Exec("DELETE FROM missed_slots WHERE slot = ?", upsert.values[0])
+		}
+		if err != nil {
+			logger.LogError(err)
+			return counts, err
+		}
+	}
+	for _, slot := range missedSlots {
+		_, err = tx.Exec("INSERT INTO missed_slots (slot, epoch) VALUES (?, ?) ON CONFLICT (slot) DO NOTHING", slot, epoch)
+		if err != nil {
+			logger.LogError(err)
+			return counts, err
+		}
+	}
+	var covered int64
+	if advanceCursor {
+		err = tx.QueryRow(fmt.Sprintf(epochCoveredSlotsQuery, "?", "?"), epoch*spec.SlotsPerEpoch, (epoch+1)*spec.SlotsPerEpoch-1,
+			epoch*spec.SlotsPerEpoch, (epoch+1)*spec.SlotsPerEpoch-1).Scan(&covered)
+	}
+	if err == nil && covered == spec.SlotsPerEpoch {
+		_, err = tx.Exec(
+			"INSERT INTO indexing_cursor (name, epoch, updated_at) VALUES (?, ?, CAST(strftime('%s', 'now') AS INTEGER)) ON CONFLICT (name) DO UPDATE SET epoch = excluded.epoch, updated_at = excluded.updated_at WHERE indexing_cursor.epoch = excluded.epoch - 1",
+			headersCursor, epoch,
+		)
+	}
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	return counts, nil
+}
+
+// runUpsert writes a row with the two statements of an SQLite upsert and counts what they did
+func runUpsert(tx *sql.Tx, upsert upsert, counts *WriteCounts) error {
+	result, err := tx.Exec(upsert.sqliteInsert(), upsert.values...)
+	if err != nil {
+		return err
+	}
+	if inserted, _ := result.RowsAffected(); inserted > 0 {
+		counts.count(true, false)
+		return nil
+	}
+	query, args := upsert.sqliteUpdate()
+	result, err = tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	updated, _ := result.RowsAffected()
+	counts.count(false, updated > 0)
+	return nil
+}
+
+func (db *SQLite) GetIndexingCursor() (int64, error) {
+	var epoch int64
+	err := db.db.QueryRow("SELECT epoch FROM indexing_cursor WHERE name = ?", headersCursor).Scan(&epoch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return 0, err
+	}
+	return epoch, nil
+}
+
+func (db *SQLite) DeleteData() error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		logger.LogError(err)
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"beacon_chain_data", "missed_slots", "indexing_cursor"} {
+		_, err = tx.Exec("DELETE FROM " + table)
+		if err != nil {
+			logger.LogError(err)
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		logger.LogError(err)
+		return err
+	}
+	return nil
+}
+
+func (db *SQLite) GetData(filter *DataFilter) ([]model.BeaconChainData, error) {
+	if filter == nil {
+		return db.queryBeaconData("SELECT " + beaconDataColumns + " FROM beacon_chain_data ORDER BY slot DESC")
+	}
+	// The column is one of filterColumns, never user input
+	return db.queryBeaconData("SELECT "+beaconDataColumns+" FROM beacon_chain_data WHERE "+filter.Column+" = ? ORDER BY slot DESC", filter.Value)
+}
+
+func (db *SQLite) GetIndexedData(fromSlot int64, toSlot int64) ([]model.BeaconChainData, error) {
+	return db.queryBeaconData("SELECT "+beaconDataColumns+" FROM beacon_chain_data WHERE slot BETWEEN ? AND ? ORDER BY slot", fromSlot, toSlot)
+}
+
+func (db *SQLite) queryBeaconData(query string, args ...interface{}) ([]model.BeaconChainData, error) {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	var data []model.BeaconChainData
+	for rows.Next() {
+		beaconData, err := scanBeaconData(rows)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		data = append(data, beaconData)
+	}
+	if err = rows.Err(); err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return data, nil
+}
+
+func (db *SQLite) GetIndexedSlotRange() (int64, int64, error) {
+	var fromSlot, toSlot int64
+	err := db.db.QueryRow("SELECT COALESCE(MIN(slot), -1), COALESCE(MAX(slot), -1) FROM beacon_chain_data").Scan(&fromSlot, &toSlot)
+	if err != nil {
+		logger.LogError(err)
+		return 0, 0, err
+	}
+	return fromSlot, toSlot, nil
+}
+
+func (db *SQLite) GetCanonicalRootBefore(slot int64) (string, error) {
+	var root []byte
+	err := db.db.QueryRow("SELECT root FROM beacon_chain_data WHERE slot < ? AND canonical ORDER BY slot DESC LIMIT 1", slot).Scan(&root)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return "", err
+	}
+	return encodeHex(root), nil
+}
+
+func (db *SQLite) GetProposers(epoch int64) (map[int64]string, error) {
+	rows, err := db.db.Query("SELECT slot, proposer_index FROM beacon_chain_data WHERE epoch = ?", epoch)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	proposers := make(map[int64]string)
+	for rows.Next() {
+		var slot, proposerIndex int64
+		err = rows.Scan(&slot, &proposerIndex)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		proposers[slot] = strconv.FormatInt(proposerIndex, 10)
+	}
+	if err = rows.Err(); err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return proposers, nil
+}
+
+/*
+This method upserts the participation of an epoch in one transaction
+*/
+func (db *SQLite) InsertEpochParticipation(participation *model.EpochParticipation, committees []model.CommitteeParticipation) (WriteCounts, error) {
+	counts := WriteCounts{Epoch: participation.Epoch}
+	tx, err := db.db.Begin()
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	defer tx.Rollback()
+	for _, upsert := range participationUpserts(participation, committees) {
+		err = runUpsert(tx, upsert, &counts)
+		if err != nil {
+			logger.LogError(err)
+			return counts, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		logger.LogError(err)
+		return counts, err
+	}
+	return counts, nil
+}
+
+func (db *SQLite) GetEpochParticipation(epoch int64) (*model.EpochParticipation, error) {
+	var participation model.EpochParticipation
+	err := db.db.QueryRow("SELECT epoch, participating, expected, participation_rate, unix_time FROM epoch_participation WHERE epoch = ?", epoch).
+		Scan(&participation.Epoch, &participation.Participating, &participation.Expected, &participation.ParticipationRate, &participation.UnixTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return &participation, nil
+}
+
+/*
+This method returns the committees of an epoch with their attestation, when one is stored, in slot order
+*/
+func (db *SQLite) GetCommittees(epoch int64) ([]model.CommitteeParticipation, error) {
+	rows, err := db.db.Query(committeesQuery("?"), epoch)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	committees, err := scanCommittees(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return committees, nil
+}
+
+func (db *SQLite) MigrateUp() ([]Migration, error) {
+	return migrateUp(db)
+}
+
+func (db *SQLite) MigrateDown(steps int) ([]Migration, error) {
+	return migrateDown(db, steps)
+}
+
+func (db *SQLite) MigrationStatus() ([]MigrationStatus, error) {
+	return migrationStatus(db)
+}
+
+func (db *SQLite) dialect() string {
+	return "sqlite"
+}
+
+func (db *SQLite) appliedMigrations() (map[int]int64, error) {
+	_, err := db.db.Exec(createMigrationsTable)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	rows, err := db.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]int64)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// SQLite runs schema changes in transactions too, so a failed migration leaves nothing behind
+func (db *SQLite) runMigration(migration Migration, up bool) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if up {
+		_, err = tx.Exec(migration.Up)
+		if err == nil {
+			_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, CAST(strftime('%s', 'now') AS INTEGER))", migration.Version, migration.Name)
+		}
+	} else {
+		_, err = tx.Exec(migration.Down)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/*
+This method computes the statistics of the latest buckets from the tables, SQLite has no continuous aggregates
+*/
+func (db *SQLite) GetStats(bucket string, limit int) ([]model.BucketStats, error) {
+	width, ok := statsBuckets[bucket]
+	if !ok {
+		return nil, ErrInvalidBucket
+	}
+	start := func(unixTime string) string {
+		if width == 0 {
+			return "epoch"
+		}
+		return unixTime + " / " + strconv.FormatInt(width, 10) + " * " + strconv.FormatInt(width, 10)
+	}
+	query := statsQuery(
+		"SELECT "+start("unix_time")+" AS bucket, SUM(CASE WHEN canonical THEN 1 ELSE 0 END) AS blocks, COUNT(*) AS slots FROM beacon_chain_data GROUP BY bucket",
+		"SELECT "+start(missedSlotTime)+" AS bucket, COUNT(*) AS missed FROM missed_slots GROUP BY bucket",
+		"SELECT "+start("unix_time")+" AS bucket, AVG(participation_rate) AS participation_rate FROM epoch_participation GROUP BY bucket", "?")
+	rows, err := db.db.Query(query, limit)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	stats, err := scanStats(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+/*
+This method deletes the rows a retention policy expires, SQLite has no compression or aggregates to drop
+*/
+func (db *SQLite) Prune(policy RetentionPolicy) (*RetentionReport, error) {
+	tables, err := policy.compressedTables()
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	report := &RetentionReport{Compressed: []string{}}
+	if len(tables) > 0 {
+		logger.LogInfo("SQLite does not compress", tables)
+	}
+	tx, err := db.db.Begin()
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, deletion := range []struct {
+		query   string
+		cutoff  int64
+		deleted *int64
+	}{
+		{"DELETE FROM committees WHERE epoch < ?", policy.CommitteesBeforeEpoch, &report.Committees},
+		{"DELETE FROM attestations WHERE epoch < ?", policy.AttestationsBeforeEpoch, &report.Attestations},
+		{"DELETE FROM epoch_participation WHERE unix_time < ?", policy.AggregatesBefore, &report.EpochParticipation},
+	} {
+		if deletion.cutoff <= 0 {
+			continue
+		}
+		result, err := tx.Exec(deletion.query, deletion.cutoff)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		*deletion.deleted, _ = result.RowsAffected()
+	}
+	err = tx.Commit()
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return report, nil
+}
+
+func (db *SQLite) GetSlotCoverage(fromSlot int64, toSlot int64) (*SlotCoverage, error) {
+	coverage := &SlotCoverage{Missing: []int64{}}
+	err := db.db.QueryRow(
+		"SELECT (SELECT COUNT(DISTINCT slot) FROM beacon_chain_data WHERE slot BETWEEN ?1 AND ?2), (SELECT COUNT(*) FROM missed_slots WHERE slot BETWEEN ?1 AND ?2)",
+		fromSlot, toSlot,
+	).Scan(&coverage.Indexed, &coverage.Missed)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	rows, err := db.db.Query(
+		"WITH RECURSIVE series(s) AS (SELECT ?1 WHERE ?1 <= ?2 UNION ALL SELECT s + 1 FROM series WHERE s < ?2) "+
+			"SELECT s FROM series WHERE NOT EXISTS (SELECT 1 FROM beacon_chain_data WHERE slot = s) AND NOT EXISTS (SELECT 1 FROM missed_slots WHERE slot = s) ORDER BY s",
+		fromSlot, toSlot,
+	)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	coverage.Missing, err = scanSlots(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return coverage, nil
+}
+
+/*
+This method returns the slots between the two slots, inclusive, that are known to be missed by their proposer
+*/
+func (db *SQLite) GetMissedSlots(fromSlot int64, toSlot int64) ([]int64, error) {
+	rows, err := db.db.Query("SELECT slot FROM missed_slots WHERE slot BETWEEN ? AND ? ORDER BY slot", fromSlot, toSlot)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	defer rows.Close()
+	slots, err := scanSlots(rows)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return slots, nil
+}
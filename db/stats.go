@@ -0,0 +1,124 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"sort"
+)
+
+var ErrInvalidBucket = errors.New("bucket must be epoch, hour or day")
+
+// The width of the hour and day buckets in seconds, epochs are grouped on their number
+var statsBuckets = map[string]int64{
+	"epoch": 0,
+	"hour":  3600,
+	"day":   86400,
+}
+
+/*
+Missed slots have no header row to take their time from. It follows from the mainnet genesis time and slot
+length, which the indexer stamps rows with and the 0004_statistics migration uses as well
+*/
+const (
+	genesisUnixTime = 1606804223
+	secondsPerSlot  = 12
+)
+
+// missedSlotTime is the SQL expression for the time of a missed slot
+var missedSlotTime = fmt.Sprintf("(%d + slot * %d)", genesisUnixTime, secondsPerSlot)
+
+/*
+This function joins the blocks, the missed slots and the participation of every bucket, latest first. Every
+query returns a bucket column, blocks also return blocks and slots, the canonical blocks and the rows of the
+bucket, missed returns missed and participation a participation_rate. A slot is missed when it is stored in
+missed_slots or its block is not canonical, slots that were not indexed are not counted, so a bucket that is
+only partly indexed does not report the rest of its slots as missed
+*/
+func statsQuery(blocks string, missed string, participation string, limitPlaceholder string) string {
+	return "WITH b AS (" + blocks + "), m AS (" + missed + "), p AS (" + participation + ")" +
+		" SELECT k.bucket AS start, CAST(COALESCE(b.blocks, 0) AS BIGINT), CAST(COALESCE(b.slots - b.blocks, 0) + COALESCE(m.missed, 0) AS BIGINT), p.participation_rate" +
+		" FROM (SELECT bucket FROM b UNION SELECT bucket FROM m UNION SELECT bucket FROM p) k" +
+		" LEFT JOIN b ON b.bucket = k.bucket LEFT JOIN m ON m.bucket = k.bucket LEFT JOIN p ON p.bucket = k.bucket ORDER BY start DESC LIMIT " + limitPlaceholder
+}
+
+func scanStats(rows interface {
+	Next() bool
+	Err() error
+	rowScanner
+}) ([]model.BucketStats, error) {
+	stats := []model.BucketStats{}
+	for rows.Next() {
+		var bucket model.BucketStats
+		if err := rows.Scan(&bucket.Start, &bucket.Blocks, &bucket.MissedSlots, &bucket.ParticipationRate); err != nil {
+			return nil, err
+		}
+		stats = append(stats, bucket)
+	}
+	return stats, rows.Err()
+}
+
+/*
+This method computes the statistics of the latest buckets from the stored maps, the way the SQL backends do
+*/
+func (db *Memory) GetStats(bucket string, limit int) ([]model.BucketStats, error) {
+	width, ok := statsBuckets[bucket]
+	if !ok {
+		return nil, ErrInvalidBucket
+	}
+	start := func(epoch int64, unixTime int64) int64 {
+		if width == 0 {
+			return epoch
+		}
+		return unixTime / width * width
+	}
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	byStart := make(map[int64]*model.BucketStats)
+	bucketOf := func(key int64) *model.BucketStats {
+		if _, ok := byStart[key]; !ok {
+			byStart[key] = &model.BucketStats{Start: key}
+		}
+		return byStart[key]
+	}
+	for _, row := range db.data {
+		collected := bucketOf(start(row.Epoch, row.Data.UnixTime))
+		if row.Data.Canonical {
+			collected.Blocks++
+		} else {
+			collected.MissedSlots++
+		}
+	}
+	for slot := range db.missed {
+		bucketOf(start(slot/spec.SlotsPerEpoch, genesisUnixTime+slot*secondsPerSlot)).MissedSlots++
+	}
+	rates := make(map[int64][]float64)
+	for epoch, participation := range db.participation {
+		key := start(epoch, participation.UnixTime)
+		rates[key] = append(rates[key], participation.ParticipationRate)
+	}
+
+	for key := range rates {
+		bucketOf(key)
+	}
+	stats := []model.BucketStats{}
+	for _, collected := range byStart {
+		stats = append(stats, *collected)
+	}
+	for i := range stats {
+		if bucketRates, ok := rates[stats[i].Start]; ok {
+			sum := 0.0
+			for _, rate := range bucketRates {
+				sum += rate
+			}
+			average := sum / float64(len(bucketRates))
+			stats[i].ParticipationRate = &average
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Start > stats[j].Start })
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
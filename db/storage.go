@@ -0,0 +1,271 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+/*
+Storage holds everything the indexer writes and the API reads: the headers of beacon_chain_data and the
+participation derived from them. Postgres (with TimescaleDB) is the production backend, SQLite and Memory
+need no database server and are meant for development and tests. Headers are written an epoch at a time:
+InsertEpochData stores all the rows and missed slots of an epoch, and advances the indexing cursor to it when
+the epoch is complete and follows the cursor, or nothing at all. ImportEpochData writes an epoch the same way
+but leaves the cursor alone, for history loaded from era files or snapshots that the indexer did not fetch.
+Every write is an upsert, so an epoch can be written again and rows stored exactly as written are left alone
+*/
+type Storage interface {
+	InsertEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error)
+	ImportEpochData(epoch int64, rows []model.BeaconChainData, missedSlots []int64) (WriteCounts, error)
+	GetIndexingCursor() (int64, error)
+	GetSlotCoverage(fromSlot int64, toSlot int64) (*SlotCoverage, error)
+	GetMissedSlots(fromSlot int64, toSlot int64) ([]int64, error)
+	DeleteData() error
+	GetData(filter *DataFilter) ([]model.BeaconChainData, error)
+	GetIndexedData(fromSlot int64, toSlot int64) ([]model.BeaconChainData, error)
+	GetIndexedSlotRange() (int64, int64, error)
+	GetCanonicalRootBefore(slot int64) (string, error)
+	GetProposers(epoch int64) (map[int64]string, error)
+	InsertEpochParticipation(participation *model.EpochParticipation, committees []model.CommitteeParticipation) (WriteCounts, error)
+	GetEpochParticipation(epoch int64) (*model.EpochParticipation, error)
+	GetCommittees(epoch int64) ([]model.CommitteeParticipation, error)
+	GetStats(bucket string, limit int) ([]model.BucketStats, error)
+	Prune(policy RetentionPolicy) (*RetentionReport, error)
+	MigrateUp() ([]Migration, error)
+	MigrateDown(steps int) ([]Migration, error)
+	MigrationStatus() ([]MigrationStatus, error)
+	Close()
+}
+
+/*
+This function opens the storage named by a database url: memory:// for the in memory backend,
+sqlite://<path> for a SQLite file and a postgres:// url otherwise. maxConnections only applies to Postgres
+*/
+func Open(url string, maxConnections int32) (Storage, error) {
+	switch {
+	case url == "memory" || strings.HasPrefix(url, "memory://"):
+		return NewMemory(), nil
+	case strings.HasPrefix(url, "sqlite://"):
+		return OpenSQLite(strings.TrimPrefix(url, "sqlite://"))
+	}
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	if maxConnections > 0 {
+		config.MaxConns = maxConnections
+	}
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return NewPostgres(pool), nil
+}
+
+// The columns of beacon_chain_data rows can be filtered on, with the type of their values
+var filterColumns = map[string]string{
+	"slot":           "integer",
+	"epoch":          "integer",
+	"unix_time":      "integer",
+	"canonical":      "boolean",
+	"root":           "hex",
+	"proposer_index": "integer",
+	"parent_root":    "hex",
+	"state_root":     "hex",
+	"body_root":      "hex",
+	"signature":      "hex",
+}
+
+/*
+DataFilter selects the beacon_chain_data rows whose column equals a value. The column is checked against
+the known columns and the value parsed to the column's type, so neither ends up in a query as text
+*/
+type DataFilter struct {
+	Column string
+	Value  interface{}
+}
+
+func NewDataFilter(column string, value string) (*DataFilter, error) {
+	kind, ok := filterColumns[column]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFilter, column)
+	}
+	switch kind {
+	case "integer":
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be an integer", ErrInvalidFilter, column)
+		}
+		return &DataFilter{Column: column, Value: parsed}, nil
+	case "boolean":
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be true or false", ErrInvalidFilter, column)
+		}
+		return &DataFilter{Column: column, Value: parsed}, nil
+	default:
+		parsed, err := decodeHex(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be 0x prefixed hex", ErrInvalidFilter, column)
+		}
+		return &DataFilter{Column: column, Value: parsed}, nil
+	}
+}
+
+// matches reports whether a row passes the filter, for backends that filter in Go
+func (f *DataFilter) matches(row *model.BeaconChainData) bool {
+	if f == nil {
+		return true
+	}
+	message := row.Data.Header.Message
+	switch f.Column {
+	case "slot":
+		slot, _ := strconv.ParseInt(message.Slot, 10, 64)
+		return slot == f.Value
+	case "epoch":
+		return row.Epoch == f.Value
+	case "unix_time":
+		return row.Data.UnixTime == f.Value
+	case "canonical":
+		return row.Data.Canonical == f.Value
+	case "proposer_index":
+		proposerIndex, err := strconv.ParseInt(message.ProposerIndex, 10, 64)
+		return err == nil && proposerIndex == f.Value
+	}
+	hexColumns := map[string]string{
+		"root":        row.Data.Root,
+		"parent_root": message.ParentRoot,
+		"state_root":  message.StateRoot,
+		"body_root":   message.BodyRoot,
+		"signature":   row.Data.Header.Signature,
+	}
+	value, ok := hexColumns[f.Column]
+	if !ok {
+		return false
+	}
+	decoded, err := decodeHex(value)
+	return err == nil && bytes.Equal(decoded, f.Value.([]byte))
+}
+
+// The columns rows are inserted into beacon_chain_data with, in the order of beaconDataValues. The
+// primary key comes first
+var insertColumns = []string{"slot", "unix_time", "epoch", "root", "canonical", "proposer_index", "parent_root", "state_root", "body_root", "signature"}
+
+/*
+This function returns the values of a row to insert. The slot, epoch and unix time are the ones set on the row,
+the proposer index is stored as a number and roots and the signature as bytes
+*/
+func beaconDataValues(row *model.BeaconChainData) ([]interface{}, error) {
+	message := row.Data.Header.Message
+	slot, err := strconv.ParseInt(message.Slot, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("row has no valid slot %q", message.Slot)
+	}
+	proposerIndex, err := strconv.ParseInt(message.ProposerIndex, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("slot %v has no valid proposer index %q", slot, message.ProposerIndex)
+	}
+	values := []interface{}{slot, row.Data.UnixTime, row.Epoch, nil, row.Data.Canonical, proposerIndex, nil, nil, nil, nil}
+	for i, value := range map[int]string{3: row.Data.Root, 6: message.ParentRoot, 7: message.StateRoot, 8: message.BodyRoot, 9: row.Data.Header.Signature} {
+		values[i], err = decodeHex(value)
+		if err != nil {
+			return nil, fmt.Errorf("slot %v has an invalid %s: %w", slot, insertColumns[i], err)
+		}
+	}
+	return values, nil
+}
+
+// Roots and signatures are 0x prefixed hex at the API and bytes in the database
+func decodeHex(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, "0x") {
+		return nil, fmt.Errorf("%q is not 0x prefixed hex", value)
+	}
+	return hex.DecodeString(value[2:])
+}
+
+func encodeHex(value []byte) string {
+	return "0x" + hex.EncodeToString(value)
+}
+
+/*
+The indexing cursor is the row of indexing_cursor with this name. It holds the latest epoch up to which the
+slots of every epoch are stored, as a header row or a missed slot, and is where indexing resumes. It is set by
+the first complete epoch and then only moves to the epoch right after it, so an epoch that failed is never
+skipped. Epochs written in part, like the one the finalized slot falls in, do not move it
+*/
+const headersCursor = "headers"
+
+// The query counting the stored slots of an epoch, which is complete once it counts spec.SlotsPerEpoch
+const epochCoveredSlotsQuery = "SELECT COUNT(*) FROM (SELECT slot FROM beacon_chain_data WHERE slot BETWEEN %[1]s AND %[2]s UNION SELECT slot FROM missed_slots WHERE slot BETWEEN %[1]s AND %[2]s) covered"
+
+/*
+SlotCoverage tells how much of a slot range is stored: slots with a header row, slots known to be missed by
+their proposer, and the missing slots that have neither because they were never fetched or failed to be
+*/
+type SlotCoverage struct {
+	Indexed int64   `json:"indexed"`
+	Missed  int64   `json:"missed"`
+	Missing []int64 `json:"missing"`
+}
+
+// rowScanner is a row of either pgx or database/sql
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSlots(rows interface {
+	Next() bool
+	Err() error
+	rowScanner
+}) ([]int64, error) {
+	slots := []int64{}
+	for rows.Next() {
+		var slot int64
+		if err := rows.Scan(&slot); err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return slots, rows.Err()
+}
+
+const beaconDataColumns = "slot, root, canonical, proposer_index, parent_root, state_root, body_root, signature, unix_time, epoch"
+
+func scanBeaconData(row rowScanner) (model.BeaconChainData, error) {
+	var slot, proposerIndex int64
+	var root, parentRoot, stateRoot, bodyRoot, signature []byte
+	var beaconData model.BeaconChainData
+	err := row.Scan(
+		&slot,
+		&root,
+		&beaconData.Data.Canonical,
+		&proposerIndex,
+		&parentRoot,
+		&stateRoot,
+		&bodyRoot,
+		&signature,
+		&beaconData.Data.UnixTime,
+		&beaconData.Epoch,
+	)
+	beaconData.Data.Root = encodeHex(root)
+	beaconData.Data.Header.Message.Slot = strconv.FormatInt(slot, 10)
+	beaconData.Data.Header.Message.ProposerIndex = strconv.FormatInt(proposerIndex, 10)
+	beaconData.Data.Header.Message.ParentRoot = encodeHex(parentRoot)
+	beaconData.Data.Header.Message.StateRoot = encodeHex(stateRoot)
+	beaconData.Data.Header.Message.BodyRoot = encodeHex(bodyRoot)
+	beaconData.Data.Header.Signature = encodeHex(signature)
+	return beaconData, err
+}
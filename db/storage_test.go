@@ -0,0 +1,377 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/model"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/*
+Every backend must behave the same, so the same checks run against each one. Postgres needs a server and
+is only checked when TEST_DATABASE_URL is set, see openTestPostgres
+*/
+func testBackends(t *testing.T, test func(t *testing.T, storage Storage)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		storage, err := OpenSQLite(":memory:")
+		if err != nil {
+			t.Fatalf("failed to open sqlite: %v", err)
+		}
+		defer storage.Close()
+		if _, err = storage.MigrateUp(); err != nil {
+			t.Fatalf("failed to migrate sqlite: %v", err)
+		}
+		test(t, storage)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, openTestPostgres(t))
+	})
+}
+
+var testSignature = "0x" + strings.Repeat("c5", 96)
+
+func testRoot(slot int64) string {
+	return fmt.Sprintf("0x%064x", slot)
+}
+
+func testRow(slot int64, proposerIndex string, canonical bool) model.BeaconChainData {
+	var row model.BeaconChainData
+	row.Epoch = slot / 32
+	row.Data.UnixTime = 1606804223 + slot*12
+	row.Data.Header.Message.Slot = strconv.FormatInt(slot, 10)
+	row.Data.Root = testRoot(slot)
+	row.Data.Canonical = canonical
+	row.Data.Header.Message.ProposerIndex = proposerIndex
+	row.Data.Header.Message.ParentRoot = testRoot(slot - 1)
+	row.Data.Header.Message.StateRoot = "0x" + strings.Repeat("5a", 32)
+	row.Data.Header.Message.BodyRoot = "0x" + strings.Repeat("b0", 32)
+	row.Data.Header.Signature = testSignature
+	return row
+}
+
+func insertRows(t *testing.T, storage Storage) {
+	t.Helper()
+	_, err := storage.InsertEpochData(10, []model.BeaconChainData{testRow(320, "11", true), testRow(321, "12", true), testRow(323, "11", false)}, []int64{322})
+	if err == nil {
+		_, err = storage.InsertEpochData(11, []model.BeaconChainData{testRow(352, "13", true)}, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to insert rows: %v", err)
+	}
+}
+
+func TestInsertAndGetData(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		insertRows(t, storage)
+		// Neither epoch has all of its slots stored
+		if cursor, err := storage.GetIndexingCursor(); err != nil || cursor != -1 {
+			t.Errorf("expected no cursor, got %v: %v", cursor, err)
+		}
+		// A failed epoch writes none of its rows and leaves the cursor where it was
+		invalid := testRow(385, "15", true)
+		invalid.Data.Header.Message.Slot = "slot"
+		if _, err := storage.InsertEpochData(12, []model.BeaconChainData{testRow(384, "14", true), invalid}, []int64{386}); err == nil {
+			t.Error("expected a row without a valid slot to fail the epoch")
+		}
+		if coverage, _ := storage.GetSlotCoverage(384, 386); coverage.Indexed != 0 || coverage.Missed != 0 {
+			t.Errorf("rows of a failed epoch were stored: %+v", coverage)
+		}
+		if cursor, _ := storage.GetIndexingCursor(); cursor != -1 {
+			t.Errorf("a failed epoch moved the cursor to %v", cursor)
+		}
+
+		all, err := storage.GetData(nil)
+		if err != nil || len(all) != 4 || all[0].Data.Header.Message.Slot != "352" || all[3].Data.Header.Message.Slot != "320" {
+			t.Fatalf("expected every row latest first, got %+v: %v", all, err)
+		}
+		first := all[3]
+		if first.Epoch != 10 || first.Data.UnixTime != 1606804223+320*12 || first.Data.Root != testRoot(320) || !first.Data.Canonical || first.Data.Header.Signature != testSignature {
+			t.Errorf("row was not stored as inserted: %+v", first)
+		}
+
+		for _, filter := range []struct {
+			column string
+			value  string
+			slots  []string
+		}{
+			{"proposer_index", "11", []string{"323", "320"}},
+			{"epoch", "10", []string{"323", "321", "320"}},
+			{"canonical", "false", []string{"323"}},
+			{"root", testRoot(352), []string{"352"}},
+			{"root", "0x" + strings.ToUpper(testRoot(352)[2:]), []string{"352"}},
+			{"proposer_index", "011", []string{"323", "320"}},
+		} {
+			dataFilter, err := NewDataFilter(filter.column, filter.value)
+			if err != nil {
+				t.Fatalf("invalid filter %s=%s: %v", filter.column, filter.value, err)
+			}
+			rows, err := storage.GetData(dataFilter)
+			if err != nil {
+				t.Fatalf("failed to filter on %s: %v", filter.column, err)
+			}
+			var slots []string
+			for _, row := range rows {
+				slots = append(slots, row.Data.Header.Message.Slot)
+			}
+			if strings.Join(slots, ",") != strings.Join(filter.slots, ",") {
+				t.Errorf("filter %s=%s returned slots %v, expected %v", filter.column, filter.value, slots, filter.slots)
+			}
+		}
+
+		if err = storage.DeleteData(); err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+		from, to, err := storage.GetIndexedSlotRange()
+		if err != nil || from != -1 || to != -1 {
+			t.Errorf("expected an empty range after deleting, got %v to %v: %v", from, to, err)
+		}
+		if cursor, _ := storage.GetIndexingCursor(); cursor != -1 {
+			t.Errorf("expected the cursor to be reset after deleting, got %v", cursor)
+		}
+	})
+}
+
+// missedFrom returns the slots from the slot to the end of its epoch
+func missedFrom(slot int64) []int64 {
+	var missed []int64
+	for ; slot%32 != 0 || len(missed) == 0; slot++ {
+		missed = append(missed, slot)
+	}
+	return missed
+}
+
+func TestIndexingCursorOnlyCoversCompleteEpochs(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		cursor := func() int64 {
+			cursor, err := storage.GetIndexingCursor()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cursor
+		}
+		// The first complete epoch sets the cursor
+		if _, err := storage.InsertEpochData(11, nil, missedFrom(352)); err != nil {
+			t.Fatal(err)
+		}
+		if cursor := cursor(); cursor != 11 {
+			t.Errorf("expected the cursor at epoch 11, got %v", cursor)
+		}
+		// The epoch of the finalized slot is written up to that slot first
+		if _, err := storage.InsertEpochData(12, []model.BeaconChainData{testRow(384, "14", true)}, []int64{385}); err != nil {
+			t.Fatal(err)
+		}
+		if cursor := cursor(); cursor != 11 {
+			t.Errorf("a partly written epoch moved the cursor to %v", cursor)
+		}
+		// A complete epoch after an incomplete one does not skip it
+		if _, err := storage.InsertEpochData(13, nil, missedFrom(416)); err != nil {
+			t.Fatal(err)
+		}
+		if cursor := cursor(); cursor != 11 {
+			t.Errorf("a complete epoch after a gap moved the cursor to %v", cursor)
+		}
+		// The next run writes it again with the rest of its slots
+		if _, err := storage.InsertEpochData(12, []model.BeaconChainData{testRow(384, "14", true), testRow(386, "15", true)}, append([]int64{385}, missedFrom(387)...)); err != nil {
+			t.Fatal(err)
+		}
+		if cursor := cursor(); cursor != 12 {
+			t.Errorf("expected the cursor at epoch 12, got %v", cursor)
+		}
+		// Writing an earlier complete epoch, as repair does, never moves the cursor back
+		if _, err := storage.InsertEpochData(11, nil, missedFrom(352)); err != nil {
+			t.Fatal(err)
+		}
+		if cursor := cursor(); cursor != 12 {
+			t.Errorf("an earlier epoch moved the cursor back to %v", cursor)
+		}
+	})
+}
+
+func TestIndexedQueries(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		insertRows(t, storage)
+		from, to, err := storage.GetIndexedSlotRange()
+		if err != nil || from != 320 || to != 352 {
+			t.Errorf("unexpected indexed range %v to %v: %v", from, to, err)
+		}
+		rows, err := storage.GetIndexedData(321, 352)
+		if err != nil || len(rows) != 3 || rows[0].Data.Header.Message.Slot != "321" {
+			t.Errorf("unexpected rows %+v: %v", rows, err)
+		}
+		// The row of slot 323 is not canonical
+		root, err := storage.GetCanonicalRootBefore(352)
+		if err != nil || root != testRoot(321) {
+			t.Errorf("unexpected canonical root %q: %v", root, err)
+		}
+		root, err = storage.GetCanonicalRootBefore(320)
+		if err != nil || root != "" {
+			t.Errorf("expected no canonical root before the first row, got %q: %v", root, err)
+		}
+		proposers, err := storage.GetProposers(10)
+		if err != nil || len(proposers) != 3 || proposers[321] != "12" {
+			t.Errorf("unexpected proposers %v: %v", proposers, err)
+		}
+	})
+}
+
+func TestEpochParticipation(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		stored, err := storage.GetEpochParticipation(10)
+		if err != nil || stored != nil {
+			t.Fatalf("expected no participation before it is stored, got %+v: %v", stored, err)
+		}
+		committees := []model.CommitteeParticipation{
+			{Epoch: 10, Slot: 320, CommitteeIndex: 0, CommitteeSize: 4, AggregationBits: "0x1b", Participants: 3, InclusionSlot: 321},
+			{Epoch: 10, Slot: 320, CommitteeIndex: 1, CommitteeSize: 4},
+		}
+		// Storing an epoch again updates the rows that changed, here only the epoch totals
+		for _, write := range []struct {
+			participating int64
+			expected      WriteCounts
+		}{
+			{2, WriteCounts{Epoch: 10, Inserted: 4}},
+			{2, WriteCounts{Epoch: 10, Unchanged: 4}},
+			{3, WriteCounts{Epoch: 10, Updated: 1, Unchanged: 3}},
+		} {
+			participation := &model.EpochParticipation{Epoch: 10, Participating: write.participating, Expected: 8, ParticipationRate: float64(write.participating) / 8}
+			counts, err := storage.InsertEpochParticipation(participation, committees)
+			if err != nil {
+				t.Fatalf("failed to store participation: %v", err)
+			}
+			if counts != write.expected {
+				t.Errorf("expected %+v, got %+v", write.expected, counts)
+			}
+		}
+		stored, err = storage.GetEpochParticipation(10)
+		if err != nil || stored == nil || stored.Participating != 3 || stored.Expected != 8 || stored.ParticipationRate != 0.375 {
+			t.Errorf("unexpected participation %+v: %v", stored, err)
+		}
+	})
+}
+
+func TestInsertEpochDataIsIdempotent(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		insertRows(t, storage)
+		changed := testRow(321, "12", false)
+		counts, err := storage.InsertEpochData(10, []model.BeaconChainData{testRow(320, "11", true), changed, testRow(322, "14", true), testRow(323, "11", false)}, nil)
+		if err != nil {
+			t.Fatalf("failed to write the epoch again: %v", err)
+		}
+		if counts != (WriteCounts{Epoch: 10, Inserted: 1, Updated: 1, Unchanged: 2}) {
+			t.Errorf("unexpected counts %+v", counts)
+		}
+		rows, err := storage.GetIndexedData(320, 323)
+		if err != nil || len(rows) != 4 || rows[1].Data.Canonical || rows[2].Data.Header.Message.ProposerIndex != "14" {
+			t.Errorf("rows were not upserted: %+v: %v", rows, err)
+		}
+		// Slot 322 was missed before it had a block
+		if coverage, err := storage.GetSlotCoverage(320, 323); err != nil || coverage.Indexed != 4 || coverage.Missed != 0 {
+			t.Errorf("expected every slot to have a row, got %+v: %v", coverage, err)
+		}
+	})
+}
+
+func TestSlotCoverage(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		insertRows(t, storage)
+		coverage, err := storage.GetSlotCoverage(320, 352)
+		if err != nil || coverage.Indexed != 4 || coverage.Missed != 1 || len(coverage.Missing) != 28 || coverage.Missing[0] != 324 || coverage.Missing[27] != 351 {
+			t.Errorf("unexpected coverage %+v: %v", coverage, err)
+		}
+		coverage, err = storage.GetSlotCoverage(352, 351)
+		if err != nil || coverage.Indexed != 0 || len(coverage.Missing) != 0 {
+			t.Errorf("expected nothing in an empty range, got %+v: %v", coverage, err)
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		insertRows(t, storage)
+		participation := &model.EpochParticipation{Epoch: 10, Participating: 4, Expected: 8, ParticipationRate: 0.5, UnixTime: 1606804223 + 320*12}
+		if _, err := storage.InsertEpochParticipation(participation, nil); err != nil {
+			t.Fatalf("failed to store participation: %v", err)
+		}
+		rate := func(stats model.BucketStats) string {
+			if stats.ParticipationRate == nil {
+				return "none"
+			}
+			return strconv.FormatFloat(*stats.ParticipationRate, 'f', -1, 64)
+		}
+
+		// The last slot of epoch 10 is missed after its last block and every slot of epoch 12 is missed
+		if _, err := storage.InsertEpochData(10, nil, []int64{351}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := storage.InsertEpochData(12, nil, missedFrom(384)); err != nil {
+			t.Fatal(err)
+		}
+
+		epochs, err := storage.GetStats("epoch", 10)
+		// Slots 322 and 351 are missed and slot 323 is not canonical, the slots between were not indexed
+		if err != nil || len(epochs) != 3 || epochs[0].Start != 12 || epochs[0].Blocks != 0 || epochs[0].MissedSlots != 32 ||
+			epochs[1].Start != 11 || epochs[1].Blocks != 1 || epochs[1].MissedSlots != 0 || rate(epochs[1]) != "none" ||
+			epochs[2].Start != 10 || epochs[2].Blocks != 2 || epochs[2].MissedSlots != 3 || rate(epochs[2]) != "0.5" {
+			t.Errorf("unexpected epoch stats %+v: %v", epochs, err)
+		}
+		days, err := storage.GetStats("day", 10)
+		if err != nil || len(days) != 1 || days[0].Start != 1606780800 || days[0].Blocks != 3 || days[0].MissedSlots != 35 || rate(days[0]) != "0.5" {
+			t.Errorf("unexpected daily stats %+v: %v", days, err)
+		}
+		hours, err := storage.GetStats("hour", 10)
+		if err != nil || len(hours) != 1 || hours[0].Start != 1606806000 || hours[0].Blocks != 3 || hours[0].MissedSlots != 35 {
+			t.Errorf("unexpected hourly stats %+v: %v", hours, err)
+		}
+		if limited, err := storage.GetStats("epoch", 1); err != nil || len(limited) != 1 || limited[0].Start != 12 {
+			t.Errorf("expected only the latest epoch, got %+v: %v", limited, err)
+		}
+		if _, err = storage.GetStats("week", 10); !errors.Is(err, ErrInvalidBucket) {
+			t.Errorf("expected an unknown bucket to be rejected, got %v", err)
+		}
+	})
+}
+
+func TestPrune(t *testing.T) {
+	testBackends(t, func(t *testing.T, storage Storage) {
+		for epoch := int64(10); epoch <= 11; epoch++ {
+			participation := &model.EpochParticipation{Epoch: epoch, Participating: 3, Expected: 4, ParticipationRate: 0.75, UnixTime: 1606804223 + epoch*384}
+			committees := []model.CommitteeParticipation{
+				{Epoch: epoch, Slot: epoch * 32, CommitteeIndex: 0, CommitteeSize: 4, AggregationBits: "0x17", Participants: 3, InclusionSlot: epoch*32 + 1},
+			}
+			if _, err := storage.InsertEpochParticipation(participation, committees); err != nil {
+				t.Fatalf("failed to store participation: %v", err)
+			}
+		}
+		if _, err := storage.Prune(RetentionPolicy{CompressAfter: map[string]int64{"committees": 86400}}); err == nil {
+			t.Error("expected a table that is not a hypertable to be rejected for compression")
+		}
+
+		report, err := storage.Prune(RetentionPolicy{AttestationsBeforeEpoch: 11})
+		if err != nil || report.Attestations != 1 || report.Committees != 0 || report.EpochParticipation != 0 {
+			t.Errorf("expected only the attestation of epoch 10 to be pruned, got %+v: %v", report, err)
+		}
+		report, err = storage.Prune(RetentionPolicy{CommitteesBeforeEpoch: 11, AggregatesBefore: 1606804223 + 11*384})
+		if err != nil || report.Committees != 1 || report.EpochParticipation != 1 {
+			t.Errorf("expected the committee and participation of epoch 10 to be pruned, got %+v: %v", report, err)
+		}
+		if stored, _ := storage.GetEpochParticipation(10); stored != nil {
+			t.Errorf("participation of epoch 10 was kept: %+v", stored)
+		}
+		if stored, _ := storage.GetEpochParticipation(11); stored == nil {
+			t.Error("participation of epoch 11 was pruned")
+		}
+	})
+}
+
+func TestDataFilterRejectsUnknownColumns(t *testing.T) {
+	for _, filter := range [][2]string{{"slot; DROP TABLE beacon_chain_data", "1"}, {"slot", "1 OR 1=1"}, {"canonical", "maybe"}, {"proposer_index", "0x11"}, {"root", "root352"}, {"signature", "0xzz"}} {
+		if _, err := NewDataFilter(filter[0], filter[1]); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("expected filter %s=%s to be rejected, got %v", filter[0], filter[1], err)
+		}
+	}
+}
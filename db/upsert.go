@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/model"
+	"strings"
+)
+
+/*
+WriteCounts tells how many rows a write of an epoch inserted, how many already stored rows it changed and how
+many it found stored exactly as written. Writes are upserts, so writing the same epoch again only counts unchanged rows
+*/
+type WriteCounts struct {
+	Epoch     int64 `json:"epoch"`
+	Inserted  int   `json:"inserted"`
+	Updated   int   `json:"updated"`
+	Unchanged int   `json:"unchanged"`
+}
+
+func (c *WriteCounts) count(inserted bool, updated bool) {
+	switch {
+	case inserted:
+		c.Inserted++
+	case updated:
+		c.Updated++
+	default:
+		c.Unchanged++
+	}
+}
+
+/*
+upsert is the write of one row to a table whose primary key is keys. Values are in the order of columns,
+which start with the keys
+*/
+type upsert struct {
+	table   string
+	keys    []string
+	columns []string
+	values  []interface{}
+}
+
+// The columns an upsert changes when its row is already stored
+func (u upsert) updated() []string {
+	return u.columns[len(u.keys):]
+}
+
+/*
+This function returns the ON CONFLICT clause of a Postgres upsert. Rows that are stored exactly as written are
+not touched, and every row that is written is returned with whether it was inserted, xmax is 0 for a new row
+*/
+func postgresConflictClause(table string, keys []string, updated []string) string {
+	set := make([]string, len(updated))
+	stored := make([]string, len(updated))
+	written := make([]string, len(updated))
+	for i, column := range updated {
+		set[i] = column + " = excluded." + column
+		stored[i] = table + "." + column
+		written[i] = "excluded." + column
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s WHERE (%s) IS DISTINCT FROM (%s) RETURNING (xmax = 0)",
+		strings.Join(keys, ", "), strings.Join(set, ", "), strings.Join(stored, ", "), strings.Join(written, ", "))
+}
+
+func (u upsert) postgres() string {
+	placeholders := make([]string, len(u.columns))
+	for i := range u.columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s",
+		u.table, strings.Join(u.columns, ", "), strings.Join(placeholders, ", "), postgresConflictClause(u.table, u.keys, u.updated()))
+}
+
+/*
+These methods return the two statements of an upsert in SQLite, which can not tell inserted and updated rows
+apart in one statement: an insert that does nothing when the key is stored, then an update of the stored row
+that only matches when one of its columns differs
+*/
+func (u upsert) sqliteInsert() string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s) ON CONFLICT DO NOTHING",
+		u.table, strings.Join(u.columns, ", "), strings.Repeat(", ?", len(u.columns)-1))
+}
+
+func (u upsert) sqliteUpdate() (string, []interface{}) {
+	updated := u.updated()
+	set := make([]string, len(updated))
+	differs := make([]string, len(updated))
+	for i, column := range updated {
+		set[i] = column + " = ?"
+		differs[i] = column + " IS NOT ?"
+	}
+	where := make([]string, len(u.keys))
+	for i, key := range u.keys {
+		where[i] = key + " = ?"
+	}
+	args := append(append(append([]interface{}{}, u.values[len(u.keys):]...), u.values[:len(u.keys)]...), u.values[len(u.keys):]...)
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s AND (%s)",
+		u.table, strings.Join(set, ", "), strings.Join(where, " AND "), strings.Join(differs, " OR ")), args
+}
+
+func headerUpsert(row *model.BeaconChainData) (upsert, error) {
+	values, err := beaconDataValues(row)
+	if err != nil {
+		return upsert{}, err
+	}
+	return upsert{table: "beacon_chain_data", keys: []string{"slot", "unix_time"}, columns: insertColumns, values: values}, nil
+}
+
+/*
+This function returns the upserts of the participation of an epoch: a committees row for every committee, an
+attestations row for every committee that attested and the epoch_participation row, last
+*/
+func participationUpserts(participation *model.EpochParticipation, committees []model.CommitteeParticipation) []upsert {
+	var upserts []upsert
+	for _, committee := range committees {
+		upserts = append(upserts, upsert{
+			table:   "committees",
+			keys:    []string{"slot", "committee_index"},
+			columns: []string{"slot", "committee_index", "epoch", "committee_size"},
+			values:  []interface{}{committee.Slot, committee.CommitteeIndex, committee.Epoch, committee.CommitteeSize},
+		})
+		if committee.AggregationBits != "" {
+			upserts = append(upserts, upsert{
+				table:   "attestations",
+				keys:    []string{"slot", "committee_index"},
+				columns: []string{"slot", "committee_index", "epoch", "aggregation_bits", "participants", "inclusion_slot"},
+				values:  []interface{}{committee.Slot, committee.CommitteeIndex, committee.Epoch, committee.AggregationBits, committee.Participants, committee.InclusionSlot},
+			})
+		}
+	}
+	return append(upserts, upsert{
+		table:   "epoch_participation",
+		keys:    []string{"epoch", "unix_time"},
+		columns: []string{"epoch", "unix_time", "participating", "expected", "participation_rate"},
+		values:  []interface{}{participation.Epoch, participation.UnixTime, participation.Participating, participation.Expected, participation.ParticipationRate},
+	})
+}
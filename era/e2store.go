@@ -0,0 +1,76 @@
+package era
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Record types of the e2store format used by .era and .era1 files
+const (
+	TypeEmpty                       = 0x0000
+	TypeCompressedSignedBeaconBlock = 0x0100
+	TypeCompressedBeaconState       = 0x0200
+	TypeVersion                     = 0x6532
+	TypeSlotIndex                   = 0x6932
+
+	headerSize = 8
+)
+
+var ErrInvalidHeader = errors.New("e2store: invalid record header")
+
+/*
+Record is the header of one e2store record: a type, the length of the data that follows and two reserved
+bytes that must be zero
+*/
+type Record struct {
+	Type   uint16
+	Length uint32
+}
+
+/*
+Reader walks the records of an e2store file in order. The data of a record is only read when the caller
+asks for it, so large records such as states can be streamed or skipped without holding them in memory
+*/
+type Reader struct {
+	reader *bufio.Reader
+	data   *io.LimitedReader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReaderSize(r, 1<<16)}
+}
+
+/*
+This method returns the next record and a reader for its data, or io.EOF after the last record. Whatever
+was not read of the previous record's data is skipped
+*/
+func (r *Reader) Next() (Record, io.Reader, error) {
+	if r.data != nil && r.data.N > 0 {
+		_, err := r.reader.Discard(int(r.data.N))
+		if err != nil {
+			return Record{}, nil, fmt.Errorf("e2store: skipping record data: %w", err)
+		}
+	}
+	r.data = nil
+	header := make([]byte, headerSize)
+	_, err := io.ReadFull(r.reader, header)
+	if err == io.EOF {
+		return Record{}, nil, io.EOF
+	}
+	if err != nil {
+		return Record{}, nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	// The type is the first two bytes as they appear in the file, which is why the version record is "e2"
+	record := Record{
+		Type:   binary.BigEndian.Uint16(header[0:2]),
+		Length: binary.LittleEndian.Uint32(header[2:6]),
+	}
+	if header[6] != 0 || header[7] != 0 {
+		return Record{}, nil, fmt.Errorf("%w: reserved bytes are not zero in a record of type %#04x", ErrInvalidHeader, record.Type)
+	}
+	r.data = &io.LimitedReader{R: r.reader, N: int64(record.Length)}
+	return record, r.data, nil
+}
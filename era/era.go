@@ -0,0 +1,60 @@
+package era
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+var ErrNotEraFile = errors.New("era: file does not start with a version record")
+
+/*
+Visitor receives the content of an era file. Blocks are SSZ encoded SignedBeaconBlocks of any fork, the
+state is the SSZ encoded BeaconState at the end of the era and is handed over as a stream since it can be
+hundreds of megabytes
+*/
+type Visitor struct {
+	Block func(block []byte) error
+	State func(state io.Reader) error
+}
+
+/*
+This function reads an era file: a version record, the blocks of the era in slot order, the state at the
+first slot after the era and the slot indices of both. Blocks and the state are compressed with framed
+snappy. Indices and unknown records are skipped
+*/
+func Read(r io.Reader, visitor Visitor) error {
+	records := NewReader(r)
+	record, _, err := records.Next()
+	if err == io.EOF || (err == nil && record.Type != TypeVersion) {
+		return ErrNotEraFile
+	}
+	if err != nil {
+		return err
+	}
+	for {
+		record, data, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch record.Type {
+		case TypeCompressedSignedBeaconBlock:
+			block, err := io.ReadAll(snappy.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("era: decompressing block: %w", err)
+			}
+			if err = visitor.Block(block); err != nil {
+				return err
+			}
+		case TypeCompressedBeaconState:
+			if err = visitor.State(snappy.NewReader(data)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+func appendRecord(t *testing.T, file *bytes.Buffer, recordType uint16, data []byte, compress bool) {
+	if compress {
+		var compressed bytes.Buffer
+		writer := snappy.NewBufferedWriter(&compressed)
+		if _, err := writer.Write(data); err != nil {
+			t.Fatalf("failed to compress: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("failed to compress: %v", err)
+		}
+		data = compressed.Bytes()
+	}
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(header, recordType)
+	binary.LittleEndian.PutUint32(header[2:], uint32(len(data)))
+	file.Write(header)
+	file.Write(data)
+}
+
+func TestRead(t *testing.T) {
+	var file bytes.Buffer
+	appendRecord(t, &file, TypeVersion, nil, false)
+	appendRecord(t, &file, TypeCompressedSignedBeaconBlock, []byte("first block"), true)
+	appendRecord(t, &file, TypeEmpty, []byte{1, 2, 3}, false)
+	appendRecord(t, &file, TypeCompressedSignedBeaconBlock, []byte("second block"), true)
+	appendRecord(t, &file, TypeCompressedBeaconState, bytes.Repeat([]byte("state"), 100000), true)
+	appendRecord(t, &file, TypeSlotIndex, make([]byte, 24), false)
+
+	var blocks []string
+	var state []byte
+	err := Read(&file, Visitor{
+		Block: func(block []byte) error {
+			blocks = append(blocks, string(block))
+			return nil
+		},
+		State: func(r io.Reader) error {
+			// Only part of the state is read, the rest must be skipped
+			state = make([]byte, 10)
+			_, err := io.ReadFull(r, state)
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to read era file: %v", err)
+	}
+	if len(blocks) != 2 || blocks[0] != "first block" || blocks[1] != "second block" {
+		t.Errorf("unexpected blocks %q", blocks)
+	}
+	if string(state) != "statestate" {
+		t.Errorf("unexpected state prefix %q", state)
+	}
+}
+
+func TestReadRejectsOtherFiles(t *testing.T) {
+	var file bytes.Buffer
+	appendRecord(t, &file, TypeCompressedSignedBeaconBlock, []byte("block"), true)
+	if err := Read(&file, Visitor{}); !errors.Is(err, ErrNotEraFile) {
+		t.Errorf("expected ErrNotEraFile, got %v", err)
+	}
+
+	file.Reset()
+	appendRecord(t, &file, TypeVersion, nil, false)
+	file.Write([]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00})
+	if err := Read(&file, Visitor{}); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
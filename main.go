@@ -1,10 +1,9 @@
 package main
 
 import (
-	"context"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/joho/godotenv"
 	"go-beacon-chain-indexer/controller"
+	"go-beacon-chain-indexer/db"
 	"go-beacon-chain-indexer/logger"
 	"go-beacon-chain-indexer/service"
 	"net/http"
@@ -20,30 +19,56 @@ func main() {
 		logger.LogError(err)
 	}
 
-	connConfig, err := pgxpool.ParseConfig(os.Getenv("DATABASE_URL"))
+	maxConnections, _ := strconv.ParseInt(os.Getenv("MAX_CONNECTIONS"), 10, 32) // Adjust the maximum number of open connections
+	storage, err := db.Open(os.Getenv("DATABASE_URL"), int32(maxConnections))
 	if err != nil {
 		logger.LogError(err)
+		return
 	}
-	maxConnections, _ := strconv.ParseInt(os.Getenv("MAX_CONNECTIONS"), 10, 32) // Adjust the maximum number of open connections
-	connConfig.MaxConns = int32(maxConnections)
-	pool, err := pgxpool.ConnectConfig(context.Background(), connConfig)
+	defer storage.Close()
+	migrate, err := strconv.ParseBool(os.Getenv("MIGRATE_ON_STARTUP"))
+	if (err != nil || migrate) && !(len(os.Args) > 1 && os.Args[1] == "migrate") {
+		if _, err = storage.MigrateUp(); err != nil {
+			logger.LogError(err)
+			return
+		}
+	}
+
+	s, err := service.NewService(storage)
 	if err != nil {
 		logger.LogError(err)
+		return
+	}
+	if len(os.Args) > 1 {
+		code := runCommand(os.Args[1], os.Args[2:], s, storage)
+		// os.Exit skips the deferred close
+		storage.Close()
+		os.Exit(code)
 	}
-	defer pool.Close()
+	// The API reads from the read replica when one is configured, ingestion keeps the pool of the primary
+	readMaxConnections, _ := strconv.ParseInt(os.Getenv("READ_MAX_CONNECTIONS"), 10, 32)
+	readStorage := db.WithReadReplica(storage, os.Getenv("DATABASE_READ_URL"), int32(readMaxConnections))
+	defer readStorage.Close()
+	s.ReadFrom(readStorage)
 
-	var s = service.NewService(pool)
+	s.StartHealthChecks()
+	s.StartPruning()
 	go func() {
 		logger.LogInfo("Starting data load service for fetching last 5 epoch data")
 		s.Run()
 		logger.LogInfo("Loaded data for last 5 epoch")
+		s.StartRepairs()
 	}()
 
-	epochController := controller.NewEpochController(pool)
-	participationController := controller.NewParticipationController(pool, s)
+	epochController := controller.NewEpochController(readStorage)
+	participationController := controller.NewParticipationController(s)
+	consistencyController := controller.NewConsistencyController(s)
+	statsController := controller.NewStatsController(readStorage)
 
 	http.HandleFunc("/data", epochController.GetData)
 	http.HandleFunc("/participation-rate", participationController.GetParticipationRate)
+	http.HandleFunc("/consistency", consistencyController.GetConsistencyReport)
+	http.HandleFunc("/stats", statsController.GetStats)
 	logger.LogInfo("Starting server at port %v", os.Getenv("PORT"))
 	logger.LogError(http.ListenAndServe(":"+os.Getenv("PORT"), nil))
 	logger.LogInfo("Server exited and released port %v", os.Getenv("PORT"))
@@ -0,0 +1,214 @@
+package model
+
+/*
+Block types follow the JSON schema of the beacon API. Every fork's body embeds the body of the fork it builds
+on and adds what changed, so a body decoded with the type of its own fork keeps every field
+*/
+
+type Eth1Data struct {
+	DepositRoot  string `json:"deposit_root"`
+	DepositCount string `json:"deposit_count"`
+	BlockHash    string `json:"block_hash"`
+}
+
+type ProposerSlashing struct {
+	SignedHeader1 HeaderData `json:"signed_header_1"`
+	SignedHeader2 HeaderData `json:"signed_header_2"`
+}
+
+type IndexedAttestation struct {
+	AttestingIndices []string           `json:"attesting_indices"`
+	Data             AttestationDetails `json:"data"`
+	Signature        string             `json:"signature"`
+}
+
+type AttesterSlashing struct {
+	Attestation1 IndexedAttestation `json:"attestation_1"`
+	Attestation2 IndexedAttestation `json:"attestation_2"`
+}
+
+type DepositData struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                string `json:"amount"`
+	Signature             string `json:"signature"`
+}
+
+type Deposit struct {
+	Proof []string    `json:"proof"`
+	Data  DepositData `json:"data"`
+}
+
+type VoluntaryExit struct {
+	Epoch          string `json:"epoch"`
+	ValidatorIndex string `json:"validator_index"`
+}
+
+type SignedVoluntaryExit struct {
+	Message   VoluntaryExit `json:"message"`
+	Signature string        `json:"signature"`
+}
+
+type SyncAggregate struct {
+	SyncCommitteeBits      string `json:"sync_committee_bits"`
+	SyncCommitteeSignature string `json:"sync_committee_signature"`
+}
+
+type Withdrawal struct {
+	Index          string `json:"index"`
+	ValidatorIndex string `json:"validator_index"`
+	Address        string `json:"address"`
+	Amount         string `json:"amount"`
+}
+
+type BLSToExecutionChange struct {
+	ValidatorIndex     string `json:"validator_index"`
+	FromBLSPubkey      string `json:"from_bls_pubkey"`
+	ToExecutionAddress string `json:"to_execution_address"`
+}
+
+type SignedBLSToExecutionChange struct {
+	Message   BLSToExecutionChange `json:"message"`
+	Signature string               `json:"signature"`
+}
+
+type BellatrixExecutionPayload struct {
+	ParentHash    string   `json:"parent_hash"`
+	FeeRecipient  string   `json:"fee_recipient"`
+	StateRoot     string   `json:"state_root"`
+	ReceiptsRoot  string   `json:"receipts_root"`
+	LogsBloom     string   `json:"logs_bloom"`
+	PrevRandao    string   `json:"prev_randao"`
+	BlockNumber   string   `json:"block_number"`
+	GasLimit      string   `json:"gas_limit"`
+	GasUsed       string   `json:"gas_used"`
+	Timestamp     string   `json:"timestamp"`
+	ExtraData     string   `json:"extra_data"`
+	BaseFeePerGas string   `json:"base_fee_per_gas"`
+	BlockHash     string   `json:"block_hash"`
+	Transactions  []string `json:"transactions"`
+}
+
+type CapellaExecutionPayload struct {
+	BellatrixExecutionPayload
+	Withdrawals []Withdrawal `json:"withdrawals"`
+}
+
+type DenebExecutionPayload struct {
+	CapellaExecutionPayload
+	BlobGasUsed   string `json:"blob_gas_used"`
+	ExcessBlobGas string `json:"excess_blob_gas"`
+}
+
+type DepositRequest struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                string `json:"amount"`
+	Signature             string `json:"signature"`
+	Index                 string `json:"index"`
+}
+
+type WithdrawalRequest struct {
+	SourceAddress   string `json:"source_address"`
+	ValidatorPubkey string `json:"validator_pubkey"`
+	Amount          string `json:"amount"`
+}
+
+type ConsolidationRequest struct {
+	SourceAddress string `json:"source_address"`
+	SourcePubkey  string `json:"source_pubkey"`
+	TargetPubkey  string `json:"target_pubkey"`
+}
+
+type ExecutionRequests struct {
+	Deposits       []DepositRequest       `json:"deposits"`
+	Withdrawals    []WithdrawalRequest    `json:"withdrawals"`
+	Consolidations []ConsolidationRequest `json:"consolidations"`
+}
+
+/*
+BeaconBlockBody is implemented by the body of every fork
+*/
+type BeaconBlockBody interface {
+	BlockAttestations() []Attestation
+}
+
+type Phase0BeaconBlockBody struct {
+	RandaoReveal      string                `json:"randao_reveal"`
+	Eth1Data          Eth1Data              `json:"eth1_data"`
+	Graffiti          string                `json:"graffiti"`
+	ProposerSlashings []ProposerSlashing    `json:"proposer_slashings"`
+	AttesterSlashings []AttesterSlashing    `json:"attester_slashings"`
+	Attestations      []Attestation         `json:"attestations"`
+	Deposits          []Deposit             `json:"deposits"`
+	VoluntaryExits    []SignedVoluntaryExit `json:"voluntary_exits"`
+}
+
+func (b *Phase0BeaconBlockBody) BlockAttestations() []Attestation {
+	return b.Attestations
+}
+
+type AltairBeaconBlockBody struct {
+	Phase0BeaconBlockBody
+	SyncAggregate SyncAggregate `json:"sync_aggregate"`
+}
+
+type BellatrixBeaconBlockBody struct {
+	AltairBeaconBlockBody
+	ExecutionPayload BellatrixExecutionPayload `json:"execution_payload"`
+}
+
+type CapellaBeaconBlockBody struct {
+	AltairBeaconBlockBody
+	ExecutionPayload      CapellaExecutionPayload      `json:"execution_payload"`
+	BLSToExecutionChanges []SignedBLSToExecutionChange `json:"bls_to_execution_changes"`
+}
+
+type DenebBeaconBlockBody struct {
+	AltairBeaconBlockBody
+	ExecutionPayload      DenebExecutionPayload        `json:"execution_payload"`
+	BLSToExecutionChanges []SignedBLSToExecutionChange `json:"bls_to_execution_changes"`
+	BlobKZGCommitments    []string                     `json:"blob_kzg_commitments"`
+}
+
+// Electra changed the attestation layout (committee_bits) and added execution requests. Fulu kept the body as is
+type ElectraBeaconBlockBody struct {
+	DenebBeaconBlockBody
+	ExecutionRequests ExecutionRequests `json:"execution_requests"`
+}
+
+type BeaconBlock struct {
+	Slot          string          `json:"slot"`
+	ProposerIndex string          `json:"proposer_index"`
+	ParentRoot    string          `json:"parent_root"`
+	StateRoot     string          `json:"state_root"`
+	Body          BeaconBlockBody `json:"body"`
+}
+
+type SignedBeaconBlock struct {
+	Version   ForkVersion `json:"version"`
+	Message   BeaconBlock `json:"message"`
+	Signature string      `json:"signature"`
+}
+
+/*
+This function returns an empty body of the type used by the given fork
+*/
+func NewBeaconBlockBody(fork ForkVersion) BeaconBlockBody {
+	switch fork {
+	case Phase0:
+		return &Phase0BeaconBlockBody{}
+	case Altair:
+		return &AltairBeaconBlockBody{}
+	case Bellatrix:
+		return &BellatrixBeaconBlockBody{}
+	case Capella:
+		return &CapellaBeaconBlockBody{}
+	case Deneb:
+		return &DenebBeaconBlockBody{}
+	case Electra, Fulu:
+		return &ElectraBeaconBlockBody{}
+	default:
+		return nil
+	}
+}
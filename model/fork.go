@@ -0,0 +1,51 @@
+package model
+
+import "fmt"
+
+type ForkVersion string
+
+const (
+	Phase0    ForkVersion = "phase0"
+	Altair    ForkVersion = "altair"
+	Bellatrix ForkVersion = "bellatrix"
+	Capella   ForkVersion = "capella"
+	Deneb     ForkVersion = "deneb"
+	Electra   ForkVersion = "electra"
+	Fulu      ForkVersion = "fulu"
+)
+
+var forkOrder = []ForkVersion{Phase0, Altair, Bellatrix, Capella, Deneb, Electra, Fulu}
+
+/*
+This function parses the version field of an /eth/v2 response, rejecting forks this indexer has no types for
+*/
+func ParseForkVersion(version string) (ForkVersion, error) {
+	for _, fork := range forkOrder {
+		if string(fork) == version {
+			return fork, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fork version %q", version)
+}
+
+// AtLeast reports whether the fork is the given fork or a later one
+func (f ForkVersion) AtLeast(other ForkVersion) bool {
+	return forkIndex(f) >= forkIndex(other)
+}
+
+func forkIndex(fork ForkVersion) int {
+	for i, f := range forkOrder {
+		if f == fork {
+			return i
+		}
+	}
+	return -1
+}
+
+// ForkAt returns the fork with the given position in the fork order, phase0 being 0
+func ForkAt(index int) (ForkVersion, error) {
+	if index < 0 || index >= len(forkOrder) {
+		return "", fmt.Errorf("no fork at position %d", index)
+	}
+	return forkOrder[index], nil
+}
@@ -31,6 +31,9 @@ type Attestation struct {
 	AggregationBits string             `json:"aggregation_bits"`
 	Details         AttestationDetails `json:"data"`
 	Signature       string             `json:"signature"`
+	// Electra attestations (EIP-7549) carry the committees they cover here and always have index 0 in
+	// their data. aggregation_bits then spans all of those committees back to back
+	CommitteeBits string `json:"committee_bits,omitempty"`
 }
 
 type AttestationDetails struct {
@@ -57,3 +60,35 @@ type Participation struct {
 	MissedAttestations  int     `json:"missed_attestations"`
 	ValidatorSetSize    int     `json:"validator_set_size"`
 }
+
+type EpochParticipation struct {
+	Epoch             int64   `json:"epoch"`
+	Participating     int64   `json:"participating"`
+	Expected          int64   `json:"expected"`
+	ParticipationRate float64 `json:"participation_rate"`
+	// The time of the first slot of the epoch
+	UnixTime int64 `json:"unix_time,omitempty"`
+}
+
+type CommitteeParticipation struct {
+	Epoch           int64  `json:"epoch"`
+	Slot            int64  `json:"slot"`
+	CommitteeIndex  int64  `json:"committee_index"`
+	CommitteeSize   int    `json:"committee_size"`
+	AggregationBits string `json:"aggregation_bits"`
+	Participants    int    `json:"participants"`
+	InclusionSlot   int64  `json:"inclusion_slot"`
+}
+
+/*
+BucketStats summarises an epoch, hour or day. Start is the epoch number for epochs and the unix time the
+bucket starts at otherwise. Missed slots are the slots between the first and last indexed slot of the bucket
+without a canonical block, and the participation rate is the average of the epochs starting in the bucket,
+null when none of them has participation stored
+*/
+type BucketStats struct {
+	Start             int64    `json:"start"`
+	Blocks            int64    `json:"blocks"`
+	MissedSlots       int64    `json:"missed_slots"`
+	ParticipationRate *float64 `json:"participation_rate"`
+}
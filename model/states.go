@@ -0,0 +1,207 @@
+package model
+
+/*
+State types follow the JSON schema of the debug state endpoint. As with blocks, every fork embeds the state of
+the fork it builds on wherever the fields carried over unchanged
+*/
+
+type Fork struct {
+	PreviousVersion string `json:"previous_version"`
+	CurrentVersion  string `json:"current_version"`
+	Epoch           string `json:"epoch"`
+}
+
+type Validator struct {
+	Pubkey                     string `json:"pubkey"`
+	WithdrawalCredentials      string `json:"withdrawal_credentials"`
+	EffectiveBalance           string `json:"effective_balance"`
+	Slashed                    bool   `json:"slashed"`
+	ActivationEligibilityEpoch string `json:"activation_eligibility_epoch"`
+	ActivationEpoch            string `json:"activation_epoch"`
+	ExitEpoch                  string `json:"exit_epoch"`
+	WithdrawableEpoch          string `json:"withdrawable_epoch"`
+}
+
+type PendingAttestation struct {
+	AggregationBits string             `json:"aggregation_bits"`
+	Data            AttestationDetails `json:"data"`
+	InclusionDelay  string             `json:"inclusion_delay"`
+	ProposerIndex   string             `json:"proposer_index"`
+}
+
+type SyncCommittee struct {
+	Pubkeys         []string `json:"pubkeys"`
+	AggregatePubkey string   `json:"aggregate_pubkey"`
+}
+
+type BellatrixExecutionPayloadHeader struct {
+	ParentHash       string `json:"parent_hash"`
+	FeeRecipient     string `json:"fee_recipient"`
+	StateRoot        string `json:"state_root"`
+	ReceiptsRoot     string `json:"receipts_root"`
+	LogsBloom        string `json:"logs_bloom"`
+	PrevRandao       string `json:"prev_randao"`
+	BlockNumber      string `json:"block_number"`
+	GasLimit         string `json:"gas_limit"`
+	GasUsed          string `json:"gas_used"`
+	Timestamp        string `json:"timestamp"`
+	ExtraData        string `json:"extra_data"`
+	BaseFeePerGas    string `json:"base_fee_per_gas"`
+	BlockHash        string `json:"block_hash"`
+	TransactionsRoot string `json:"transactions_root"`
+}
+
+type CapellaExecutionPayloadHeader struct {
+	BellatrixExecutionPayloadHeader
+	WithdrawalsRoot string `json:"withdrawals_root"`
+}
+
+type DenebExecutionPayloadHeader struct {
+	CapellaExecutionPayloadHeader
+	BlobGasUsed   string `json:"blob_gas_used"`
+	ExcessBlobGas string `json:"excess_blob_gas"`
+}
+
+type HistoricalSummary struct {
+	BlockSummaryRoot string `json:"block_summary_root"`
+	StateSummaryRoot string `json:"state_summary_root"`
+}
+
+type PendingDeposit struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                string `json:"amount"`
+	Signature             string `json:"signature"`
+	Slot                  string `json:"slot"`
+}
+
+type PendingPartialWithdrawal struct {
+	ValidatorIndex    string `json:"validator_index"`
+	Amount            string `json:"amount"`
+	WithdrawableEpoch string `json:"withdrawable_epoch"`
+}
+
+type PendingConsolidation struct {
+	SourceIndex string `json:"source_index"`
+	TargetIndex string `json:"target_index"`
+}
+
+/*
+BeaconState is implemented by the state of every fork
+*/
+type BeaconState interface {
+	StateSlot() string
+	StateValidators() []Validator
+}
+
+/*
+BeaconStateBase holds the fields every fork has in common
+*/
+type BeaconStateBase struct {
+	GenesisTime           string      `json:"genesis_time"`
+	GenesisValidatorsRoot string      `json:"genesis_validators_root"`
+	Slot                  string      `json:"slot"`
+	Fork                  Fork        `json:"fork"`
+	LatestBlockHeader     MessageData `json:"latest_block_header"`
+	BlockRoots            []string    `json:"block_roots"`
+	StateRoots            []string    `json:"state_roots"`
+	HistoricalRoots       []string    `json:"historical_roots"`
+	Eth1Data              Eth1Data    `json:"eth1_data"`
+	Eth1DataVotes         []Eth1Data  `json:"eth1_data_votes"`
+	Eth1DepositIndex      string      `json:"eth1_deposit_index"`
+	Validators            []Validator `json:"validators"`
+	Balances              []string    `json:"balances"`
+	RandaoMixes           []string    `json:"randao_mixes"`
+	Slashings             []string    `json:"slashings"`
+	JustificationBits     string      `json:"justification_bits"`
+	PreviousJustified     Epoch       `json:"previous_justified_checkpoint"`
+	CurrentJustified      Epoch       `json:"current_justified_checkpoint"`
+	FinalizedCheckpoint   Epoch       `json:"finalized_checkpoint"`
+}
+
+func (s *BeaconStateBase) StateSlot() string {
+	return s.Slot
+}
+
+func (s *BeaconStateBase) StateValidators() []Validator {
+	return s.Validators
+}
+
+type Phase0BeaconState struct {
+	BeaconStateBase
+	PreviousEpochAttestations []PendingAttestation `json:"previous_epoch_attestations"`
+	CurrentEpochAttestations  []PendingAttestation `json:"current_epoch_attestations"`
+}
+
+// Altair replaced the pending attestations with participation flags and added sync committees
+type AltairBeaconState struct {
+	BeaconStateBase
+	PreviousEpochParticipation []string      `json:"previous_epoch_participation"`
+	CurrentEpochParticipation  []string      `json:"current_epoch_participation"`
+	InactivityScores           []string      `json:"inactivity_scores"`
+	CurrentSyncCommittee       SyncCommittee `json:"current_sync_committee"`
+	NextSyncCommittee          SyncCommittee `json:"next_sync_committee"`
+}
+
+type BellatrixBeaconState struct {
+	AltairBeaconState
+	LatestExecutionPayloadHeader BellatrixExecutionPayloadHeader `json:"latest_execution_payload_header"`
+}
+
+type CapellaBeaconState struct {
+	AltairBeaconState
+	LatestExecutionPayloadHeader CapellaExecutionPayloadHeader `json:"latest_execution_payload_header"`
+	NextWithdrawalIndex          string                        `json:"next_withdrawal_index"`
+	NextWithdrawalValidatorIndex string                        `json:"next_withdrawal_validator_index"`
+	HistoricalSummaries          []HistoricalSummary           `json:"historical_summaries"`
+}
+
+type DenebBeaconState struct {
+	AltairBeaconState
+	LatestExecutionPayloadHeader DenebExecutionPayloadHeader `json:"latest_execution_payload_header"`
+	NextWithdrawalIndex          string                      `json:"next_withdrawal_index"`
+	NextWithdrawalValidatorIndex string                      `json:"next_withdrawal_validator_index"`
+	HistoricalSummaries          []HistoricalSummary         `json:"historical_summaries"`
+}
+
+type ElectraBeaconState struct {
+	DenebBeaconState
+	DepositRequestsStartIndex     string                     `json:"deposit_requests_start_index"`
+	DepositBalanceToConsume       string                     `json:"deposit_balance_to_consume"`
+	ExitBalanceToConsume          string                     `json:"exit_balance_to_consume"`
+	EarliestExitEpoch             string                     `json:"earliest_exit_epoch"`
+	ConsolidationBalanceToConsume string                     `json:"consolidation_balance_to_consume"`
+	EarliestConsolidationEpoch    string                     `json:"earliest_consolidation_epoch"`
+	PendingDeposits               []PendingDeposit           `json:"pending_deposits"`
+	PendingPartialWithdrawals     []PendingPartialWithdrawal `json:"pending_partial_withdrawals"`
+	PendingConsolidations         []PendingConsolidation     `json:"pending_consolidations"`
+}
+
+type FuluBeaconState struct {
+	ElectraBeaconState
+	ProposerLookahead []string `json:"proposer_lookahead"`
+}
+
+/*
+This function returns an empty state of the type used by the given fork
+*/
+func NewBeaconState(fork ForkVersion) BeaconState {
+	switch fork {
+	case Phase0:
+		return &Phase0BeaconState{}
+	case Altair:
+		return &AltairBeaconState{}
+	case Bellatrix:
+		return &BellatrixBeaconState{}
+	case Capella:
+		return &CapellaBeaconState{}
+	case Deneb:
+		return &DenebBeaconState{}
+	case Electra:
+		return &ElectraBeaconState{}
+	case Fulu:
+		return &FuluBeaconState{}
+	default:
+		return nil
+	}
+}
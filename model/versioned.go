@@ -0,0 +1,87 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type VersionedBeaconState struct {
+	Version ForkVersion
+	State   BeaconState
+}
+
+/*
+This function decodes an /eth/v2/beacon/blocks response. The version field of the response picks the body
+type of that fork, and any field the type does not know about is an error instead of being dropped
+*/
+func DecodeSignedBeaconBlock(r io.Reader) (*SignedBeaconBlock, error) {
+	var envelope struct {
+		Version string `json:"version"`
+		Data    struct {
+			Message struct {
+				Slot          string          `json:"slot"`
+				ProposerIndex string          `json:"proposer_index"`
+				ParentRoot    string          `json:"parent_root"`
+				StateRoot     string          `json:"state_root"`
+				Body          json.RawMessage `json:"body"`
+			} `json:"message"`
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	err := json.NewDecoder(r).Decode(&envelope)
+	if err != nil {
+		return nil, err
+	}
+	fork, err := ParseForkVersion(envelope.Version)
+	if err != nil {
+		return nil, err
+	}
+	body := NewBeaconBlockBody(fork)
+	err = decodeStrict(envelope.Data.Message.Body, body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s block body: %w", fork, err)
+	}
+	return &SignedBeaconBlock{
+		Version: fork,
+		Message: BeaconBlock{
+			Slot:          envelope.Data.Message.Slot,
+			ProposerIndex: envelope.Data.Message.ProposerIndex,
+			ParentRoot:    envelope.Data.Message.ParentRoot,
+			StateRoot:     envelope.Data.Message.StateRoot,
+			Body:          body,
+		},
+		Signature: envelope.Data.Signature,
+	}, nil
+}
+
+/*
+This function decodes an /eth/v2/debug/beacon/states response into the state type of its fork
+*/
+func DecodeBeaconState(r io.Reader) (*VersionedBeaconState, error) {
+	var envelope struct {
+		Version string          `json:"version"`
+		Data    json.RawMessage `json:"data"`
+	}
+	err := json.NewDecoder(r).Decode(&envelope)
+	if err != nil {
+		return nil, err
+	}
+	fork, err := ParseForkVersion(envelope.Version)
+	if err != nil {
+		return nil, err
+	}
+	state := NewBeaconState(fork)
+	err = decodeStrict(envelope.Data, state)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s state: %w", fork, err)
+	}
+	return &VersionedBeaconState{Version: fork, State: state}, nil
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+The responses in testdata were generated from the block and state types of protolambda/zrnt, an independent
+implementation of the consensus specs, with every list holding one element so every field of the fork is
+present. zrnt has no fulu types, the fulu responses are the electra ones with the fields fulu added. Real
+responses of every fork are decoded by TestMainnetVersionedBlocks in the service package when a beacon node
+is configured
+*/
+var testForks = []ForkVersion{Phase0, Altair, Bellatrix, Capella, Deneb, Electra, Fulu}
+
+func readResponse(t *testing.T, kind string, fork ForkVersion) ([]byte, map[string]interface{}) {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + kind + "_" + string(fork) + ".json")
+	if err != nil {
+		t.Fatalf("failed to read the %s %s response: %v", fork, kind, err)
+	}
+	var response map[string]interface{}
+	if err = json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("failed to parse the %s %s response: %v", fork, kind, err)
+	}
+	return body, response
+}
+
+// reencodes checks that v encodes back to the data of the response, so no field of the fork was dropped
+func reencodes(t *testing.T, v interface{}, expected interface{}) {
+	t.Helper()
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("decoding dropped or changed fields, got %s", encoded)
+	}
+}
+
+func TestDecodeSignedBeaconBlock(t *testing.T) {
+	for _, fork := range testForks {
+		t.Run(string(fork), func(t *testing.T) {
+			body, response := readResponse(t, "block", fork)
+			block, err := DecodeSignedBeaconBlock(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if block.Version != fork || reflect.TypeOf(block.Message.Body) != reflect.TypeOf(NewBeaconBlockBody(fork)) {
+				t.Errorf("decoded as %s with body %T", block.Version, block.Message.Body)
+			}
+			attestations := block.Message.Body.BlockAttestations()
+			if len(attestations) != 1 || (attestations[0].CommitteeBits != "") != fork.AtLeast(Electra) {
+				t.Errorf("unexpected attestations %+v", attestations)
+			}
+			data := response["data"].(map[string]interface{})
+			reencodes(t, map[string]interface{}{"message": block.Message, "signature": block.Signature}, data)
+		})
+	}
+}
+
+func TestDecodeBeaconState(t *testing.T) {
+	for _, fork := range testForks {
+		t.Run(string(fork), func(t *testing.T) {
+			body, response := readResponse(t, "state", fork)
+			state, err := DecodeBeaconState(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if state.Version != fork || reflect.TypeOf(state.State) != reflect.TypeOf(NewBeaconState(fork)) {
+				t.Errorf("decoded as %s with state %T", state.Version, state.State)
+			}
+			if state.State.StateSlot() != "7" || len(state.State.StateValidators()) != 1 {
+				t.Errorf("unexpected slot %s and %d validators", state.State.StateSlot(), len(state.State.StateValidators()))
+			}
+			reencodes(t, state.State, response["data"])
+		})
+	}
+}
+
+func TestDecodeRejectsUnknownFieldsAndForks(t *testing.T) {
+	body, _ := readResponse(t, "block", Deneb)
+	// A field of a later fork in a deneb body must not be dropped silently
+	withRequests := strings.Replace(string(body), `"blob_kzg_commitments"`, `"execution_requests": {}, "blob_kzg_commitments"`, 1)
+	if _, err := DecodeSignedBeaconBlock(strings.NewReader(withRequests)); err == nil {
+		t.Errorf("expected an unknown body field to be rejected")
+	}
+	unknownFork := strings.Replace(string(body), `"version": "deneb"`, `"version": "gloas"`, 1)
+	if _, err := DecodeSignedBeaconBlock(strings.NewReader(unknownFork)); err == nil {
+		t.Errorf("expected an unknown block version to be rejected")
+	}
+
+	body, _ = readResponse(t, "state", Capella)
+	withLookahead := strings.Replace(string(body), `"historical_summaries"`, `"proposer_lookahead": [], "historical_summaries"`, 1)
+	if _, err := DecodeBeaconState(strings.NewReader(withLookahead)); err == nil {
+		t.Errorf("expected an unknown state field to be rejected")
+	}
+}
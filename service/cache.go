@@ -0,0 +1,128 @@
+package service
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"go-beacon-chain-indexer/logger"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type cacheEntry struct {
+	key  string
+	body []byte
+}
+
+/*
+ResponseCache keeps raw upstream response bodies keyed by request path. Entries live in an in-memory
+LRU bounded by total size and, when a directory is configured, are also written to disk so they
+survive restarts. Only responses that can never change (finalized data) should be stored in it
+*/
+type ResponseCache struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	size     int64
+	entries  *list.List
+	index    map[string]*list.Element
+	dir      string
+}
+
+func NewResponseCache(maxBytes int64, dir string) *ResponseCache {
+	if dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			logger.LogError(err)
+			dir = ""
+		}
+	}
+	return &ResponseCache{
+		maxBytes: maxBytes,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+		dir:      dir,
+	}
+}
+
+/*
+This method looks the key up in memory first and then on disk, promoting disk hits into memory
+*/
+func (c *ResponseCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	if element, ok := c.index[key]; ok {
+		c.entries.MoveToFront(element)
+		body := element.Value.(*cacheEntry).body
+		c.mutex.Unlock()
+		return body, true
+	}
+	c.mutex.Unlock()
+
+	if c.dir == "" {
+		return nil, false
+	}
+	body, err := os.ReadFile(c.filePath(key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.LogError(err)
+		}
+		return nil, false
+	}
+	c.store(key, body)
+	return body, true
+}
+
+// Put stores the body in memory and, if enabled, on disk
+func (c *ResponseCache) Put(key string, body []byte) {
+	c.store(key, body)
+	if c.dir == "" {
+		return
+	}
+	// Write to a temporary file first so a crash never leaves a truncated entry behind
+	path := c.filePath(key)
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		logger.LogError(err)
+		return
+	}
+	_, err = tmp.Write(body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		logger.LogError(err)
+		os.Remove(tmp.Name())
+	}
+}
+
+func (c *ResponseCache) store(key string, body []byte) {
+	if int64(len(body)) > c.maxBytes {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.index[key]; ok {
+		c.size -= int64(len(element.Value.(*cacheEntry).body))
+		element.Value.(*cacheEntry).body = body
+		c.size += int64(len(body))
+		c.entries.MoveToFront(element)
+	} else {
+		c.index[key] = c.entries.PushFront(&cacheEntry{key: key, body: body})
+		c.size += int64(len(body))
+	}
+	for c.size > c.maxBytes {
+		oldest := c.entries.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.entries.Remove(oldest)
+		delete(c.index, entry.key)
+		c.size -= int64(len(entry.body))
+	}
+}
+
+func (c *ResponseCache) filePath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResponseCacheEvictsLeastRecentlyUsedBytes(t *testing.T) {
+	cache := NewResponseCache(10, "")
+	cache.Put("a", []byte("aaaa"))
+	cache.Put("b", []byte("bbbb"))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	// a was used last, so b makes room for c
+	cache.Put("c", []byte("cccc"))
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+	if cache.size != 8 {
+		t.Errorf("expected 8 cached bytes, got %v", cache.size)
+	}
+
+	// Replacing an entry counts its new size only
+	cache.Put("a", []byte("aa"))
+	if body, _ := cache.Get("a"); string(body) != "aa" || cache.size != 6 {
+		t.Errorf("expected a replaced with 6 cached bytes, got %q and %v", body, cache.size)
+	}
+	// A body larger than the cache is never stored and evicts nothing
+	cache.Put("big", make([]byte, 11))
+	if _, ok := cache.Get("big"); ok || cache.entries.Len() != 2 {
+		t.Errorf("expected the oversized body to be skipped, %v entries", cache.entries.Len())
+	}
+}
+
+func TestResponseCacheWritesThroughToDisk(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewResponseCache(4, dir)
+	cache.Put("a", []byte("aaaa"))
+	cache.Put("b", []byte("bbbb"))
+	// a is gone from memory but still on disk, and is promoted back on a hit
+	if body, ok := cache.Get("a"); !ok || string(body) != "aaaa" {
+		t.Fatalf("expected a from disk, got %q", body)
+	}
+	if _, ok := cache.index["a"]; !ok {
+		t.Error("expected the disk hit to be promoted into memory")
+	}
+
+	// A new cache on the same directory serves what the previous process stored
+	reloaded := NewResponseCache(1<<20, dir)
+	for key, expected := range map[string]string{"a": "aaaa", "b": "bbbb"} {
+		if body, ok := reloaded.Get(key); !ok || string(body) != expected {
+			t.Errorf("expected %s reloaded from disk, got %q", key, body)
+		}
+	}
+	if _, ok := reloaded.Get("c"); ok {
+		t.Error("expected a miss for a key that was never stored")
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("expected one file per entry and no temporary files, got %v", len(files))
+	}
+}
+
+func TestFetchBodyOnlyCachesFinalizedResponses(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/invalid" {
+			w.Write([]byte(`{"data":`))
+			return
+		}
+		w.Write([]byte(`{"data":"ok"}`))
+	}))
+	defer server.Close()
+	s := &Service{client: newTestClient(t, 1, server.URL), cache: NewResponseCache(1<<20, "")}
+
+	for i := 0; i < 2; i++ {
+		if body, err := s.fetchBody("/head", false); err != nil || string(body) != `{"data":"ok"}` {
+			t.Fatalf("unexpected body %q: %v", body, err)
+		}
+	}
+	if calls != 2 || s.cache.entries.Len() != 0 {
+		t.Errorf("expected data that is not finalized to be fetched every time and never cached, %v calls", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 2; i++ {
+		s.fetchBody("/finalized", true)
+	}
+	if calls != 1 {
+		t.Errorf("expected finalized data to be fetched once, got %v calls", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 2; i++ {
+		s.fetchBody("/invalid", true)
+	}
+	if calls != 2 {
+		t.Errorf("expected a body that is not JSON never to be cached, got %v calls", calls)
+	}
+
+	// Nothing is finalized before the finalized slot is known
+	if s.isFinalizedSlot(0) {
+		t.Error("expected no slot to be finalized before the first finalized checkpoint")
+	}
+	s.finalizedSlot = 100
+	if !s.isFinalizedSlot(100) || s.isFinalizedSlot(101) {
+		t.Error("expected slots up to the finalized slot only to be finalized")
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open, beacon node is considered unhealthy")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+/*
+CircuitBreaker stops requests from reaching a node after too many consecutive transient failures.
+Once the cooldown has passed a single probe request is let through, and its outcome decides whether
+the breaker closes again or stays open for another cooldown period
+*/
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a request may be sent to the node
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// Only one probe is allowed while half open
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker and resets the failure count
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// RecordFailure counts a transient failure and opens the breaker once the threshold is reached
+func (b *CircuitBreaker) RecordFailure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Open reports whether the breaker is currently rejecting requests
+func (b *CircuitBreaker) Open() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state == breakerOpen && time.Since(b.openedAt) < b.cooldown
+}
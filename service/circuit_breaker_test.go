@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	if !breaker.Allow() || breaker.Open() {
+		t.Fatal("a new breaker must be closed")
+	}
+
+	// Failures below the threshold keep it closed, and a success resets the count
+	breaker.RecordFailure()
+	breaker.RecordSuccess()
+	breaker.RecordFailure()
+	if !breaker.Allow() || breaker.Open() {
+		t.Fatal("expected the breaker closed below the threshold")
+	}
+	breaker.RecordFailure()
+	if breaker.Allow() || !breaker.Open() {
+		t.Fatal("expected the breaker open at the threshold")
+	}
+
+	// After the cooldown a single probe is let through
+	time.Sleep(25 * time.Millisecond)
+	if breaker.Open() || !breaker.Allow() {
+		t.Fatal("expected a probe after the cooldown")
+	}
+	if breaker.Allow() {
+		t.Fatal("only one probe may be sent while half open")
+	}
+	// A failed probe opens the breaker for another cooldown, even below the threshold
+	breaker.RecordFailure()
+	if breaker.Allow() || !breaker.Open() {
+		t.Fatal("expected a failed probe to open the breaker")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("expected a second probe after the cooldown")
+	}
+	breaker.RecordSuccess()
+	for i := 0; i < 3; i++ {
+		if !breaker.Allow() {
+			t.Fatal("expected a successful probe to close the breaker")
+		}
+	}
+}
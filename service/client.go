@@ -0,0 +1,335 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBeaconNodeURL = "https://wiser-side-morning.discover.quiknode.pro"
+)
+
+var (
+	ErrNotFound       = errors.New("resource not found on beacon node")
+	ErrSSZUnsupported = errors.New("beacon node did not answer with ssz")
+)
+
+/*
+UpstreamError describes a failed call to the beacon node. Transient errors (network failures,
+rate limiting, 5xx responses, truncated bodies) are worth retrying, permanent ones are not
+*/
+type UpstreamError struct {
+	URL        string
+	StatusCode int
+	Transient  bool
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("upstream request %s failed with status %d: %v", e.URL, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("upstream request %s failed: %v", e.URL, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+/*
+NotFoundError is returned when the nodes asked answered 404, Nodes counts them. It matches ErrNotFound, so
+callers that do not care how many nodes agreed check for that
+*/
+type NotFoundError struct {
+	Nodes int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%v on %d node(s)", ErrNotFound, e.Nodes)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// notFoundNodes returns how many nodes answered 404 for the request that failed with err
+func notFoundNodes(err error) int {
+	var notFoundErr *NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return notFoundErr.Nodes
+	}
+	return 0
+}
+
+// IsTransient reports whether the error is worth retrying
+func IsTransient(err error) bool {
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Transient
+	}
+	return false
+}
+
+/*
+BeaconClient wraps the http client used to talk to the beacon nodes. Every request is routed through
+the node pool, passes the chosen node's circuit breaker and fails over to the next node on transient
+failures. Once every node has been tried the round is retried with jittered exponential backoff
+*/
+type BeaconClient struct {
+	client      *http.Client
+	nodes       *NodePool
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func NewBeaconClient() (*BeaconClient, error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        10,               // Set the maximum number of idle connections in the pool
+			IdleConnTimeout:     30 * time.Second, // Set the maximum idle connection timeout
+			MaxIdleConnsPerHost: 10,               // Set the maximum number of idle connections per host
+		},
+	}
+	nodes, err := NewNodePool(client)
+	if err != nil {
+		return nil, err
+	}
+	return &BeaconClient{
+		client:      client,
+		nodes:       nodes,
+		maxAttempts: int(getEnvInt("UPSTREAM_MAX_ATTEMPTS", 5)),
+		baseDelay:   time.Duration(getEnvInt("UPSTREAM_RETRY_BASE_DELAY_MS", 250)) * time.Millisecond,
+		maxDelay:    time.Duration(getEnvInt("UPSTREAM_RETRY_MAX_DELAY_MS", 5000)) * time.Millisecond,
+	}, nil
+}
+
+func (c *BeaconClient) Nodes() *NodePool {
+	return c.nodes
+}
+
+/*
+This method performs a GET request and hands the body to decode as a stream, so large payloads never
+have to be held in memory. Decoding happens inside the retry loop, a body that is cut off half way is
+retried from the start, so decode must not keep state between calls
+*/
+func (c *BeaconClient) Stream(path string, accept string, decode func(body io.Reader, version string) error) error {
+	return c.retry(path, func(url string) error {
+		return c.streamURL(url, accept, decode)
+	})
+}
+
+/*
+This method performs a GET request against one node only, retrying transient failures on that node with
+backoff. It never fails over, so callers comparing what different nodes return know who answered
+*/
+func (c *BeaconClient) GetJSONFrom(node *BeaconNode, path string, v any) error {
+	var err error
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.backoff(attempt))
+		}
+		err = c.streamURL(node.URL+path, "application/json", func(body io.Reader, _ string) error {
+			return json.NewDecoder(body).Decode(v)
+		})
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *BeaconClient) streamURL(url string, accept string, decode func(body io.Reader, version string) error) error {
+	response, err := c.do(url, accept)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logger.LogError(err)
+		}
+	}(response.Body)
+	if accept == "application/octet-stream" && !strings.HasPrefix(response.Header.Get("Content-Type"), accept) {
+		return ErrSSZUnsupported
+	}
+	err = decode(response.Body, response.Header.Get("Eth-Consensus-Version"))
+	if err != nil {
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) || errors.Is(err, ErrSSZUnsupported) {
+			return err
+		}
+		return &UpstreamError{URL: url, Transient: isTransientNetworkError(err), Err: err}
+	}
+	return nil
+}
+
+/*
+This method performs a GET request and decodes the JSON body into v
+*/
+func (c *BeaconClient) GetJSON(path string, v any) error {
+	return c.Stream(path, "application/json", func(body io.Reader, _ string) error {
+		return json.NewDecoder(body).Decode(v)
+	})
+}
+
+/*
+This method performs a GET request and returns the whole body
+*/
+func (c *BeaconClient) GetBytes(path string) ([]byte, error) {
+	var body []byte
+	err := c.Stream(path, "application/json", func(r io.Reader, _ string) error {
+		var err error
+		body, err = io.ReadAll(r)
+		return err
+	})
+	return body, err
+}
+
+/*
+This method requests the SSZ encoding of a resource and returns the body together with the fork
+version from the Eth-Consensus-Version header. Nodes that answer with anything other than
+application/octet-stream result in ErrSSZUnsupported so the caller can fall back to JSON
+*/
+func (c *BeaconClient) GetSSZ(path string) ([]byte, string, error) {
+	var body []byte
+	var version string
+	err := c.Stream(path, "application/octet-stream", func(r io.Reader, v string) error {
+		var err error
+		version = v
+		body, err = io.ReadAll(r)
+		return err
+	})
+	return body, version, err
+}
+
+/*
+This method sends the request to the candidate nodes in turn until one answers. A node that answers 404 may
+only be missing the resource, so the other nodes are asked as well and the request only fails with a
+NotFoundError once every node that answered in the attempt returned 404
+*/
+func (c *BeaconClient) retry(path string, request func(url string) error) error {
+	err := ErrNoBeaconNodes
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.backoff(attempt))
+		}
+		notFound := 0
+		for _, node := range c.nodes.Candidates() {
+			if !node.breaker.Allow() {
+				err = &UpstreamError{URL: node.URL + path, Transient: true, Err: ErrCircuitOpen}
+				continue
+			}
+			err = request(node.URL + path)
+			if errors.Is(err, ErrNotFound) {
+				node.breaker.RecordSuccess()
+				notFound++
+				continue
+			}
+			if err == nil {
+				node.breaker.RecordSuccess()
+				return err
+			}
+			if !IsTransient(err) {
+				// The node answered, it just did not like the request
+				node.breaker.RecordSuccess()
+				return err
+			}
+			node.breaker.RecordFailure()
+			logger.LogInfo("Transient upstream failure on", node.URL, "attempt", attempt+1, "of", c.maxAttempts, ":", err)
+		}
+		if notFound > 0 {
+			return &NotFoundError{Nodes: notFound}
+		}
+	}
+	return err
+}
+
+func (c *BeaconClient) do(url string, accept string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", accept)
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, &UpstreamError{URL: url, Transient: true, Err: err}
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	response.Body.Close()
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case response.StatusCode == http.StatusNotAcceptable || response.StatusCode == http.StatusUnsupportedMediaType:
+		return nil, ErrSSZUnsupported
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return nil, &UpstreamError{URL: url, StatusCode: response.StatusCode, Transient: true, Err: errors.New(string(body))}
+	default:
+		return nil, &UpstreamError{URL: url, StatusCode: response.StatusCode, Transient: false, Err: errors.New(string(body))}
+	}
+}
+
+/*
+This method returns the delay before the given retry attempt using exponential backoff with full jitter
+*/
+func (c *BeaconClient) backoff(attempt int) time.Duration {
+	delay := c.baseDelay << uint(attempt-1)
+	if delay <= 0 || delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	return time.Duration(randomInt63n(int64(delay) + 1))
+}
+
+/*
+The global source of math/rand is only seeded on its own from Go 1.20 on and this module declares 1.18, so
+every instance would draw the same jitter and pick the same nodes. This source is seeded once per process
+*/
+var random = struct {
+	sync.Mutex
+	source *rand.Rand
+}{source: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func randomInt63n(n int64) int64 {
+	random.Lock()
+	defer random.Unlock()
+	return random.source.Int63n(n)
+}
+
+func isTransientNetworkError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func getEnvInt(name string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func getEnvBool(name string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient builds a client for the given nodes that retries quickly
+func newTestClient(t *testing.T, maxAttempts int, urls ...string) *BeaconClient {
+	t.Helper()
+	t.Setenv("BEACON_NODES", strings.Join(urls, ","))
+	t.Setenv("UPSTREAM_MAX_ATTEMPTS", fmt.Sprint(maxAttempts))
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY_MS", "1")
+	t.Setenv("UPSTREAM_RETRY_MAX_DELAY_MS", "2")
+	client, err := NewBeaconClient()
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+	return client
+}
+
+func TestUpstreamErrorClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/truncated":
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte(`{"data":`))
+			return
+		case "/malformed":
+			w.Write([]byte(`{"data":]`))
+			return
+		}
+		var status int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/"), "%d", &status)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	client := newTestClient(t, 1, server.URL)
+	for _, test := range []struct {
+		url       string
+		expected  error
+		transient bool
+	}{
+		{server.URL + "/404", ErrNotFound, false},
+		{server.URL + "/406", ErrSSZUnsupported, false},
+		{server.URL + "/429", nil, true},
+		{server.URL + "/500", nil, true},
+		{server.URL + "/503", nil, true},
+		{server.URL + "/400", nil, false},
+		{server.URL + "/truncated", nil, true},
+		{server.URL + "/malformed", nil, false},
+		{closed.URL + "/200", nil, true},
+	} {
+		var v interface{}
+		err := client.streamURL(test.url, "application/json", func(body io.Reader, _ string) error {
+			return json.NewDecoder(body).Decode(&v)
+		})
+		if err == nil {
+			t.Errorf("%s: expected an error", test.url)
+			continue
+		}
+		if test.expected != nil && !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.url, test.expected, err)
+		}
+		if IsTransient(err) != test.transient {
+			t.Errorf("%s: expected transient %v, got %v", test.url, test.transient, err)
+		}
+	}
+}
+
+func TestRetryFailsOverOnTransientErrors(t *testing.T) {
+	var failingCalls, healthyCalls int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failingCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthyCalls, 1)
+		w.Write([]byte(`{"value":"ok"}`))
+	}))
+	defer healthy.Close()
+
+	// The weighted pick makes either node first, a request fails over to the other one within a round
+	client := newTestClient(t, 3, failing.URL, healthy.URL)
+	for i := 0; i < 10; i++ {
+		var v struct{ Value string }
+		if err := client.GetJSON("/value", &v); err != nil || v.Value != "ok" {
+			t.Fatalf("expected the healthy node to answer, got %+v: %v", v, err)
+		}
+	}
+	if atomic.LoadInt32(&healthyCalls) != 10 {
+		t.Errorf("expected 10 calls to the healthy node, got %v", healthyCalls)
+	}
+
+	// Only transient failures are retried, every attempt of every round
+	client = newTestClient(t, 3, failing.URL)
+	atomic.StoreInt32(&failingCalls, 0)
+	if err := client.GetJSON("/value", &struct{}{}); !IsTransient(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&failingCalls); calls != 3 {
+		t.Errorf("expected 3 attempts, got %v", calls)
+	}
+}
+
+func TestRetryStopsOnPermanentErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	client := newTestClient(t, 5, server.URL)
+	err := client.GetJSON("/value", &struct{}{})
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != http.StatusBadRequest || upstreamErr.Transient {
+		t.Errorf("expected a permanent 400, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a permanent error not to be retried, got %v calls", calls)
+	}
+	if client.Nodes().Nodes()[0].breaker.Open() {
+		t.Error("a node that answered must not count as failing")
+	}
+}
+
+func TestRetryAsksAnotherNodeOnNotFound(t *testing.T) {
+	var calls int32
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.NotFound(w, r)
+	}))
+	defer notFound.Close()
+	found := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"value":"ok"}`))
+	}))
+	defer found.Close()
+
+	// A node that is missing the resource does not hide it when another node has it
+	client := newTestClient(t, 3, notFound.URL, found.URL)
+	for i := 0; i < 10; i++ {
+		var v struct{ Value string }
+		if err := client.GetJSON("/value", &v); err != nil || v.Value != "ok" {
+			t.Fatalf("expected the node with the resource to answer, got %+v: %v", v, err)
+		}
+	}
+
+	// Once every node answered 404 the request fails without being retried, telling how many nodes agreed
+	notFoundToo := httptest.NewServer(http.NotFoundHandler())
+	defer notFoundToo.Close()
+	client = newTestClient(t, 3, notFound.URL, notFoundToo.URL)
+	atomic.StoreInt32(&calls, 0)
+	err := client.GetJSON("/value", &struct{}{})
+	if !errors.Is(err, ErrNotFound) || notFoundNodes(err) != 2 {
+		t.Errorf("expected both nodes to answer not found, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a 404 not to be retried, got %v calls", calls)
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	client := &BeaconClient{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+	for attempt := 1; attempt <= 70; attempt++ {
+		limit := time.Second
+		if attempt <= 4 {
+			limit = 100 * time.Millisecond << uint(attempt-1)
+		}
+		for i := 0; i < 50; i++ {
+			if delay := client.backoff(attempt); delay < 0 || delay > limit {
+				t.Fatalf("attempt %v: delay %v outside [0, %v]", attempt, delay, limit)
+			}
+		}
+	}
+	// Full jitter spreads the delays instead of always waiting the same time
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		seen[client.backoff(5)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected jittered delays")
+	}
+}
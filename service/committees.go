@@ -0,0 +1,223 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/spec"
+	"go-beacon-chain-indexer/ssz"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+registryEntry is the part of a validator the spec needs to place it in committees and to weigh it as a
+proposer. Keeping only these fields holds a registry of two million validators in under 50MB
+*/
+type registryEntry struct {
+	activationEpoch  uint64
+	exitEpoch        uint64
+	effectiveBalance uint64
+}
+
+/*
+validatorRegistry is the validator registry as of a finalized state. Activation and exit epochs are fixed
+at least MAX_SEED_LOOKAHEAD epochs ahead, so the registry gives the active set of every epoch up to the
+one after its state, which is also the last epoch whose seed the state's randao mixes can provide
+*/
+type validatorRegistry struct {
+	slot       int64
+	validators []registryEntry
+}
+
+func (r *validatorRegistry) covers(epoch int64) bool {
+	return epoch <= getEpochNumber(r.slot)+1
+}
+
+// activeIndices is get_active_validator_indices from the spec
+func (r *validatorRegistry) activeIndices(epoch uint64) []uint64 {
+	indices := make([]uint64, 0, len(r.validators))
+	for i, validator := range r.validators {
+		if validator.activationEpoch <= epoch && epoch < validator.exitEpoch {
+			indices = append(indices, uint64(i))
+		}
+	}
+	return indices
+}
+
+// effectiveBalances returns the effective balance of every validator, indexed by validator index
+func (r *validatorRegistry) effectiveBalances() []uint64 {
+	balances := make([]uint64, len(r.validators))
+	for i, validator := range r.validators {
+		balances[i] = validator.effectiveBalance
+	}
+	return balances
+}
+
+/*
+This method computes the committees of an epoch locally with the spec's swap-or-not shuffle, from the
+randao mix that seeds the epoch and its active validator indices. It returns the same committee sizes and
+validator position as the committees endpoint without downloading every committee of every epoch
+*/
+func (s *Service) computeCommittees(epoch int64, validatorIndex string) (map[committeeKey]int, committeePosition, error) {
+	var validator committeePosition
+	if epoch < 0 {
+		return nil, validator, fmt.Errorf("invalid epoch %v", epoch)
+	}
+	registry, err := s.validatorRegistryFor(epoch)
+	if err != nil {
+		return nil, validator, err
+	}
+	mix, err := s.fetchRandaoMix(registry.slot, spec.SeedEpoch(uint64(epoch)))
+	if err != nil {
+		return nil, validator, err
+	}
+	committees, validator := registry.committees(epoch, mix, validatorIndex)
+	return committees, validator, nil
+}
+
+/*
+This method shuffles the validators active in the epoch into its committees, seeded by the randao mix of
+the epoch's seed epoch, and returns the size of every committee and the position of the given validator
+*/
+func (r *validatorRegistry) committees(epoch int64, mix [32]byte, validatorIndex string) (map[committeeKey]int, committeePosition) {
+	var validator committeePosition
+	seed := spec.GetSeed(mix, uint64(epoch), spec.DomainBeaconAttester)
+	target, targetErr := strconv.ParseUint(validatorIndex, 10, 64)
+
+	committees := make(map[committeeKey]int)
+	for _, committee := range spec.ComputeBeaconCommittees(r.activeIndices(uint64(epoch)), seed, uint64(epoch)) {
+		key := committeeKey{slot: int64(committee.Slot), index: int64(committee.Index)}
+		committees[key] = len(committee.Validators)
+		if validatorIndex == "" || targetErr != nil {
+			continue
+		}
+		for i, member := range committee.Validators {
+			if member == target {
+				validator = committeePosition{key: key, position: i, found: true}
+			}
+		}
+	}
+	return committees, validator
+}
+
+/*
+This method returns a validator registry that covers the epoch, loading the one of the latest finalized
+state when the registry held in memory is too old. Concurrent callers wait for a single download
+*/
+func (s *Service) validatorRegistryFor(epoch int64) (*validatorRegistry, error) {
+	s.registryMutex.Lock()
+	defer s.registryMutex.Unlock()
+	if s.registry != nil && s.registry.covers(epoch) {
+		return s.registry, nil
+	}
+	finalizedSlot, err := s.fetchLatestSlot()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := s.fetchValidatorRegistry(finalizedSlot)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	s.registry = registry
+	if !registry.covers(epoch) {
+		return nil, fmt.Errorf("epoch %v is too far ahead of finalized epoch %v to compute its committees", epoch, getEpochNumber(finalizedSlot))
+	}
+	return registry, nil
+}
+
+/*
+This method loads the validator registry of the state at the given slot, streaming the SSZ state when the
+node supports it and decoding the JSON state of its fork otherwise
+*/
+func (s *Service) fetchValidatorRegistry(slot int64) (*validatorRegistry, error) {
+	if s.useSSZ {
+		registry, err := s.fetchValidatorRegistrySSZ(slot)
+		if err == nil {
+			return registry, nil
+		}
+		if !errors.Is(err, ErrSSZUnsupported) {
+			logger.LogError(fmt.Errorf("falling back to json for the validator registry: %w", err))
+		}
+	}
+	state, err := s.FetchState(strconv.FormatInt(slot, 10))
+	if err != nil {
+		return nil, err
+	}
+	stateSlot, err := strconv.ParseInt(state.State.StateSlot(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slot %q in the %s state", state.State.StateSlot(), state.Version)
+	}
+	validators := state.State.StateValidators()
+	registry := &validatorRegistry{slot: stateSlot, validators: make([]registryEntry, 0, len(validators))}
+	for _, validator := range validators {
+		entry := registryEntry{}
+		entry.activationEpoch, _ = strconv.ParseUint(validator.ActivationEpoch, 10, 64)
+		entry.exitEpoch, _ = strconv.ParseUint(validator.ExitEpoch, 10, 64)
+		entry.effectiveBalance, _ = strconv.ParseUint(validator.EffectiveBalance, 10, 64)
+		registry.validators = append(registry.validators, entry)
+	}
+	return registry, nil
+}
+
+func (s *Service) fetchValidatorRegistrySSZ(slot int64) (*validatorRegistry, error) {
+	var registry *validatorRegistry
+	err := s.client.Stream(fmt.Sprintf("/eth/v2/debug/beacon/states/%v", slot), "application/octet-stream", func(body io.Reader, _ string) error {
+		reader, err := ssz.NewValidatorRegistryReader(body)
+		if err != nil {
+			return err
+		}
+		registry, err = readValidatorRegistry(reader)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+func readValidatorRegistry(reader *ssz.ValidatorRegistryReader) (*validatorRegistry, error) {
+	registry := &validatorRegistry{slot: int64(reader.Slot), validators: make([]registryEntry, 0, reader.Count)}
+	for {
+		validator, err := reader.Next()
+		if err == io.EOF {
+			return registry, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		registry.validators = append(registry.validators, registryEntry{
+			activationEpoch:  validator.ActivationEpoch,
+			exitEpoch:        validator.ExitEpoch,
+			effectiveBalance: validator.EffectiveBalance,
+		})
+	}
+}
+
+/*
+This method fetches the randao mix of an epoch as recorded in the state at the given slot
+*/
+func (s *Service) fetchRandaoMix(stateSlot int64, epoch uint64) ([32]byte, error) {
+	var mix [32]byte
+	var response struct {
+		Data struct {
+			Randao string `json:"randao"`
+		} `json:"data"`
+	}
+	err := s.fetchJSON(fmt.Sprintf("/eth/v1/beacon/states/%v/randao?epoch=%v", stateSlot, epoch), s.isFinalizedSlot(stateSlot), &response)
+	if err != nil {
+		logger.LogError(err)
+		return mix, err
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(response.Data.Randao, "0x"))
+	if err != nil || len(decoded) != len(mix) {
+		err = fmt.Errorf("invalid randao mix %q for epoch %v", response.Data.Randao, epoch)
+		logger.LogError(err)
+		return mix, err
+	}
+	copy(mix[:], decoded)
+	return mix, nil
+}
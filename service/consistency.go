@@ -0,0 +1,220 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/model"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrTooFewNodes = errors.New("at least two beacon nodes must be configured in BEACON_NODES to compare them")
+
+/*
+Discrepancy is one value the compared nodes disagree on, with what each node returned keyed by node url.
+Large values such as committees and attestations are summarised by a digest
+*/
+type Discrepancy struct {
+	Slot   int64             `json:"slot"`
+	Kind   string            `json:"kind"`
+	Field  string            `json:"field"`
+	Values map[string]string `json:"values"`
+}
+
+/*
+ConsistencyReport is the result of comparing the nodes over a range of slots
+*/
+type ConsistencyReport struct {
+	FromSlot      int64         `json:"from_slot"`
+	ToSlot        int64         `json:"to_slot"`
+	Nodes         []string      `json:"nodes"`
+	Checked       int           `json:"checked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// observation maps every field of one kind of data to the value a node returned for it
+type observation map[string]string
+
+/*
+This method asks every configured beacon node for the header, the committees and the included attestations
+of each slot in the range and reports every field on which they disagree. A node that fails to answer is
+reported with the kind of error it returned, so a node that is missing data shows up as well
+*/
+func (s *Service) CompareNodes(fromSlot int64, toSlot int64) (*ConsistencyReport, error) {
+	nodes := s.client.Nodes().Nodes()
+	if len(nodes) < 2 {
+		return nil, ErrTooFewNodes
+	}
+	if toSlot < fromSlot {
+		return nil, fmt.Errorf("invalid slot range %v to %v", fromSlot, toSlot)
+	}
+	report := &ConsistencyReport{FromSlot: fromSlot, ToSlot: toSlot, Discrepancies: []Discrepancy{}}
+	for _, node := range nodes {
+		report.Nodes = append(report.Nodes, node.URL)
+	}
+	kinds := []struct {
+		name    string
+		observe func(node *BeaconNode, slot int64) observation
+	}{
+		{"header", s.observeHeader},
+		{"committees", s.observeCommittees},
+		{"attestations", s.observeAttestations},
+	}
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		for _, kind := range kinds {
+			observations := make([]observation, len(nodes))
+			var wg sync.WaitGroup
+			for i, node := range nodes {
+				s.rateLimit()
+				wg.Add(1)
+				go func(i int, node *BeaconNode) {
+					defer wg.Done()
+					observations[i] = kind.observe(node, slot)
+				}(i, node)
+			}
+			wg.Wait()
+			report.Discrepancies = append(report.Discrepancies, compareObservations(slot, kind.name, nodes, observations)...)
+		}
+		report.Checked++
+	}
+	return report, nil
+}
+
+/*
+This function returns a discrepancy for every field that is missing on a node or differs between nodes,
+in field order so reports are stable. Every observation carries a status, ok or the kind of error the node returned
+*/
+func compareObservations(slot int64, kind string, nodes []*BeaconNode, observations []observation) []Discrepancy {
+	fields := make(map[string]bool)
+	for _, observed := range observations {
+		for field := range observed {
+			fields[field] = true
+		}
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	// Fields of a node that did not answer are all missing, only its status is worth reporting then
+	for i := range observations {
+		if observations[i]["status"] != observations[0]["status"] {
+			names = []string{"status"}
+			break
+		}
+	}
+
+	var discrepancies []Discrepancy
+	for _, field := range names {
+		values := make(map[string]string, len(nodes))
+		distinct := make(map[string]bool)
+		for i, node := range nodes {
+			value, ok := observations[i][field]
+			if !ok {
+				value = "<missing>"
+			}
+			values[node.URL] = value
+			distinct[value] = true
+		}
+		if len(distinct) > 1 {
+			discrepancies = append(discrepancies, Discrepancy{Slot: slot, Kind: kind, Field: field, Values: values})
+		}
+	}
+	return discrepancies
+}
+
+func (s *Service) observeHeader(node *BeaconNode, slot int64) observation {
+	var header model.BeaconChainData
+	err := s.client.GetJSONFrom(node, fmt.Sprintf("/eth/v1/beacon/headers/%v", slot), &header)
+	if err != nil {
+		return errorObservation(err)
+	}
+	message := header.Data.Header.Message
+	return observation{
+		"status":         "ok",
+		"root":           header.Data.Root,
+		"canonical":      strconv.FormatBool(header.Data.Canonical),
+		"proposer_index": message.ProposerIndex,
+		"parent_root":    message.ParentRoot,
+		"state_root":     message.StateRoot,
+		"body_root":      message.BodyRoot,
+		"signature":      header.Data.Header.Signature,
+	}
+}
+
+func (s *Service) observeCommittees(node *BeaconNode, slot int64) observation {
+	var response struct {
+		Data []model.Committee `json:"data"`
+	}
+	err := s.client.GetJSONFrom(node, fmt.Sprintf("/eth/v1/beacon/states/%v/committees?slot=%v", slot, slot), &response)
+	if err != nil {
+		return errorObservation(err)
+	}
+	observed := observation{"status": "ok", "count": strconv.Itoa(len(response.Data))}
+	for _, committee := range response.Data {
+		observed["committee "+committee.Index] = fmt.Sprintf("size %d %s", len(committee.Validators), digest(strings.Join(committee.Validators, ",")))
+	}
+	return observed
+}
+
+func (s *Service) observeAttestations(node *BeaconNode, slot int64) observation {
+	var response struct {
+		Data []model.Attestation `json:"data"`
+	}
+	err := s.client.GetJSONFrom(node, fmt.Sprintf("/eth/v2/beacon/blocks/%v/attestations", slot), &response)
+	if err != nil {
+		return errorObservation(err)
+	}
+	observed := observation{"status": "ok", "count": strconv.Itoa(len(response.Data))}
+	for i, attestation := range response.Data {
+		details := attestation.Details
+		observed[fmt.Sprintf("attestation %d", i)] = digest(strings.Join([]string{
+			attestation.AggregationBits, attestation.CommitteeBits, details.Slot, details.Index, details.BeaconBlockRoot,
+			details.Source.Epoch, details.Source.Root, details.Target.Epoch, details.Target.Root, attestation.Signature,
+		}, "|"))
+	}
+	return observed
+}
+
+/*
+A missed slot is a valid answer, every node should agree on it like on any other value. Other failures are
+reduced to their class or HTTP status, the error message names the node it came from and would make nodes
+that failed the same way look like they disagree
+*/
+func errorObservation(err error) observation {
+	return observation{"status": errorStatus(err)}
+}
+
+func errorStatus(err error) string {
+	if errors.Is(err, ErrNotFound) {
+		return "not found"
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return "error: circuit open"
+	}
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return "error: request failed"
+	}
+	var netErr net.Error
+	switch {
+	case upstreamErr.StatusCode != 0:
+		return fmt.Sprintf("error: status %d", upstreamErr.StatusCode)
+	case errors.As(upstreamErr.Err, &netErr) && netErr.Timeout():
+		return "error: timeout"
+	case upstreamErr.Transient:
+		return "error: unreachable"
+	default:
+		return "error: invalid response"
+	}
+}
+
+func digest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256 " + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCompareObservations(t *testing.T) {
+	nodes := []*BeaconNode{{URL: "http://a"}, {URL: "http://b"}}
+
+	same := observation{"status": "ok", "root": "0x01", "proposer_index": "7"}
+	if discrepancies := compareObservations(10, "header", nodes, []observation{same, same}); len(discrepancies) != 0 {
+		t.Errorf("expected no discrepancies between identical answers, got %v", discrepancies)
+	}
+
+	different := observation{"status": "ok", "root": "0x02", "proposer_index": "7"}
+	discrepancies := compareObservations(10, "header", nodes, []observation{same, different})
+	if len(discrepancies) != 1 || discrepancies[0].Field != "root" {
+		t.Fatalf("expected one root discrepancy, got %v", discrepancies)
+	}
+	if discrepancies[0].Values["http://a"] != "0x01" || discrepancies[0].Values["http://b"] != "0x02" {
+		t.Errorf("unexpected values %v", discrepancies[0].Values)
+	}
+
+	// A node that did not answer only differs in its status, not in every field it could not return
+	missing := observation{"status": "not found"}
+	discrepancies = compareObservations(10, "header", nodes, []observation{same, missing})
+	if len(discrepancies) != 1 || discrepancies[0].Field != "status" {
+		t.Errorf("expected one status discrepancy, got %v", discrepancies)
+	}
+}
+
+func TestErrorStatusLeavesOutTheNode(t *testing.T) {
+	cases := []struct {
+		err    error
+		status string
+	}{
+		{ErrNotFound, "not found"},
+		{&UpstreamError{URL: "http://a/eth/v1/beacon/headers/5", StatusCode: 503, Transient: true, Err: errors.New("busy")}, "error: status 503"},
+		{&UpstreamError{URL: "http://a/eth/v1/beacon/headers/5", Transient: true, Err: ErrCircuitOpen}, "error: circuit open"},
+		{&UpstreamError{URL: "http://a/eth/v1/beacon/headers/5", Transient: true, Err: errors.New("connection refused")}, "error: unreachable"},
+		{&UpstreamError{URL: "http://a/eth/v1/beacon/headers/5", Err: errors.New("invalid character")}, "error: invalid response"},
+	}
+	for _, c := range cases {
+		if status := errorStatus(c.err); status != c.status {
+			t.Errorf("expected %q for %v, got %q", c.status, c.err, status)
+		}
+	}
+}
+
+func TestCompareNodesFailingTheSameWay(t *testing.T) {
+	failing := func(status int, body string) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, body, status)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	limiter := make(chan time.Time)
+	close(limiter)
+
+	s := &Service{client: newTestClient(t, 1, failing(500, "out of memory"), failing(500, "internal error")), limiter: limiter}
+	report, err := s.CompareNodes(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Discrepancies) != 0 {
+		t.Errorf("nodes failing with the same status must agree, got %v", report.Discrepancies)
+	}
+
+	s = &Service{client: newTestClient(t, 1, failing(500, "internal error"), failing(400, "bad request")), limiter: limiter}
+	if report, err = s.CompareNodes(5, 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Discrepancies) != 3 || report.Discrepancies[0].Field != "status" {
+		t.Errorf("expected a status discrepancy for every kind, got %v", report.Discrepancies)
+	}
+}
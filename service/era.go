@@ -0,0 +1,299 @@
+package service
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/db"
+	"go-beacon-chain-indexer/era"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"go-beacon-chain-indexer/ssz"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// eraNetwork is the network whose era files can be imported, the one of spec.MainnetForkEpochs
+const eraNetwork = "mainnet"
+
+/*
+ImportedFile is the outcome of importing one file of an era directory. Status is imported, skipped or failed.
+Headers and Participation count the rows written for every epoch, which are only unchanged when the file
+was imported before
+*/
+type ImportedFile struct {
+	Name          string           `json:"name"`
+	Status        string           `json:"status"`
+	Reason        string           `json:"reason,omitempty"`
+	Blocks        int              `json:"blocks"`
+	Epochs        int              `json:"epochs"`
+	Headers       []db.WriteCounts `json:"headers,omitempty"`
+	Participation []db.WriteCounts `json:"participation,omitempty"`
+}
+
+/*
+ImportReport lists what was imported from every file of an era directory
+*/
+type ImportReport struct {
+	Dir   string         `json:"dir"`
+	Files []ImportedFile `json:"files"`
+}
+
+// eraBlock is what the importer keeps of a block: its header row and the attestations it included
+type eraBlock struct {
+	slot         int64
+	data         *model.BeaconChainData
+	attestations []model.Attestation
+}
+
+/*
+eraImport carries the last epoch of an era over to the next era file. Attestations for it can be included
+up to the end of the following epoch, whose blocks are in the next file
+*/
+type eraImport struct {
+	pending    *epochAttestations
+	targetRoot string
+	nextSlot   int64
+}
+
+/*
+This method imports every .era file of a directory in name order, which is era order for the standard
+<network>-<era>-<root>.era names. Headers go to beacon_chain_data and the participation of every epoch
+whose inclusion window is covered goes to the committees, attestations and epoch_participation tables.
+Committees come from the validator registry and randao mixes of the state stored with each era, so
+nothing is fetched from the beacon nodes. .era1 files hold execution layer history and are skipped, as are
+the era files of other networks, since the fork of a block is taken from the mainnet fork schedule.
+Epochs are written with ImportEpochData, so the indexing cursor does not move: the indexer keeps resuming
+from its own cursor, or from the last EPOCH_COUNT epochs when it has none, instead of fetching every slot
+from the last imported epoch up to the head
+*/
+func (s *Service) ImportEraDirectory(dir string) (*ImportReport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	report := &ImportReport{Dir: dir, Files: []ImportedFile{}}
+	importer := &eraImport{}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch filepath.Ext(name) {
+		case ".era1":
+			report.Files = append(report.Files, ImportedFile{Name: name, Status: "skipped", Reason: "era1 files hold execution layer blocks, not beacon chain data"})
+		case ".era":
+			if network := strings.SplitN(name, "-", 2)[0]; network != eraNetwork {
+				report.Files = append(report.Files, ImportedFile{Name: name, Status: "skipped", Reason: fmt.Sprintf("era files of %s can not be imported, the fork schedule is the one of %s", network, eraNetwork)})
+				continue
+			}
+			file, err := s.importEraFile(filepath.Join(dir, name), importer)
+			file.Name = name
+			if err != nil {
+				logger.LogError(fmt.Errorf("failed to import %s: %w", name, err))
+				file.Status, file.Reason = "failed", err.Error()
+				importer.pending = nil
+			}
+			report.Files = append(report.Files, file)
+		}
+	}
+	if importer.pending != nil {
+		logger.LogInfo("Participation of epoch", importer.pending.epoch, "was not imported, the era file with the end of its inclusion window is missing")
+	}
+	return report, nil
+}
+
+/*
+This method imports one era file. Its blocks are read first and its state last, so blocks are held until the
+state provides the committees and target roots of their epochs
+*/
+func (s *Service) importEraFile(path string, importer *eraImport) (ImportedFile, error) {
+	result := ImportedFile{Status: "imported"}
+	file, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+
+	var blocks []eraBlock
+	var history *ssz.StateHistory
+	var registry *validatorRegistry
+	err = era.Read(file, era.Visitor{
+		Block: func(block []byte) error {
+			decoded, err := decodeEraBlock(block)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, decoded)
+			return nil
+		},
+		State: func(state io.Reader) error {
+			var reader *ssz.ValidatorRegistryReader
+			var err error
+			history, reader, err = ssz.NewStateHistoryReader(state)
+			if err != nil {
+				return fmt.Errorf("reading era state: %w", err)
+			}
+			registry, err = readValidatorRegistry(reader)
+			return err
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+	if history == nil {
+		return result, fmt.Errorf("era file has no state")
+	}
+	// The file name can be changed, the state can not
+	if history.GenesisValidatorsRoot != spec.MainnetGenesisValidatorsRoot {
+		return result, fmt.Errorf("era file is not from %s, its state has genesis validators root %s", eraNetwork, encodeHex(history.GenesisValidatorsRoot[:]))
+	}
+
+	// An era file holds every block of its era, so the slots of its epochs without a block were missed. The
+	// genesis block of slot 0 is never in an era file
+	eraSlot := int64(history.Slot)
+	bySlot := make(map[int64]*model.BeaconChainData, len(blocks))
+	for _, block := range blocks {
+		bySlot[block.slot] = block.data
+	}
+	secondsPerSlot, _ := strconv.ParseInt(os.Getenv("SECONDS_PER_SLOT"), 10, 64)
+	for epoch := (eraSlot - spec.SlotsPerHistoricalRoot) / spec.SlotsPerEpoch; epoch < eraSlot/spec.SlotsPerEpoch; epoch++ {
+		if epoch < 0 {
+			continue
+		}
+		var rows []model.BeaconChainData
+		var missed []int64
+		for slot := epoch * spec.SlotsPerEpoch; slot < (epoch+1)*spec.SlotsPerEpoch; slot++ {
+			data, ok := bySlot[slot]
+			if !ok {
+				if slot > 0 {
+					missed = append(missed, slot)
+				}
+				continue
+			}
+			row := *data
+			row.Epoch = epoch
+			row.Data.UnixTime = GenesisUnixTime + slot*secondsPerSlot
+			rows = append(rows, row)
+		}
+		counts, err := s.db.ImportEpochData(epoch, rows, missed)
+		if err != nil {
+			return result, err
+		}
+		result.Blocks += len(rows)
+		result.Headers = append(result.Headers, counts)
+	}
+	result.Participation, err = s.importEraParticipation(blocks, history, registry, importer)
+	result.Epochs = len(result.Participation)
+	return result, err
+}
+
+/*
+This method computes and stores the participation of the epochs of an era. The state at the end of era N
+has the block roots of all its slots and its registry gives the active set of every earlier epoch, since
+activation and exit epochs never change once set. The last epoch of the era is carried over to the next file
+*/
+func (s *Service) importEraParticipation(blocks []eraBlock, history *ssz.StateHistory, registry *validatorRegistry, importer *eraImport) ([]db.WriteCounts, error) {
+	eraSlot := int64(history.Slot)
+	firstEpoch := (eraSlot - spec.SlotsPerHistoricalRoot) / spec.SlotsPerEpoch
+	if firstEpoch < 0 {
+		firstEpoch = 0
+	}
+	lastEpoch := eraSlot/spec.SlotsPerEpoch - 1
+
+	epochs := make(map[int64]*epochAttestations)
+	targetRoots := make(map[int64]string)
+	if importer.pending != nil && importer.nextSlot == eraSlot-spec.SlotsPerHistoricalRoot {
+		epochs[importer.pending.epoch] = importer.pending
+		targetRoots[importer.pending.epoch] = importer.targetRoot
+	} else if importer.pending != nil {
+		logger.LogInfo("Participation of epoch", importer.pending.epoch, "was not imported, the next era file does not follow its era")
+	}
+	importer.pending = nil
+	for epoch := firstEpoch; epoch <= lastEpoch; epoch++ {
+		mix, err := history.RandaoMix(spec.SeedEpoch(uint64(epoch)))
+		if err != nil {
+			return nil, err
+		}
+		targetRoot, err := history.BlockRoot(uint64(epoch * spec.SlotsPerEpoch))
+		if err != nil {
+			return nil, err
+		}
+		committees, _ := registry.committees(epoch, mix, "")
+		epochs[epoch] = newEpochAttestations(epoch, committees)
+		targetRoots[epoch] = encodeHex(targetRoot[:])
+	}
+	for _, block := range blocks {
+		for _, attestation := range block.attestations {
+			targetEpoch, _ := strconv.ParseInt(attestation.Details.Target.Epoch, 10, 64)
+			if collected, ok := epochs[targetEpoch]; ok {
+				collected.add(block.slot, attestation, targetRoots[targetEpoch])
+			}
+		}
+	}
+
+	order := make([]int64, 0, len(epochs))
+	for epoch := range epochs {
+		order = append(order, epoch)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	var imported []db.WriteCounts
+	for _, epoch := range order {
+		collected := epochs[epoch]
+		if epoch == lastEpoch {
+			importer.pending, importer.targetRoot, importer.nextSlot = collected, targetRoots[epoch], eraSlot
+			continue
+		}
+		participation, committees := collected.summarise()
+		counts, err := s.db.InsertEpochParticipation(participation, committees)
+		if err != nil {
+			return imported, err
+		}
+		imported = append(imported, counts)
+	}
+	return imported, nil
+}
+
+/*
+This function decodes the header and the attestations of a block of an era file. Era files only hold the
+canonical chain
+*/
+func decodeEraBlock(block []byte) (eraBlock, error) {
+	slot, err := ssz.BlockSlot(block)
+	if err != nil {
+		return eraBlock{}, err
+	}
+	fork, err := model.ForkAt(spec.ForkPosition(slot / spec.SlotsPerEpoch))
+	if err != nil {
+		return eraBlock{}, err
+	}
+	header, signature, err := ssz.DecodeSignedBlockHeader(block, fork)
+	if err != nil {
+		return eraBlock{}, fmt.Errorf("decoding block of slot %v: %w", slot, err)
+	}
+	decoded, err := ssz.DecodeBlockAttestations(block, fork)
+	if err != nil {
+		return eraBlock{}, fmt.Errorf("decoding attestations of slot %v: %w", slot, err)
+	}
+	attestations := make([]model.Attestation, 0, len(decoded))
+	for _, attestation := range decoded {
+		attestations = append(attestations, toModelAttestation(attestation))
+	}
+	root := header.HashTreeRoot()
+	data := &model.BeaconChainData{Data: model.SlotData{
+		Root:      encodeHex(root[:]),
+		Canonical: true,
+		Header: model.HeaderData{
+			Message: model.MessageData{
+				Slot:          strconv.FormatUint(header.Slot, 10),
+				ProposerIndex: strconv.FormatUint(header.ProposerIndex, 10),
+				ParentRoot:    encodeHex(header.ParentRoot[:]),
+				StateRoot:     encodeHex(header.StateRoot[:]),
+				BodyRoot:      encodeHex(header.BodyRoot[:]),
+			},
+			Signature: encodeHex(signature[:]),
+		},
+	}}
+	return eraBlock{slot: int64(header.Slot), data: data, attestations: attestations}, nil
+}
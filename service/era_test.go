@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"go-beacon-chain-indexer/db"
+	"go-beacon-chain-indexer/era"
+	"go-beacon-chain-indexer/spec"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+const eraTestValidators = 64
+
+func eraTestRoot(kind string, n uint64) [32]byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], n)
+	return sha256.Sum256(append([]byte(kind), b[:]...))
+}
+
+// eraTestAttestation is a phase0 attestation for committee 0 of its slot
+type eraTestAttestation struct {
+	slot       uint64
+	bits       byte
+	targetRoot [32]byte
+}
+
+/*
+eraTestBlock encodes a phase0 SignedBeaconBlock of the slot whose body only holds the given attestations
+*/
+func eraTestBlock(slot uint64, attestations []eraTestAttestation) []byte {
+	var encoded [][]byte
+	for _, attestation := range attestations {
+		var a bytes.Buffer
+		binary.Write(&a, binary.LittleEndian, uint32(4+128+96))
+		binary.Write(&a, binary.LittleEndian, attestation.slot)
+		binary.Write(&a, binary.LittleEndian, uint64(0))
+		a.Write(make([]byte, 32))
+		a.Write(make([]byte, 40))
+		binary.Write(&a, binary.LittleEndian, attestation.slot/spec.SlotsPerEpoch)
+		a.Write(attestation.targetRoot[:])
+		a.Write(make([]byte, 96))
+		a.WriteByte(attestation.bits)
+		encoded = append(encoded, a.Bytes())
+	}
+	var list bytes.Buffer
+	offset := 4 * len(encoded)
+	for _, a := range encoded {
+		binary.Write(&list, binary.LittleEndian, uint32(offset))
+		offset += len(a)
+	}
+	for _, a := range encoded {
+		list.Write(a)
+	}
+
+	// randao_reveal, eth1_data and graffiti, then the offsets of the five operation lists
+	const bodyFixedSize = 96 + 72 + 32 + 5*4
+	var body bytes.Buffer
+	body.Write(make([]byte, 96+72+32))
+	for _, end := range []int{0, 0, 0, list.Len(), list.Len()} {
+		binary.Write(&body, binary.LittleEndian, uint32(bodyFixedSize+end))
+	}
+	body.Write(list.Bytes())
+
+	var block bytes.Buffer
+	binary.Write(&block, binary.LittleEndian, uint32(4+96))
+	block.Write(make([]byte, 96))
+	binary.Write(&block, binary.LittleEndian, slot)
+	binary.Write(&block, binary.LittleEndian, slot%eraTestValidators)
+	parentRoot := eraTestRoot("block", slot-1)
+	block.Write(parentRoot[:])
+	block.Write(make([]byte, 32))
+	binary.Write(&block, binary.LittleEndian, uint32(8+8+32+32+4))
+	block.Write(body.Bytes())
+	return block.Bytes()
+}
+
+/*
+eraTestState encodes the part of a phase0 BeaconState the importer reads, everything up to the randao mixes
+and the validator registry, for eraTestValidators validators active from genesis on
+*/
+func eraTestState(slot uint64, genesisValidatorsRoot [32]byte) []byte {
+	var state bytes.Buffer
+	state.Write(make([]byte, 8))
+	state.Write(genesisValidatorsRoot[:])
+	binary.Write(&state, binary.LittleEndian, slot)
+	state.Write(make([]byte, 16+112))
+	for position := uint64(0); position < spec.SlotsPerHistoricalRoot; position++ {
+		// The slot of the era whose root is kept at this position
+		historySlot := slot - spec.SlotsPerHistoricalRoot + position
+		root := eraTestRoot("block", historySlot)
+		state.Write(root[:])
+	}
+	state.Write(make([]byte, spec.SlotsPerHistoricalRoot*32))
+	state.Write(make([]byte, 4+72+4+8))
+	prefixSize := state.Len() + 4 + 4 + spec.EpochsPerHistoricalVector*32
+	binary.Write(&state, binary.LittleEndian, uint32(prefixSize))
+	binary.Write(&state, binary.LittleEndian, uint32(prefixSize+eraTestValidators*121))
+	for epoch := uint64(0); epoch < spec.EpochsPerHistoricalVector; epoch++ {
+		mix := eraTestRoot("mix", epoch)
+		state.Write(mix[:])
+	}
+	for i := uint64(0); i < eraTestValidators; i++ {
+		pubkey := eraTestRoot("validator", i)
+		state.Write(pubkey[:])
+		state.Write(make([]byte, 16+32))
+		binary.Write(&state, binary.LittleEndian, uint64(spec.MaxEffectiveBalance))
+		state.WriteByte(0)
+		binary.Write(&state, binary.LittleEndian, []uint64{0, 0, spec.FarFutureEpoch, spec.FarFutureEpoch})
+	}
+	for i := 0; i < eraTestValidators; i++ {
+		binary.Write(&state, binary.LittleEndian, uint64(spec.MaxEffectiveBalance))
+	}
+	return state.Bytes()
+}
+
+func writeEraTestFile(t *testing.T, path string, blocks [][]byte, state []byte) {
+	t.Helper()
+	var file bytes.Buffer
+	record := func(recordType uint16, data []byte) {
+		header := make([]byte, 8)
+		binary.BigEndian.PutUint16(header, recordType)
+		binary.LittleEndian.PutUint32(header[2:], uint32(len(data)))
+		file.Write(header)
+		file.Write(data)
+	}
+	compress := func(data []byte) []byte {
+		var compressed bytes.Buffer
+		writer := snappy.NewBufferedWriter(&compressed)
+		writer.Write(data)
+		writer.Close()
+		return compressed.Bytes()
+	}
+	record(era.TypeVersion, nil)
+	for _, block := range blocks {
+		record(era.TypeCompressedSignedBeaconBlock, compress(block))
+	}
+	record(era.TypeCompressedBeaconState, compress(state))
+	if err := os.WriteFile(path, file.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+/*
+Two consecutive eras of 64 validators, one committee of two per slot. The votes of the last epoch of the
+first era are split over both files and must be merged, the file of another network must be skipped and a
+file whose state is not from mainnet must not write anything
+*/
+func TestImportEraDirectory(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	dir := t.TempDir()
+	target := func(epoch uint64) [32]byte { return eraTestRoot("block", epoch*spec.SlotsPerEpoch) }
+
+	writeEraTestFile(t, filepath.Join(dir, "mainnet-00001-aaaaaaaa.era"), [][]byte{
+		eraTestBlock(8130, []eraTestAttestation{{slot: 8129, bits: 0x07, targetRoot: target(254)}}),
+		eraTestBlock(8161, []eraTestAttestation{
+			{slot: 8160, bits: 0x05, targetRoot: target(255)},
+			// A vote for another chain does not count
+			{slot: 8161, bits: 0x07, targetRoot: eraTestRoot("fork", 255)},
+		}),
+		eraTestBlock(8191, nil),
+	}, eraTestState(8192, spec.MainnetGenesisValidatorsRoot))
+	writeEraTestFile(t, filepath.Join(dir, "mainnet-00002-bbbbbbbb.era"), [][]byte{
+		eraTestBlock(8193, []eraTestAttestation{{slot: 8160, bits: 0x06, targetRoot: target(255)}}),
+	}, eraTestState(16384, spec.MainnetGenesisValidatorsRoot))
+	writeEraTestFile(t, filepath.Join(dir, "mainnet-00003-cccccccc.era"), [][]byte{
+		eraTestBlock(16400, nil),
+	}, eraTestState(24576, eraTestRoot("network", 0)))
+	writeEraTestFile(t, filepath.Join(dir, "holesky-00001-dddddddd.era"), [][]byte{
+		eraTestBlock(8130, nil),
+	}, eraTestState(8192, eraTestRoot("network", 0)))
+
+	storage := db.NewMemory()
+	s := &Service{db: storage}
+	report, err := s.ImportEraDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, file := range report.Files {
+		statuses[file.Name] = file.Status
+	}
+	expected := map[string]string{
+		"holesky-00001-dddddddd.era": "skipped",
+		"mainnet-00001-aaaaaaaa.era": "imported",
+		"mainnet-00002-bbbbbbbb.era": "imported",
+		"mainnet-00003-cccccccc.era": "failed",
+	}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("expected %s to be %s, got %+v", name, status, report.Files)
+		}
+	}
+	first, second := report.Files[1], report.Files[2]
+	// The last epoch of an era is imported with the next file, which completes its inclusion window
+	if first.Blocks != 3 || first.Epochs != 255 || second.Blocks != 1 || second.Epochs != 256 {
+		t.Errorf("unexpected files %+v and %+v", first, second)
+	}
+
+	participation, err := storage.GetEpochParticipation(254)
+	if err != nil || participation == nil || participation.Participating != 2 || participation.Expected != eraTestValidators {
+		t.Errorf("unexpected participation of epoch 254: %+v, %v", participation, err)
+	}
+	participation, err = storage.GetEpochParticipation(255)
+	if err != nil || participation == nil || participation.Participating != 2 || participation.Expected != eraTestValidators {
+		t.Errorf("unexpected participation of epoch 255: %+v, %v", participation, err)
+	}
+	committees, err := storage.GetCommittees(255)
+	if err != nil || len(committees) != 32 {
+		t.Fatalf("expected the 32 committees of epoch 255, got %v, %v", len(committees), err)
+	}
+	if c := committees[0]; c.Slot != 8160 || c.CommitteeSize != 2 || c.AggregationBits != "0x07" || c.InclusionSlot != 8161 {
+		t.Errorf("expected both votes of slot 8160 merged with the first inclusion, got %+v", c)
+	}
+	if c := committees[1]; c.Slot != 8161 || c.Participants != 0 {
+		t.Errorf("expected the vote for another target to be ignored, got %+v", c)
+	}
+	if participation, _ = storage.GetEpochParticipation(511); participation != nil {
+		t.Errorf("the last epoch of the last era has no complete inclusion window, got %+v", participation)
+	}
+
+	missed, err := storage.GetMissedSlots(8160, 8191)
+	if err != nil || len(missed) != 30 {
+		t.Errorf("expected the 30 slots of epoch 255 without a block to be missed, got %v, %v", missed, err)
+	}
+	if rows, _ := storage.GetIndexedData(16384, 24575); len(rows) != 0 {
+		t.Errorf("a file that is not from mainnet must not be imported, got %v rows", len(rows))
+	}
+	// Imported history is not the indexer's, so it would not backfill from the last imported epoch to the head
+	if cursor, _ := storage.GetIndexingCursor(); cursor != -1 {
+		t.Errorf("expected an import to leave the indexing cursor unset, got %v", cursor)
+	}
+
+	// Importing the same era again leaves every row alone
+	file, err := s.importEraFile(filepath.Join(dir, "mainnet-00001-aaaaaaaa.era"), &eraImport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, counts := range append(file.Headers, file.Participation...) {
+		if counts.Inserted != 0 || counts.Updated != 0 {
+			t.Fatalf("expected a second import to change nothing, got %+v", counts)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"strconv"
+)
+
+/*
+ForkScheduleEntry is one fork of the node's /eth/v1/config/fork_schedule, versions are 0x prefixed hex
+*/
+type ForkScheduleEntry struct {
+	PreviousVersion string `json:"previous_version"`
+	CurrentVersion  string `json:"current_version"`
+	Epoch           string `json:"epoch"`
+}
+
+/*
+This method returns the fork schedule of the network, fetched once per process. The schedule lists every
+fork from phase0 on in order, so a fork is identified by its position rather than by its version bytes,
+which differ between networks
+*/
+func (s *Service) forkSchedule() ([]ForkScheduleEntry, error) {
+	s.forksMutex.Lock()
+	defer s.forksMutex.Unlock()
+	if s.forks != nil {
+		return s.forks, nil
+	}
+	var response struct {
+		Data []ForkScheduleEntry `json:"data"`
+	}
+	err := s.client.GetJSON("/eth/v1/config/fork_schedule", &response)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	s.forks = response.Data
+	return s.forks, nil
+}
+
+/*
+This method returns the fork active at the epoch
+*/
+func (s *Service) forkAtEpoch(epoch int64) (model.ForkVersion, error) {
+	position, _, err := s.forkEntryAt(epoch)
+	if err != nil {
+		return "", err
+	}
+	return model.ForkAt(position)
+}
+
+/*
+This method returns the position and the schedule entry of the fork active at the epoch
+*/
+func (s *Service) forkEntryAt(epoch int64) (int, ForkScheduleEntry, error) {
+	forks, err := s.forkSchedule()
+	if err != nil {
+		return 0, ForkScheduleEntry{}, err
+	}
+	if len(forks) == 0 {
+		return 0, ForkScheduleEntry{}, errors.New("the beacon node returned an empty fork schedule")
+	}
+	position := 0
+	for i, fork := range forks {
+		forkEpoch, err := strconv.ParseInt(fork.Epoch, 10, 64)
+		if err != nil {
+			// Unscheduled forks are announced with the far future epoch, which does not fit an int64
+			continue
+		}
+		if forkEpoch <= epoch {
+			position = i
+		}
+	}
+	return position, forks[position], nil
+}
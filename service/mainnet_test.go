@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/bitfield"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"go-beacon-chain-indexer/ssz"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+/*
+These tests read real mainnet blocks from the beacon node in TEST_BEACON_NODE_URL and are skipped without
+one. The fixtures in testdata are hand built in the response format, these check the same code against
+what the chain actually produced
+*/
+func newMainnetService(t *testing.T, useSSZ bool) *Service {
+	t.Helper()
+	url := os.Getenv("TEST_BEACON_NODE_URL")
+	if url == "" {
+		t.Skip("TEST_BEACON_NODE_URL is not set")
+	}
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	return &Service{client: newTestClient(t, 3, url), cache: NewResponseCache(1<<20, ""), useSSZ: useSSZ}
+}
+
+// A deneb block and an electra block of mainnet, both long finalized
+var mainnetAttestationBlocks = map[model.ForkVersion]int64{
+	model.Deneb:   9000000,
+	model.Electra: 11650000,
+}
+
+func TestMainnetBlockAttestationsSplitIntoCommittees(t *testing.T) {
+	for fork, slot := range mainnetAttestationBlocks {
+		t.Run(string(fork), func(t *testing.T) {
+			sszService := newMainnetService(t, true)
+			attestations, err := sszService.fetchBlockAttestations(slot)
+			if err != nil || len(attestations) == 0 {
+				t.Fatalf("failed to fetch the attestations of slot %v over ssz: %v", slot, err)
+			}
+			// The JSON endpoint must decode to exactly what the SSZ block holds
+			jsonAttestations, err := newMainnetService(t, false).fetchBlockAttestations(slot)
+			if err != nil || !reflect.DeepEqual(attestations, jsonAttestations) {
+				t.Fatalf("ssz and json attestations of slot %v differ: %v", slot, err)
+			}
+
+			byEpoch := make(map[int64]*epochAttestations)
+			multiCommittee := false
+			for _, attestation := range attestations {
+				if (attestation.CommitteeBits != "") != (fork == model.Electra) {
+					t.Fatalf("attestation does not have the %s layout: %+v", fork, attestation)
+				}
+				targetEpoch, _ := strconv.ParseInt(attestation.Details.Target.Epoch, 10, 64)
+				collected, ok := byEpoch[targetEpoch]
+				if !ok {
+					collected = newEpochAttestations(targetEpoch, fetchMainnetCommittees(t, sszService, slot, targetEpoch))
+					byEpoch[targetEpoch] = collected
+				}
+				attestationSlot, _ := strconv.ParseInt(attestation.Details.Slot, 10, 64)
+				bits, err := bitfield.BitlistFromHex(attestation.AggregationBits)
+				if err != nil {
+					t.Fatal(err)
+				}
+				split, err := collected.splitByCommittee(attestationSlot, attestation, bits)
+				if err != nil {
+					t.Fatalf("attestation of slot %v does not fit its committees: %v", attestationSlot, err)
+				}
+				participants := uint64(0)
+				for _, committee := range split {
+					participants += committee.Count()
+				}
+				if participants != bits.Count() {
+					t.Errorf("splitting lost votes: %v of %v", participants, bits.Count())
+				}
+				multiCommittee = multiCommittee || len(split) > 1
+			}
+			if fork == model.Electra && !multiCommittee {
+				t.Error("expected an electra block to hold an aggregate spanning committees")
+			}
+		})
+	}
+}
+
+func fetchMainnetCommittees(t *testing.T, s *Service, stateSlot int64, epoch int64) map[committeeKey]int {
+	t.Helper()
+	var response struct {
+		Data []model.Committee `json:"data"`
+	}
+	err := s.client.GetJSON(fmt.Sprintf("/eth/v1/beacon/states/%v/committees?epoch=%v", stateSlot, epoch), &response)
+	if err != nil {
+		t.Fatalf("failed to fetch the committees of epoch %v: %v", epoch, err)
+	}
+	committees := make(map[committeeKey]int)
+	for _, committee := range response.Data {
+		slot, _ := strconv.ParseInt(committee.Slot, 10, 64)
+		index, _ := strconv.ParseInt(committee.Index, 10, 64)
+		committees[committeeKey{slot: slot, index: index}] = len(committee.Validators)
+	}
+	return committees
+}
+
+/*
+Every fork of mainnet must decode strictly into its own body type, a field the model does not know about
+fails the test instead of being dropped
+*/
+func TestMainnetVersionedBlocks(t *testing.T) {
+	s := newMainnetService(t, false)
+	for position, forkEpoch := range spec.MainnetForkEpochs {
+		fork, _ := model.ForkAt(position)
+		t.Run(string(fork), func(t *testing.T) {
+			// The first slot of a fork can be missed, the next few are tried too
+			slot := int64(forkEpoch)*spec.SlotsPerEpoch + 1
+			for ; slot < int64(forkEpoch+1)*spec.SlotsPerEpoch; slot++ {
+				block, err := s.FetchBlock(slot)
+				if err != nil {
+					t.Fatalf("failed to decode the block of slot %v: %v", slot, err)
+				}
+				if block == nil {
+					continue
+				}
+				if block.Version != fork || block.Message.Slot != strconv.FormatInt(slot, 10) {
+					t.Errorf("slot %v decoded as a %s block of slot %s", slot, block.Version, block.Message.Slot)
+				}
+				checkMainnetBlockRoot(t, s, slot, fork)
+				return
+			}
+			t.Errorf("no block in the first epoch of %s", fork)
+		})
+	}
+}
+
+// The header decoded from the SSZ block must hash to the root the node reports for the slot
+func checkMainnetBlockRoot(t *testing.T, s *Service, slot int64, fork model.ForkVersion) {
+	t.Helper()
+	encoded, _, err := s.client.GetSSZ(fmt.Sprintf("/eth/v2/beacon/blocks/%v", slot))
+	if err != nil {
+		t.Fatalf("failed to fetch the ssz block of slot %v: %v", slot, err)
+	}
+	header, _, err := ssz.DecodeSignedBlockHeader(encoded, fork)
+	if err != nil {
+		t.Fatalf("failed to decode the ssz block of slot %v: %v", slot, err)
+	}
+	var response struct {
+		Data struct {
+			Root string `json:"root"`
+		} `json:"data"`
+	}
+	if err = s.client.GetJSON(fmt.Sprintf("/eth/v1/beacon/headers/%v", slot), &response); err != nil {
+		t.Fatalf("failed to fetch the header of slot %v: %v", slot, err)
+	}
+	root := header.HashTreeRoot()
+	if encodeHex(root[:]) != response.Data.Root {
+		t.Errorf("the %s block of slot %v hashes to %s, the node reports %s", fork, slot, encodeHex(root[:]), response.Data.Root)
+	}
+}
+
+/*
+The registry of the latest finalized checkpoint is loaded through the same header and state root checks as
+a pinned one, which hashes a state of the fork mainnet is on now. The checkpoint root itself is taken from
+the node here, the test checks the hashing and not the trust
+*/
+func TestMainnetTrustedRegistry(t *testing.T) {
+	s := newMainnetService(t, true)
+	var response struct {
+		Data struct {
+			Root string `json:"root"`
+		} `json:"data"`
+	}
+	err := s.client.GetJSON("/eth/v1/beacon/headers/finalized", &response)
+	if err != nil {
+		t.Fatalf("failed to fetch the finalized header: %v", err)
+	}
+	t.Setenv("TRUSTED_CHECKPOINT_ROOT", response.Data.Root)
+	t.Setenv("GENESIS_VALIDATORS_ROOT", mainnetGenesisValidatorsRoot)
+	registry, err := s.trustedRegistry()
+	if err != nil {
+		t.Fatalf("failed to load the registry of %s: %v", response.Data.Root, err)
+	}
+	if len(registry.publicKeys) < mainnetGenesisValidatorsCount {
+		t.Errorf("expected at least the genesis validators, got %v keys", len(registry.publicKeys))
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoBeaconNodes = errors.New("no beacon node is available to serve the request")
+
+/*
+BeaconNode is one configured upstream endpoint. Health and sync status are refreshed by the node
+pool's health checks, failures of individual requests are tracked by its own circuit breaker
+*/
+type BeaconNode struct {
+	URL     string
+	Weight  int
+	breaker *CircuitBreaker
+
+	mutex        sync.RWMutex
+	healthy      bool
+	synced       bool
+	syncDistance int64
+	lastChecked  time.Time
+}
+
+/*
+NodeStatus is a point in time view of a beacon node, used for logging and reporting
+*/
+type NodeStatus struct {
+	URL          string `json:"url"`
+	Weight       int    `json:"weight"`
+	Healthy      bool   `json:"healthy"`
+	Synced       bool   `json:"synced"`
+	SyncDistance int64  `json:"sync_distance"`
+	CircuitOpen  bool   `json:"circuit_open"`
+}
+
+func (n *BeaconNode) usable() bool {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.healthy && n.synced && !n.breaker.Open()
+}
+
+func (n *BeaconNode) Status() NodeStatus {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return NodeStatus{
+		URL:          n.URL,
+		Weight:       n.Weight,
+		Healthy:      n.healthy,
+		Synced:       n.synced,
+		SyncDistance: n.syncDistance,
+		CircuitOpen:  n.breaker.Open(),
+	}
+}
+
+/*
+NodePool holds the ordered list of beacon nodes and decides which of them a request is routed to
+*/
+type NodePool struct {
+	nodes           []*BeaconNode
+	client          *http.Client
+	maxSyncDistance int64
+}
+
+/*
+This method builds the node pool from the BEACON_NODES variable, a comma separated list of
+url|weight entries in order of preference. BEACON_NODE_URL is used when no list is configured
+*/
+func NewNodePool(client *http.Client) (*NodePool, error) {
+	spec := os.Getenv("BEACON_NODES")
+	if spec == "" {
+		spec = os.Getenv("BEACON_NODE_URL")
+	}
+	if spec == "" {
+		spec = DefaultBeaconNodeURL
+	}
+	pool := &NodePool{
+		client:          client,
+		maxSyncDistance: getEnvInt("MAX_SYNC_DISTANCE", 2),
+	}
+	threshold := int(getEnvInt("CIRCUIT_BREAKER_THRESHOLD", 5))
+	cooldown := time.Duration(getEnvInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		url, weightValue, hasWeight := strings.Cut(entry, "|")
+		weight := 1
+		if hasWeight {
+			parsed, err := strconv.Atoi(strings.TrimSpace(weightValue))
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid weight %q for beacon node %s", weightValue, url)
+			}
+			weight = parsed
+		}
+		pool.nodes = append(pool.nodes, &BeaconNode{
+			URL:     strings.TrimRight(strings.TrimSpace(url), "/"),
+			Weight:  weight,
+			breaker: NewCircuitBreaker(threshold, cooldown),
+			// Nodes are assumed usable until the first health check says otherwise
+			healthy: true,
+			synced:  true,
+		})
+	}
+	if len(pool.nodes) == 0 {
+		return nil, ErrNoBeaconNodes
+	}
+	return pool, nil
+}
+
+/*
+This method returns the nodes in the order a request should try them. Healthy and synced nodes come
+first, with the first one picked by weight so load is spread across them, followed by the remaining
+usable nodes in configured order. Unhealthy nodes are only returned as a last resort
+*/
+func (p *NodePool) Candidates() []*BeaconNode {
+	var usable, unusable []*BeaconNode
+	totalWeight := 0
+	for _, node := range p.nodes {
+		if node.usable() {
+			usable = append(usable, node)
+			totalWeight += node.Weight
+		} else {
+			unusable = append(unusable, node)
+		}
+	}
+	if len(usable) > 1 {
+		pick := int(randomInt63n(int64(totalWeight)))
+		for i, node := range usable {
+			pick -= node.Weight
+			if pick < 0 {
+				ordered := append([]*BeaconNode{node}, usable[:i]...)
+				usable = append(ordered, usable[i+1:]...)
+				break
+			}
+		}
+	}
+	return append(usable, unusable...)
+}
+
+func (p *NodePool) Nodes() []*BeaconNode {
+	return p.nodes
+}
+
+/*
+This method checks every node once and then keeps re-checking them in the background
+*/
+func (p *NodePool) StartHealthChecks(interval time.Duration) {
+	p.checkAll()
+	go func() {
+		for range time.Tick(interval) {
+			p.checkAll()
+		}
+	}()
+}
+
+func (p *NodePool) checkAll() {
+	var wg sync.WaitGroup
+	for _, node := range p.nodes {
+		wg.Add(1)
+		go func(node *BeaconNode) {
+			defer wg.Done()
+			p.check(node)
+		}(node)
+	}
+	wg.Wait()
+}
+
+/*
+This method queries /eth/v1/node/health and /eth/v1/node/syncing for a node and records the result
+*/
+func (p *NodePool) check(node *BeaconNode) {
+	healthy := false
+	response, err := p.client.Get(node.URL + "/eth/v1/node/health")
+	if err == nil {
+		response.Body.Close()
+		// 206 means the node is up but still syncing, the syncing endpoint tells us how far behind it is
+		healthy = response.StatusCode == http.StatusOK || response.StatusCode == http.StatusPartialContent
+	}
+
+	synced := false
+	var distance int64
+	if healthy {
+		var syncing struct {
+			Data struct {
+				IsSyncing    bool   `json:"is_syncing"`
+				SyncDistance string `json:"sync_distance"`
+				IsOptimistic bool   `json:"is_optimistic"`
+			} `json:"data"`
+		}
+		response, err = p.client.Get(node.URL + "/eth/v1/node/syncing")
+		if err == nil {
+			err = json.NewDecoder(response.Body).Decode(&syncing)
+			response.Body.Close()
+		}
+		if err == nil {
+			distance, _ = strconv.ParseInt(syncing.Data.SyncDistance, 10, 64)
+			synced = !syncing.Data.IsOptimistic && (!syncing.Data.IsSyncing || distance <= p.maxSyncDistance)
+		}
+	}
+
+	node.mutex.Lock()
+	changed := node.healthy != healthy || node.synced != synced
+	node.healthy = healthy
+	node.synced = synced
+	node.syncDistance = distance
+	node.lastChecked = time.Now()
+	node.mutex.Unlock()
+	if changed {
+		logger.LogInfo("Beacon node", node.URL, "healthy:", healthy, "synced:", synced, "sync distance:", distance)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T, nodes string) *NodePool {
+	t.Helper()
+	t.Setenv("BEACON_NODES", nodes)
+	t.Setenv("MAX_SYNC_DISTANCE", "2")
+	pool, err := NewNodePool(http.DefaultClient)
+	if err != nil {
+		t.Fatalf("failed to create the pool: %v", err)
+	}
+	return pool
+}
+
+func TestCandidatesPickByWeight(t *testing.T) {
+	pool := newTestPool(t, "http://a|3,http://b|1")
+	first := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		candidates := pool.Candidates()
+		if len(candidates) != 2 {
+			t.Fatalf("expected both nodes, got %v", len(candidates))
+		}
+		first[candidates[0].URL]++
+	}
+	// a is picked first three times out of four
+	if first["http://a"] < 2800 || first["http://a"] > 3200 {
+		t.Errorf("expected http://a first about 3000 times, got %v", first)
+	}
+}
+
+func TestCandidatesDemoteUnusableNodes(t *testing.T) {
+	pool := newTestPool(t, "http://a,http://b,http://c")
+	nodes := pool.Nodes()
+	nodes[0].mutex.Lock()
+	nodes[0].healthy = false
+	nodes[0].mutex.Unlock()
+	for i := 0; i < 5; i++ {
+		nodes[1].breaker.RecordFailure()
+	}
+	for i := 0; i < 20; i++ {
+		candidates := pool.Candidates()
+		// c is the only usable node, the others follow in configured order as a last resort
+		if len(candidates) != 3 || candidates[0] != nodes[2] || candidates[1] != nodes[0] || candidates[2] != nodes[1] {
+			t.Fatalf("unexpected order %v %v %v", candidates[0].URL, candidates[1].URL, candidates[2].URL)
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		health          int
+		syncing         string
+		healthy, synced bool
+	}{
+		{"synced", http.StatusOK, `{"data":{"is_syncing":false,"sync_distance":"0","is_optimistic":false}}`, true, true},
+		{"close behind", http.StatusPartialContent, `{"data":{"is_syncing":true,"sync_distance":"2","is_optimistic":false}}`, true, true},
+		{"far behind", http.StatusPartialContent, `{"data":{"is_syncing":true,"sync_distance":"40","is_optimistic":false}}`, true, false},
+		{"optimistic", http.StatusOK, `{"data":{"is_syncing":false,"sync_distance":"0","is_optimistic":true}}`, true, false},
+		{"broken syncing", http.StatusOK, `not json`, true, false},
+		{"unhealthy", http.StatusServiceUnavailable, "", false, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/eth/v1/node/health" {
+					w.WriteHeader(test.health)
+					return
+				}
+				fmt.Fprint(w, test.syncing)
+			}))
+			defer server.Close()
+			pool := newTestPool(t, server.URL)
+			node := pool.Nodes()[0]
+			pool.check(node)
+			status := node.Status()
+			if status.Healthy != test.healthy || status.Synced != test.synced || node.usable() != (test.healthy && test.synced) {
+				t.Errorf("expected healthy %v and synced %v, got %+v", test.healthy, test.synced, status)
+			}
+			if node.lastChecked.Before(time.Now().Add(-time.Minute)) {
+				t.Error("expected the check time to be recorded")
+			}
+		})
+	}
+
+	// A node that can not be reached is unhealthy
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	pool := newTestPool(t, server.URL)
+	pool.check(pool.Nodes()[0])
+	if pool.Nodes()[0].usable() {
+		t.Error("expected an unreachable node to be unusable")
+	}
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/bitfield"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	maxCommitteesPerSlot = 64
+)
+
+type committeeKey struct {
+	slot  int64
+	index int64
+}
+
+/*
+committeePosition locates a single validator: the committee it attests in and its position in that committee
+*/
+type committeePosition struct {
+	key      committeeKey
+	position int
+	found    bool
+}
+
+/*
+epochAttestations holds everything the participation engine has gathered for one epoch: the size of every
+committee and, per committee, the OR of all aggregates that were included on chain
+*/
+type epochAttestations struct {
+	epoch      int64
+	committees map[committeeKey]int
+	merged     map[committeeKey]bitfield.Bitlist
+	inclusion  map[committeeKey]int64
+	validator  committeePosition
+}
+
+/*
+This method returns the participation of an epoch, reading it from the database when it has already been
+computed and computing it otherwise. Results are only stored once the whole inclusion window is finalized
+*/
+func (s *Service) GetEpochParticipation(epoch int64) (*model.EpochParticipation, error) {
+	reads := s.reads
+	if reads == nil {
+		reads = s.db
+	}
+	stored, err := reads.GetEpochParticipation(epoch)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		return stored, nil
+	}
+	participation, committees, err := s.ComputeEpochParticipation(epoch)
+	if err != nil {
+		return nil, err
+	}
+	_, inclusionEnd := inclusionWindow(epoch)
+	if s.isFinalizedSlot(inclusionEnd) {
+		counts, err := s.db.InsertEpochParticipation(participation, committees)
+		if err != nil {
+			logger.LogError(err)
+		} else {
+			logger.LogInfo("Participation rows of epoch", epoch, "inserted", counts.Inserted, "updated", counts.Updated, "unchanged", counts.Unchanged)
+		}
+	}
+	return participation, nil
+}
+
+/*
+This method computes the participation of an epoch. Every aggregate that votes for the epoch's canonical
+target and is included anywhere in the inclusion window is ORed into its (slot, committee) bitlist, so a
+validator counts as participating if any included aggregate carries its vote. This matches the target
+participation a consensus client reports for the epoch
+*/
+func (s *Service) ComputeEpochParticipation(epoch int64) (*model.EpochParticipation, []model.CommitteeParticipation, error) {
+	collected, err := s.collectEpochAttestations(epoch, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	participation, committees := collected.summarise()
+	return participation, committees, nil
+}
+
+/*
+This method turns the collected attestations of an epoch into the epoch totals and one row per committee,
+in slot and committee order
+*/
+func (c *epochAttestations) summarise() (*model.EpochParticipation, []model.CommitteeParticipation) {
+	participation := &model.EpochParticipation{Epoch: c.epoch, UnixTime: epochUnixTime(c.epoch)}
+	committees := make([]model.CommitteeParticipation, 0, len(c.committees))
+	for key, size := range c.committees {
+		committee := model.CommitteeParticipation{
+			Epoch:          c.epoch,
+			Slot:           key.slot,
+			CommitteeIndex: key.index,
+			CommitteeSize:  size,
+		}
+		if merged, ok := c.merged[key]; ok {
+			committee.AggregationBits = merged.Hex()
+			committee.Participants = int(merged.Count())
+			committee.InclusionSlot = c.inclusion[key]
+		}
+		participation.Participating += int64(committee.Participants)
+		participation.Expected += int64(size)
+		committees = append(committees, committee)
+	}
+	sort.Slice(committees, func(i, j int) bool {
+		if committees[i].Slot != committees[j].Slot {
+			return committees[i].Slot < committees[j].Slot
+		}
+		return committees[i].CommitteeIndex < committees[j].CommitteeIndex
+	})
+	if participation.Expected > 0 {
+		participation.ParticipationRate = float64(participation.Participating) / float64(participation.Expected)
+	}
+	return participation, committees
+}
+
+/*
+This method reports whether a validator's attestation for the epoch made it on chain. found is false when
+the validator is not part of any committee in the epoch
+*/
+func (s *Service) ComputeValidatorParticipation(epoch int64, validatorIndex string) (participated bool, found bool, err error) {
+	collected, err := s.collectEpochAttestations(epoch, validatorIndex)
+	if err != nil {
+		return false, false, err
+	}
+	if !collected.validator.found {
+		return false, false, nil
+	}
+	merged, ok := collected.merged[collected.validator.key]
+	if !ok {
+		return false, true, nil
+	}
+	return merged.BitAt(uint64(collected.validator.position)), true, nil
+}
+
+func (s *Service) collectEpochAttestations(epoch int64, validatorIndex string) (*epochAttestations, error) {
+	committees, validator, err := s.fetchCommittees(epoch, validatorIndex)
+	if err != nil {
+		return nil, err
+	}
+	collected := newEpochAttestations(epoch, committees)
+	collected.validator = validator
+	targetRoot, err := s.fetchEpochBoundaryRoot(epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSlot, lastSlot := inclusionWindow(epoch)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var fetchErr error
+	for slot := firstSlot; slot <= lastSlot; slot++ {
+		s.rateLimit()
+		wg.Add(1)
+		go func(slot int64) {
+			defer wg.Done()
+			attestations, err := s.fetchBlockAttestations(slot)
+			if errors.Is(err, ErrNotFound) {
+				return
+			}
+			if err != nil {
+				mutex.Lock()
+				fetchErr = fmt.Errorf("failed to fetch attestations included in slot %v: %w", slot, err)
+				mutex.Unlock()
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, attestation := range attestations {
+				collected.add(slot, attestation, targetRoot)
+			}
+		}(slot)
+	}
+	wg.Wait()
+	if fetchErr != nil {
+		// A missing block would silently lower participation, so the whole epoch is treated as failed
+		logger.LogError(fetchErr)
+		return nil, fetchErr
+	}
+	return collected, nil
+}
+
+func newEpochAttestations(epoch int64, committees map[committeeKey]int) *epochAttestations {
+	return &epochAttestations{
+		epoch:      epoch,
+		committees: committees,
+		merged:     make(map[committeeKey]bitfield.Bitlist),
+		inclusion:  make(map[committeeKey]int64),
+	}
+}
+
+/*
+This method merges one included aggregate into the collected bits of the committees it covers. Aggregates
+for other epochs or for a non canonical target are ignored, as are bitlists that do not fit their committees
+*/
+func (c *epochAttestations) add(inclusionSlot int64, attestation model.Attestation, targetRoot string) {
+	targetEpoch, _ := strconv.ParseInt(attestation.Details.Target.Epoch, 10, 64)
+	if targetEpoch != c.epoch || !strings.EqualFold(attestation.Details.Target.Root, targetRoot) {
+		return
+	}
+	slot, _ := strconv.ParseInt(attestation.Details.Slot, 10, 64)
+	aggregationBits, err := bitfield.BitlistFromHex(attestation.AggregationBits)
+	if err != nil {
+		logger.LogError(fmt.Errorf("invalid aggregation bits %s in attestation included in slot %v: %w", attestation.AggregationBits, inclusionSlot, err))
+		return
+	}
+	committees, err := c.splitByCommittee(slot, attestation, aggregationBits)
+	if err != nil {
+		logger.LogError(fmt.Errorf("attestation included in slot %v does not fit its committees: %w", inclusionSlot, err))
+		return
+	}
+	for key, committeeBits := range committees {
+		merged, ok := c.merged[key]
+		if !ok {
+			c.merged[key] = committeeBits
+			c.inclusion[key] = inclusionSlot
+			continue
+		}
+		c.merged[key], _ = merged.Or(committeeBits)
+		if inclusionSlot < c.inclusion[key] {
+			c.inclusion[key] = inclusionSlot
+		}
+	}
+}
+
+/*
+This method splits an aggregate into one bitlist per committee. Before electra an aggregate covers the one
+committee named in its data. From electra on (EIP-7549) the data index is always 0 and the aggregate covers
+every committee set in committee_bits, in increasing index order, with their bits laid out back to back
+*/
+func (c *epochAttestations) splitByCommittee(slot int64, attestation model.Attestation, aggregationBits bitfield.Bitlist) (map[committeeKey]bitfield.Bitlist, error) {
+	if attestation.CommitteeBits == "" {
+		index, _ := strconv.ParseInt(attestation.Details.Index, 10, 64)
+		key := committeeKey{slot: slot, index: index}
+		size, ok := c.committees[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown committee %v of slot %v", index, slot)
+		}
+		err := aggregationBits.Validate(uint64(size))
+		if err != nil {
+			return nil, err
+		}
+		return map[committeeKey]bitfield.Bitlist{key: aggregationBits}, nil
+	}
+
+	committeeBits, err := bitfield.BitvectorFromHex(attestation.CommitteeBits, maxCommitteesPerSlot)
+	if err != nil {
+		return nil, err
+	}
+	if attestation.Details.Index != "0" {
+		return nil, fmt.Errorf("electra attestation has committee index %s in its data", attestation.Details.Index)
+	}
+	split := make(map[committeeKey]bitfield.Bitlist)
+	offset := uint64(0)
+	for _, index := range committeeBits.Indices() {
+		key := committeeKey{slot: slot, index: int64(index)}
+		size, ok := c.committees[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown committee %v of slot %v", index, slot)
+		}
+		committee := bitfield.NewBitlist(uint64(size))
+		for i := uint64(0); i < uint64(size); i++ {
+			committee.SetBitAt(i, aggregationBits.BitAt(offset+i))
+		}
+		split[key] = committee
+		offset += uint64(size)
+	}
+	if aggregationBits.Len() != offset {
+		return nil, fmt.Errorf("%w: aggregation bits hold %d bits for committees of %d members", bitfield.ErrLengthMismatch, aggregationBits.Len(), offset)
+	}
+	return split, nil
+}
+
+/*
+This method returns the size of every committee of an epoch keyed by (slot, committee index), and the
+position of the given validator if one is passed. Committees are computed locally when possible and
+fetched from the committees endpoint otherwise
+*/
+func (s *Service) fetchCommittees(epoch int64, validatorIndex string) (map[committeeKey]int, committeePosition, error) {
+	if s.localShuffling {
+		committees, validator, err := s.computeCommittees(epoch, validatorIndex)
+		if err == nil {
+			return committees, validator, nil
+		}
+		logger.LogError(fmt.Errorf("falling back to the committees endpoint for epoch %v: %w", epoch, err))
+	}
+	return s.fetchCommitteesFromAPI(epoch, validatorIndex)
+}
+
+func (s *Service) fetchCommitteesFromAPI(epoch int64, validatorIndex string) (map[committeeKey]int, committeePosition, error) {
+	var committees map[committeeKey]int
+	var validator committeePosition
+	err := s.streamBody(fmt.Sprintf("/eth/v1/beacon/states/finalized/committees?epoch=%v", epoch), s.isFinalizedEpoch(epoch), func(body io.Reader) error {
+		committees = make(map[committeeKey]int)
+		validator = committeePosition{}
+		return decodeDataArray(body, func(dec *json.Decoder) error {
+			var committee model.Committee
+			err := dec.Decode(&committee)
+			if err != nil {
+				return err
+			}
+			slot, _ := strconv.ParseInt(committee.Slot, 10, 64)
+			index, _ := strconv.ParseInt(committee.Index, 10, 64)
+			key := committeeKey{slot: slot, index: index}
+			committees[key] = len(committee.Validators)
+			if validatorIndex == "" {
+				return nil
+			}
+			for i, member := range committee.Validators {
+				if member == validatorIndex {
+					validator = committeePosition{key: key, position: i, found: true}
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logger.LogError(err)
+		return nil, validator, err
+	}
+	return committees, validator, nil
+}
+
+/*
+This method returns the root of the block at the start of the epoch, walking back over missed slots. That
+root is the target every correct attestation for the epoch votes for
+*/
+func (s *Service) fetchEpochBoundaryRoot(epoch int64) (string, error) {
+	slotsPerEpoch, _ := strconv.ParseInt(os.Getenv("SLOTS_PER_EPOCH"), 10, 64)
+	startSlot := epoch * slotsPerEpoch
+	for slot := startSlot; slot >= 0 && slot > startSlot-slotsPerEpoch*2; slot-- {
+		header, err := s.fetchBeaconData(slot)
+		if err != nil {
+			return "", err
+		}
+		if header != nil {
+			return header.Data.Root, nil
+		}
+	}
+	return "", fmt.Errorf("no block found before the start of epoch %v", epoch)
+}
+
+/*
+This function returns the range of slots whose blocks can include attestations for the epoch. Since
+deneb an attestation can be included up to the end of the epoch after its own
+*/
+func inclusionWindow(epoch int64) (int64, int64) {
+	slotsPerEpoch, _ := strconv.ParseInt(os.Getenv("SLOTS_PER_EPOCH"), 10, 64)
+	firstSlot := epoch*slotsPerEpoch + 1
+	lastSlot := (epoch+2)*slotsPerEpoch - 1
+	return firstSlot, lastSlot
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"encoding/json"
+	"go-beacon-chain-indexer/bitfield"
+	"go-beacon-chain-indexer/model"
+	"os"
+	"strings"
+	"testing"
+)
+
+const fixtureTargetRoot = "0x8f7a4c1e2b3d5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8"
+
+/*
+The fixtures are hand built in the /eth/v2/beacon/blocks/{block_id}/attestations response format of a deneb
+and an electra block, with committees shrunk to a handful of members so the expected bits can be worked out
+by hand. They are not real blocks, TestMainnetBlockAttestationsSplitIntoCommittees runs the same code on real
+mainnet blocks when a beacon node is configured
+*/
+func loadAttestationFixture(t *testing.T, name string) []model.Attestation {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	var response struct {
+		Version string              `json:"version"`
+		Data    []model.Attestation `json:"data"`
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+	return response.Data
+}
+
+func newFixtureEpoch() *epochAttestations {
+	return &epochAttestations{
+		epoch: 10,
+		committees: map[committeeKey]int{
+			{slot: 320, index: 0}: 5,
+			{slot: 320, index: 1}: 4,
+			{slot: 321, index: 0}: 3,
+		},
+		merged:    make(map[committeeKey]bitfield.Bitlist),
+		inclusion: make(map[committeeKey]int64),
+	}
+}
+
+func participants(collected *epochAttestations, key committeeKey) uint64 {
+	return collected.merged[key].Count()
+}
+
+func TestPreElectraAttestationsMergePerCommittee(t *testing.T) {
+	collected := newFixtureEpoch()
+	for _, attestation := range loadAttestationFixture(t, "deneb_attestations.json") {
+		collected.add(322, attestation, fixtureTargetRoot)
+	}
+
+	// Two aggregates for committee 0 overlap on member 1, the OR holds members 0, 1 and 3
+	key := committeeKey{slot: 320, index: 0}
+	if got := participants(collected, key); got != 3 {
+		t.Errorf("expected 3 participants in committee 0, got %d", got)
+	}
+	for i, expected := range []bool{true, true, false, true, false} {
+		if collected.merged[key].BitAt(uint64(i)) != expected {
+			t.Errorf("unexpected bit %d in merged bits %s", i, collected.merged[key].Hex())
+		}
+	}
+	if got := participants(collected, committeeKey{slot: 320, index: 1}); got != 4 {
+		t.Errorf("expected 4 participants in committee 1, got %d", got)
+	}
+	// Votes for a non canonical target and for the previous epoch are not counted
+	if _, ok := collected.merged[committeeKey{slot: 321, index: 0}]; ok {
+		t.Errorf("expected the attestation with a wrong target root to be ignored")
+	}
+	if _, ok := collected.merged[committeeKey{slot: 319, index: 0}]; ok {
+		t.Errorf("expected the attestation for the previous epoch to be ignored")
+	}
+}
+
+func TestElectraAttestationsSplitAcrossCommittees(t *testing.T) {
+	collected := newFixtureEpoch()
+	for _, attestation := range loadAttestationFixture(t, "electra_attestations.json") {
+		collected.add(322, attestation, fixtureTargetRoot)
+	}
+
+	// The first aggregate covers committees 0 and 1 back to back, the second adds member 4 of committee 0
+	committee0 := committeeKey{slot: 320, index: 0}
+	if got := participants(collected, committee0); got != 3 {
+		t.Errorf("expected 3 participants in committee 0, got %d", got)
+	}
+	for i, expected := range []bool{true, true, false, false, true} {
+		if collected.merged[committee0].BitAt(uint64(i)) != expected {
+			t.Errorf("unexpected bit %d in merged bits %s", i, collected.merged[committee0].Hex())
+		}
+	}
+	committee1 := committeeKey{slot: 320, index: 1}
+	if got := participants(collected, committee1); got != 2 {
+		t.Errorf("expected 2 participants in committee 1, got %d", got)
+	}
+	if !collected.merged[committee1].BitAt(0) || !collected.merged[committee1].BitAt(3) {
+		t.Errorf("expected members 0 and 3 of committee 1 to participate, got %s", collected.merged[committee1].Hex())
+	}
+	// The aggregate whose bits do not add up to the committee size is rejected without touching the valid one
+	if got := participants(collected, committeeKey{slot: 321, index: 0}); got != 3 {
+		t.Errorf("expected 3 participants in committee 0 of slot 321, got %d", got)
+	}
+}
+
+func TestInclusionSlotIsTheEarliest(t *testing.T) {
+	collected := newFixtureEpoch()
+	attestations := loadAttestationFixture(t, "deneb_attestations.json")
+	collected.add(330, attestations[0], fixtureTargetRoot)
+	collected.add(325, attestations[1], fixtureTargetRoot)
+	collected.add(340, attestations[1], fixtureTargetRoot)
+	if got := collected.inclusion[committeeKey{slot: 320, index: 0}]; got != 325 {
+		t.Errorf("expected inclusion slot 325, got %d", got)
+	}
+}
+
+func TestInclusionWindow(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	// From the slot after the first of the epoch to the last slot of the next epoch
+	if first, last := inclusionWindow(10); first != 321 || last != 383 {
+		t.Errorf("expected slots 321 to 383, got %v to %v", first, last)
+	}
+}
+
+func TestVotesMergeAcrossTheInclusionWindow(t *testing.T) {
+	collected := newEpochAttestations(10, map[committeeKey]int{{slot: 320, index: 0}: 4, {slot: 321, index: 0}: 2})
+	vote := func(bits string, targetEpoch string, targetRoot string) model.Attestation {
+		return model.Attestation{
+			AggregationBits: bits,
+			Details:         model.AttestationDetails{Slot: "320", Index: "0", Target: model.Epoch{Epoch: targetEpoch, Root: targetRoot}},
+		}
+	}
+	for _, included := range []struct {
+		slot        int64
+		attestation model.Attestation
+	}{
+		// Members 0 and 1, then the same aggregate again in a later block
+		{330, vote("0x13", "10", fixtureTargetRoot)},
+		{352, vote("0x13", "10", fixtureTargetRoot)},
+		// Members 1 and 2 overlap on member 1
+		{340, vote("0x16", "10", fixtureTargetRoot)},
+		// Member 3 votes for another target root and for the previous epoch, neither counts
+		{325, vote("0x18", "10", "0x"+strings.Repeat("ab", 32))},
+		{326, vote("0x18", "9", fixtureTargetRoot)},
+	} {
+		collected.add(included.slot, included.attestation, fixtureTargetRoot)
+	}
+
+	key := committeeKey{slot: 320, index: 0}
+	if got := collected.merged[key].Hex(); got != "0x17" {
+		t.Errorf("expected members 0, 1 and 2 merged into 0x17, got %s", got)
+	}
+	// The ignored votes were included earlier, but only counted votes set the inclusion slot
+	if got := collected.inclusion[key]; got != 330 {
+		t.Errorf("expected inclusion slot 330, got %v", got)
+	}
+
+	// Target roots are compared regardless of case, but a root without its 0x prefix is not the same root
+	collected.add(383, vote("0x18", "10", strings.ToUpper(fixtureTargetRoot[2:])), strings.ToLower(fixtureTargetRoot))
+	if got := collected.merged[key].Hex(); got != "0x17" {
+		t.Errorf("expected a root without its prefix to be ignored, got %s", got)
+	}
+	collected.add(383, vote("0x18", "10", "0x"+strings.ToUpper(fixtureTargetRoot[2:])), fixtureTargetRoot)
+	participation, committees := collected.summarise()
+	if participation.Participating != 4 || participation.Expected != 6 || len(committees) != 2 ||
+		committees[0].Participants != 4 || committees[0].AggregationBits != "0x1f" || committees[1].Participants != 0 {
+		t.Errorf("unexpected summary %+v %+v", participation, committees)
+	}
+}
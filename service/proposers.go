@@ -0,0 +1,89 @@
+package service
+
+import (
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"os"
+	"strconv"
+)
+
+/*
+This method cross checks the stored proposer of every indexed slot of the epoch against the proposer the
+spec selects. Proposers are first computed with the effective balances of the cached finalized registry,
+which only differ from the epoch's own balances for a handful of validators, so any slot that disagrees is
+recomputed with the registry of the state the spec takes the balances from before it is reported
+*/
+func (s *Service) VerifyProposers(epoch int64) ([]*IntegrityError, error) {
+	stored, err := s.db.GetProposers(epoch)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	fork, err := s.forkAtEpoch(epoch)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := s.validatorRegistryFor(epoch)
+	if err != nil {
+		return nil, err
+	}
+	mismatches, err := s.compareProposers(epoch, fork, registry, registry.slot, stored)
+	if err != nil || len(mismatches) == 0 {
+		return nil, err
+	}
+
+	// Before fulu the proposers of an epoch are drawn with the balances of that epoch, from fulu on they
+	// are fixed in the proposer lookahead one epoch ahead, with the balances of the epoch before
+	slotsPerEpoch, _ := strconv.ParseInt(os.Getenv("SLOTS_PER_EPOCH"), 10, 64)
+	balanceEpoch := epoch
+	if fork.AtLeast(model.Fulu) && epoch > 0 {
+		balanceEpoch = epoch - 1
+	}
+	exact, err := s.fetchValidatorRegistry(balanceEpoch * slotsPerEpoch)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	recheck := make(map[int64]string, len(mismatches))
+	for _, mismatch := range mismatches {
+		recheck[mismatch.Slot] = mismatch.Stored
+	}
+	// The randao mix still comes from the finalized state, the balance state may be too old to hold it
+	mismatches, err = s.compareProposers(epoch, fork, exact, registry.slot, recheck)
+	if err != nil {
+		return nil, err
+	}
+	for _, mismatch := range mismatches {
+		logger.LogError(mismatch)
+	}
+	return mismatches, nil
+}
+
+func (s *Service) compareProposers(epoch int64, fork model.ForkVersion, registry *validatorRegistry, mixSlot int64, stored map[int64]string) ([]*IntegrityError, error) {
+	mix, err := s.fetchRandaoMix(mixSlot, spec.SeedEpoch(uint64(epoch)))
+	if err != nil {
+		return nil, err
+	}
+	seed := spec.GetSeed(mix, uint64(epoch), spec.DomainBeaconProposer)
+	proposers, err := spec.ComputeProposers(registry.activeIndices(uint64(epoch)), registry.effectiveBalances(), seed, uint64(epoch), fork.AtLeast(model.Electra))
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	var mismatches []*IntegrityError
+	for i, proposer := range proposers {
+		slot := int64(uint64(epoch)*spec.SlotsPerEpoch) + int64(i)
+		storedIndex, ok := stored[slot]
+		if !ok {
+			continue
+		}
+		expected := strconv.FormatUint(proposer, 10)
+		if storedIndex != expected {
+			mismatches = append(mismatches, &IntegrityError{Slot: slot, Field: "proposer_index", Expected: expected, Stored: storedIndex})
+		}
+	}
+	return mismatches, nil
+}
@@ -4,16 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"go-beacon-chain-indexer/db"
 	"go-beacon-chain-indexer/logger"
 	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
 	"io"
 	"log"
-	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,29 +24,61 @@ const (
 )
 
 type Service struct {
-	db     *db.Database
-	client *http.Client
+	db db.Storage
+	// The API reads stored participation from here, the read replica when one is configured
+	reads  db.Storage
+	client *BeaconClient
+	cache  *ResponseCache
+	useSSZ bool
+	// Shared by every fan out of upstream requests so they stay within the provider's rate limit
+	limiter <-chan time.Time
+	// Latest finalized slot seen, anything at or below it is immutable and safe to cache
+	finalizedSlot int64
+	// Committees are computed locally from this registry unless LOCAL_SHUFFLING is turned off
+	localShuffling bool
+	registryMutex  sync.Mutex
+	registry       *validatorRegistry
+	forksMutex     sync.Mutex
+	forks          []ForkScheduleEntry
+	trustedMutex   sync.Mutex
+	trusted        *trustedRegistry
 }
 
-type Counter struct {
-	value int
-	mutex sync.Mutex
+func NewService(storage db.Storage) (*Service, error) {
+	client, err := NewBeaconClient()
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return &Service{
+		db:             storage,
+		reads:          storage,
+		client:         client,
+		cache:          NewResponseCache(getEnvInt("RESPONSE_CACHE_MAX_BYTES", 256<<20), os.Getenv("RESPONSE_CACHE_DIR")),
+		useSSZ:         getEnvBool("USE_SSZ", true),
+		localShuffling: getEnvBool("LOCAL_SHUFFLING", true),
+		limiter:        time.Tick(time.Second / time.Duration(getEnvInt("UPSTREAM_REQUESTS_PER_SECOND", 24))),
+	}, nil
 }
 
-func NewCounter() *Counter {
-	return &Counter{}
+/*
+This method makes the reads of the API go to storage, usually the primary wrapped with its read replica by
+db.WithReadReplica. Writes keep going to the storage the service was created with. It must be called before
+the service is started
+*/
+func (s *Service) ReadFrom(storage db.Storage) {
+	s.reads = storage
 }
 
-func NewService(pool *pgxpool.Pool) *Service {
-	return &Service{
-		db: db.NewDatabase(pool),
-		client: &http.Client{
-			Transport: &http.Transport{
-				MaxIdleConns:        10,               // Set the maximum number of idle connections in the pool
-				IdleConnTimeout:     30 * time.Second, // Set the maximum idle connection timeout
-				MaxIdleConnsPerHost: 10,               // Set the maximum number of idle connections per host
-			},
-		},
+/*
+This method runs the first health check against every configured beacon node and keeps checking them
+in the background so requests are only routed to healthy, synced nodes
+*/
+func (s *Service) StartHealthChecks() {
+	interval := time.Duration(getEnvInt("NODE_HEALTH_CHECK_INTERVAL_SECONDS", 15)) * time.Second
+	s.client.Nodes().StartHealthChecks(interval)
+	for _, node := range s.client.Nodes().Nodes() {
+		logger.LogInfo("Beacon node status", node.Status())
 	}
 }
 
@@ -55,103 +89,317 @@ func (s *Service) Run() {
 	err := indexEpochData(s)
 	if err != nil {
 		logger.LogError(errors.New("Error encountered while indexing epoch data from quicnode api"))
+		return
+	}
+	s.verifyIndexedProposers()
+	s.indexParticipation()
+}
+
+/*
+This method cross checks the proposer of every indexed slot against the spec, mismatches are logged as integrity errors
+*/
+func (s *Service) verifyIndexedProposers() {
+	latestSlot := atomic.LoadInt64(&s.finalizedSlot)
+	mismatches := 0
+	for epoch := getEpochNumber(getStartingSlotNumber(latestSlot)); epoch <= getEpochNumber(latestSlot); epoch++ {
+		integrityErrors, err := s.VerifyProposers(epoch)
+		if err != nil {
+			logger.LogError(fmt.Errorf("failed to verify proposers of epoch %v: %w", epoch, err))
+			continue
+		}
+		mismatches += len(integrityErrors)
+	}
+	logger.LogInfo("Proposer verification found", mismatches, "integrity errors")
+}
+
+/*
+This method computes and stores the participation of every indexed epoch whose inclusion window is finalized
+*/
+func (s *Service) indexParticipation() {
+	latestSlot := atomic.LoadInt64(&s.finalizedSlot)
+	startEpoch := getEpochNumber(getStartingSlotNumber(latestSlot))
+	for epoch := startEpoch; epoch <= getEpochNumber(latestSlot); epoch++ {
+		_, inclusionEnd := inclusionWindow(epoch)
+		if !s.isFinalizedSlot(inclusionEnd) {
+			continue
+		}
+		participation, err := s.GetEpochParticipation(epoch)
+		if err != nil {
+			logger.LogError(fmt.Errorf("failed to index participation of epoch %v: %w", epoch, err))
+			continue
+		}
+		logger.LogInfo("Participation of epoch", epoch, "is", participation.ParticipationRate)
 	}
 }
 
+func (s *Service) rateLimit() {
+	<-s.limiter
+}
+
+/*
+This function indexes the headers up to the finalized slot, resuming after the epoch of the indexing cursor or,
+on a first run, from the last EPOCH_COUNT epochs. The slots of an epoch are fetched concurrently and written
+together once all of them have been fetched. An epoch with a slot that could not be fetched is not written at
+all and indexing stops there, so the next run resumes with it. The epoch of the finalized slot is written in part and does not move the cursor, so it is fetched again
+by the next run. Writes are upserts, so epochs indexed before are written again without being cleared first
+*/
 func indexEpochData(s *Service) error {
-	err := s.db.DeleteData()
+	latestSlot, err := s.fetchLatestSlot()
+	if err != nil {
+		logger.LogError(err)
+		return fmt.Errorf("failed to fetch latest slot: %v", err)
+	}
+	cursor, err := s.db.GetIndexingCursor()
 	if err != nil {
 		logger.LogError(err)
 		return err
 	}
-	latestSlot, err := s.fetchLatestSlot()
+
+	startingSlot := getStartingSlotNumber(latestSlot)
+	if cursor >= 0 {
+		startingSlot, _ = s.GetSlotRange(cursor + 1)
+	}
+	for epoch := getEpochNumber(startingSlot); epoch <= getEpochNumber(latestSlot); epoch++ {
+		fromSlot, toSlot := s.GetSlotRange(epoch)
+		if fromSlot < startingSlot {
+			fromSlot = startingSlot
+		}
+		if toSlot > latestSlot {
+			toSlot = latestSlot
+		}
+		fetched := s.fetchHeaders(slotRange(fromSlot, toSlot))
+		if len(fetched.failed) > 0 {
+			// Later epochs would not move the cursor past this one, the next run starts here again
+			logger.LogError(fmt.Errorf("indexing stopped at epoch %v, slots %v could not be fetched", epoch, fetched.failed))
+			break
+		}
+		counts, err := s.db.InsertEpochData(epoch, fetched.rows, fetched.missed)
+		if err != nil {
+			logger.LogError(err)
+			return err
+		}
+		logger.LogInfo("Header rows of epoch", epoch, "inserted", counts.Inserted, "updated", counts.Updated, "unchanged", counts.Unchanged)
+	}
+	cursor, err = s.db.GetIndexingCursor()
 	if err != nil {
 		logger.LogError(err)
-		return fmt.Errorf("failed to fetch latest slot: %v", err)
+		return err
 	}
+	log.Println("Data insertion completed up to epoch", cursor)
+	return nil
+}
+
+// fetchedSlots sorts fetched slots into header rows, slots missed by their proposer and slots that failed
+type fetchedSlots struct {
+	rows   []model.BeaconChainData
+	missed []int64
+	failed []int64
+}
 
-	genesisSlotUnixTimestamp := int64(GenesisUnixTime) // Replace with the actual genesis slot Unix timestamp
-	//latestEpoch := getEpochNumber(latestSlot)
-	startingSlot := latestSlot
-	endSlot := getStartingSlotNumber(latestSlot)
+/*
+This method fetches the headers of the slots concurrently as rows for beacon_chain_data, in slot order. A slot
+the nodes answer 404 for is only returned as missed once that is confirmed, by two nodes answering 404 or by
+the chain itself, see confirmMissedSlots. Unconfirmed slots are returned as failed and fetched again later
+*/
+func (s *Service) fetchHeaders(slots []int64) fetchedSlots {
+	timePerSlot, _ := strconv.ParseInt(os.Getenv("SECONDS_PER_SLOT"), 10, 64)
+	headers := make([]*model.BeaconChainData, len(slots))
+	errs := make([]error, len(slots))
 	var wg sync.WaitGroup
-	rateLimiter := time.Tick(time.Second / 24)
-	for slot := startingSlot; slot >= endSlot; slot-- {
+	for i, slot := range slots {
 		wg.Add(1)
-		go func(slot int64) {
+		go func(i int, slot int64) {
 			defer wg.Done()
-			timePerSlot, _ := strconv.ParseInt(os.Getenv("SECONDS_PER_SLOT"), 10, 64)
-			slotTime := genesisSlotUnixTimestamp + slot*timePerSlot // Assuming slot duration is 384 seconds
-			<-rateLimiter
-			beaconData, err := s.fetchBeaconData(slot)
-			if err != nil || beaconData == nil {
-				return
-			}
-			epoch := getEpochNumber(slot)
-
-			err = s.db.InsertData(epoch, slot, slotTime, beaconData)
+			s.rateLimit()
+			beaconData, err := s.fetchHeader(slot)
 			if err != nil {
-				logger.LogError(err)
+				errs[i] = err
+				if !errors.Is(err, ErrNotFound) {
+					logger.LogError(fmt.Errorf("giving up on slot %v: %w", slot, err))
+				}
 				return
 			}
-		}(slot)
+			beaconData.Epoch = getEpochNumber(slot)
+			beaconData.Data.UnixTime = GenesisUnixTime + slot*timePerSlot
+			beaconData.Data.Header.Message.Slot = strconv.FormatInt(slot, 10)
+			headers[i] = beaconData
+		}(i, slot)
 	}
 	wg.Wait()
-	log.Println("Data insertion completed!")
-	return nil
+	var fetched fetchedSlots
+	var notFound []int64
+	known := make(map[int64]chainSlot, len(slots))
+	for i, header := range headers {
+		switch {
+		case notFoundNodes(errs[i]) >= 2:
+			fetched.missed = append(fetched.missed, slots[i])
+			known[slots[i]] = chainSlot{}
+		case errors.Is(errs[i], ErrNotFound):
+			notFound = append(notFound, slots[i])
+			known[slots[i]] = chainSlot{}
+		case errs[i] != nil:
+			fetched.failed = append(fetched.failed, slots[i])
+			known[slots[i]] = chainSlot{failed: true}
+		default:
+			fetched.rows = append(fetched.rows, *header)
+			known[slots[i]] = chainSlot{row: header}
+		}
+	}
+	confirmed := s.confirmMissedSlots(notFound, known)
+	for _, slot := range notFound {
+		if confirmed[slot] {
+			fetched.missed = append(fetched.missed, slot)
+		} else {
+			logger.LogError(fmt.Errorf("slot %v was not found on one node and is not confirmed missed by the chain, it is fetched again later", slot))
+			fetched.failed = append(fetched.failed, slot)
+		}
+	}
+	sort.Slice(fetched.missed, func(i, j int) bool { return fetched.missed[i] < fetched.missed[j] })
+	sort.Slice(fetched.failed, func(i, j int) bool { return fetched.failed[i] < fetched.failed[j] })
+	return fetched
+}
+
+// chainSlot is what is known of a slot: its block, no block, or that it could not be fetched
+type chainSlot struct {
+	row    *model.BeaconChainData
+	failed bool
 }
 
 /*
-This method fetches the latest finalized slot number
+This method confirms slots that a single node answered 404 for from the chain itself. A slot was missed when
+the first block after it has the last canonical block before it as parent, which holds for every slot in
+between. Blocks are looked for up to an epoch away, in the slots fetched with it, the stored rows and missed
+slots, and else by fetching the slot, never past the finalized slot. A slot stored as missed that did have a
+block breaks the link, so it can not confirm a slot by mistake
 */
-func (s *Service) fetchLatestSlot() (int64, error) {
-	url := "https://wiser-side-morning.discover.quiknode.pro/eth/v1/beacon/headers/finalized" // Replace with the actual endpoint
-
-	response, err := s.client.Get(url)
+func (s *Service) confirmMissedSlots(slots []int64, known map[int64]chainSlot) map[int64]bool {
+	confirmed := make(map[int64]bool)
+	if len(slots) == 0 {
+		return confirmed
+	}
+	sorted := append([]int64(nil), slots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	fromSlot, toSlot := sorted[0]-spec.SlotsPerEpoch, sorted[len(sorted)-1]+spec.SlotsPerEpoch
+	if fromSlot < 0 {
+		fromSlot = 0
+	}
+	stored, err := s.db.GetIndexedData(fromSlot, toSlot)
 	if err != nil {
 		logger.LogError(err)
-		return 0, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+	for i := range stored {
+		slot, _ := strconv.ParseInt(stored[i].Data.Header.Message.Slot, 10, 64)
+		if _, ok := known[slot]; !ok {
+			known[slot] = chainSlot{row: &stored[i]}
+		}
+	}
+	missed, err := s.db.GetMissedSlots(fromSlot, toSlot)
+	if err != nil {
+		logger.LogError(err)
+	}
+	for _, slot := range missed {
+		if _, ok := known[slot]; !ok {
+			known[slot] = chainSlot{}
+		}
+	}
+
+	finalizedSlot := atomic.LoadInt64(&s.finalizedSlot)
+	resolve := func(slot int64) chainSlot {
+		if known, ok := known[slot]; ok {
+			return known
+		}
+		if finalizedSlot > 0 && slot > finalizedSlot {
+			return chainSlot{failed: true}
+		}
+		s.rateLimit()
+		header, err := s.fetchHeader(slot)
+		resolved := chainSlot{row: header}
 		if err != nil {
-			logger.LogError(err)
+			resolved = chainSlot{failed: !errors.Is(err, ErrNotFound)}
+		}
+		known[slot] = resolved
+		return resolved
+	}
+	for _, slot := range sorted {
+		var previous, next *model.BeaconChainData
+		for before := slot - 1; before >= 0 && before >= slot-spec.SlotsPerEpoch; before-- {
+			resolved := resolve(before)
+			if resolved.failed || (resolved.row != nil && resolved.row.Data.Canonical) {
+				previous = resolved.row
+				break
+			}
 		}
-	}(response.Body)
+		for after := slot + 1; after <= slot+spec.SlotsPerEpoch; after++ {
+			resolved := resolve(after)
+			if resolved.failed || (resolved.row != nil && resolved.row.Data.Canonical) {
+				next = resolved.row
+				break
+			}
+		}
+		confirmed[slot] = previous != nil && next != nil && strings.EqualFold(next.Data.Header.Message.ParentRoot, previous.Data.Root)
+	}
+	return confirmed
+}
+
+func slotRange(fromSlot int64, toSlot int64) []int64 {
+	slots := make([]int64, 0, toSlot-fromSlot+1)
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		slots = append(slots, slot)
+	}
+	return slots
+}
 
+/*
+This method fetches the latest finalized slot number
+*/
+func (s *Service) fetchLatestSlot() (int64, error) {
 	var beaconData model.BeaconChainData
-	err = json.NewDecoder(response.Body).Decode(&beaconData)
+	err := s.client.GetJSON("/eth/v1/beacon/headers/finalized", &beaconData)
+	if err != nil {
+		logger.LogError(err)
+		return 0, err
+	}
+	slotNumber, err := strconv.ParseInt(beaconData.Data.Header.Message.Slot, 10, 64)
 	if err != nil {
 		logger.LogError(err)
 		return 0, err
 	}
-	slotNumber, _ := strconv.ParseInt(beaconData.Data.Header.Message.Slot, 10, 64)
+	atomic.StoreInt64(&s.finalizedSlot, slotNumber)
 	return slotNumber, nil
 }
 
 /*
-This method fetches the header data for a specific slot
+This method fetches the header data for a specific slot. A missed slot is returned as nil data
+without an error, anything that could not be fetched or decoded is returned as an error
 */
 func (s *Service) fetchBeaconData(slotNumber int64) (*model.BeaconChainData, error) {
-	url := fmt.Sprintf("https://wiser-side-morning.discover.quiknode.pro/eth/v1/beacon/headers/%v", slotNumber)
-	response, err := s.client.Get(url)
+	beaconData, err := s.fetchHeader(slotNumber)
+	if errors.Is(err, ErrNotFound) {
+		logger.LogInfo("Slot number ", slotNumber, " was missed")
+		return nil, nil
+	}
+	return beaconData, err
+}
+
+/*
+This method fetches the header data for a specific slot. A slot without a block fails with a NotFoundError,
+which tells how many nodes answered 404
+*/
+func (s *Service) fetchHeader(slotNumber int64) (*model.BeaconChainData, error) {
+	var beaconData model.BeaconChainData
+	err := s.fetchJSON(fmt.Sprintf("/eth/v1/beacon/headers/%v", slotNumber), s.isFinalizedSlot(slotNumber), &beaconData)
+	if errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
 	if err != nil {
 		logger.LogError(err)
 		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			logger.LogError(err)
-		}
-	}(response.Body)
-	if response.StatusCode == 404 {
-		logger.LogInfo("Slot number ", slotNumber, " was missed")
-		return nil, nil
+	if beaconData.Data.Root == "" || beaconData.Data.Header.Message.Slot == "" {
+		err = fmt.Errorf("header response for slot %v is missing the root or slot", slotNumber)
+		logger.LogError(err)
+		return nil, err
 	}
-	var beaconData model.BeaconChainData
-	err = json.NewDecoder(response.Body).Decode(&beaconData)
-
 	return &beaconData, nil
 }
 
@@ -168,210 +416,77 @@ func (s *Service) FetchLatestEpochNumber() (int64, error) {
 }
 
 /*
-This method fetches the no of validators in a specific validator set
-*/
-func (s *Service) FetchTotalNumberOfValidators(epoch int64) map[string]int {
-	logger.LogInfo("Fetching committees for epoch %v", epoch)
-	indexToValidators := make(map[string]int)
-	response, err := s.client.Get(fmt.Sprintf("https://wiser-side-morning.discover.quiknode.pro/eth/v1/beacon/states/finalized/committees?epoch=%v", epoch))
-	if err != nil {
-		logger.LogError(err)
-		return nil
-	}
-	defer response.Body.Close()
-	dec := json.NewDecoder(response.Body)
-	dec.UseNumber()
-	committeeData := make(map[string]interface{})
-	err = dec.Decode(&committeeData)
-	if err != nil {
-		logger.LogError(err)
-		return nil
-	}
-	data, ok := committeeData["data"].([]interface{})
-	if !ok {
-		logger.LogError(errors.New("data field is not an array"))
-		return nil
-	}
-	for _, item := range data {
-		committee, ok := item.(map[string]interface{})
-		if !ok {
-			logger.LogError(errors.New("invalid committee object"))
-			continue
-		}
-		index, ok := committee["index"].(string)
-		if !ok {
-			logger.LogError(errors.New("invalid index value"))
-			continue
-		}
-		validators, ok := committee["validators"].([]interface{})
-		if !ok {
-			logger.LogError(errors.New("invalid validators value"))
-			continue
-		}
-		indexToValidators[index] = len(validators)
-	}
-	return indexToValidators
-}
-
-/*
-This method fetches the committee and the position of the validator in a specific epoch
+This method counts the active_ongoing validators. Validators are decoded one at a time from the
+response stream and only their status is kept, so memory stays flat however large the set grows
 */
-func (s *Service) FetchValidatorInfo(epoch int64, validatorIndex string) (string, int) {
-	logger.LogInfo("Fetching committee info for validatorIndex %v in epoch %v", validatorIndex, epoch)
-	response, err := http.Get(fmt.Sprintf("https://wiser-side-morning.discover.quiknode.pro/eth/v1/beacon/states/finalized/committees?epoch=%v", epoch)) // Replace with the actual API endpoint
-	if err != nil {
-		logger.LogError(err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			logger.LogError(err)
+func (s *Service) FetchValidatorSetSize() (int, error) {
+	if s.useSSZ {
+		count, err := s.fetchValidatorSetSizeSSZ()
+		if err == nil {
+			return count, nil
 		}
-	}(response.Body)
-	dec := json.NewDecoder(response.Body)
-	var committeeData struct {
-		Data []model.Committee `json:"data"`
-	}
-
-	err = dec.Decode(&committeeData)
-	if err != nil {
-		logger.LogError(err)
-
-	}
-	committeeIndex := ""
-	committeePosition := 0
-	for _, committee := range committeeData.Data {
-		for i, validator := range committee.Validators {
-			if validator == validatorIndex {
-				committeeIndex = committee.Index
-				committeePosition = i
-			}
+		if !errors.Is(err, ErrSSZUnsupported) {
+			logger.LogError(fmt.Errorf("falling back to json for the validator set size: %w", err))
 		}
 	}
-	return committeeIndex, committeePosition
-}
-
-/*
-This method fetches the aggregation_bits string based on each index in a slot
-*/
-func (s *Service) FetchAggregationBits(startingSlot int64) map[string]string {
-	logger.LogInfo("Fetching attestation bits for %v", startingSlot)
-	indexToAggregationBits := sync.Map{} // Thread-safe map
-	slotPerEpoch, _ := strconv.ParseInt(os.Getenv("SLOTS_PER_EPOCH"), 10, 64)
-	endSlot := startingSlot + slotPerEpoch
-	rateLimiter := time.Tick(time.Second / 25)
-	var wg sync.WaitGroup
-	for slot := startingSlot; slot < endSlot; slot++ {
-		<-rateLimiter
-		wg.Add(1)
-		go func(slot int64) {
-			defer wg.Done()
-			response, err := s.client.Get(fmt.Sprintf("https://wiser-side-morning.discover.quiknode.pro/eth/v1/beacon/blocks/%v/attestations", slot)) // Replace with the actual API endpoint
-			if err != nil {
-				logger.LogError(err)
-				return
+	count := 0
+	err := s.client.Stream("/eth/v1/beacon/states/head/validators?status=active_ongoing", "application/json", func(body io.Reader, _ string) error {
+		count = 0
+		return decodeDataArray(body, func(dec *json.Decoder) error {
+			var validator struct {
+				Status string `json:"status"`
 			}
-			defer func(Body io.ReadCloser) {
-				err := Body.Close()
-				if err != nil {
-					logger.LogError(err)
-				}
-			}(response.Body)
-
-			if response.StatusCode == 404 {
-				logger.LogInfo("Slot number ", slot, " was missed")
-				return
-			}
-
-			dec := json.NewDecoder(response.Body)
-			dec.UseNumber()
-
-			var attestationData struct {
-				Data []model.Attestation `json:"data"`
-			}
-
-			err = dec.Decode(&attestationData)
+			err := dec.Decode(&validator)
 			if err != nil {
-				logger.LogError(err)
-				return
-			}
-
-			for _, attestation := range attestationData.Data {
-				indexToAggregationBits.Store(attestation.Details.Index, attestation.AggregationBits)
+				return err
 			}
-		}(slot)
-	}
-
-	go func() {
-		wg.Wait()
-	}()
-
-	result := make(map[string]string)
-	indexToAggregationBits.Range(func(key, value interface{}) bool {
-		result[key.(string)] = value.(string)
-		return true
+			count++
+			return nil
+		})
 	})
-	return result
-}
-
-func (s *Service) FetchValidatorSetSize() (int, error) {
-	url := "https://wiser-side-morning.discover.quiknode.pro/eth/v1/beacon/states/head/validators?status=active_ongoing" // Replace with the actual API endpoint
-	response, err := http.Get(url)
 	if err != nil {
 		logger.LogError(err)
 		return 0, err
 	}
-	defer response.Body.Close()
-	var wg sync.WaitGroup
-	counter := NewCounter()
-	decoder := json.NewDecoder(response.Body)
-	for {
-		tok, err := decoder.Token()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			logger.LogError(err)
-			return 0, err
-		}
+	return count, nil
+}
 
-		if tok == "data" {
-			break
+/*
+This method returns the response body for the path, serving it from the response cache when the
+requested data is finalized. Only bodies that decode as valid JSON are stored
+*/
+func (s *Service) fetchBody(path string, finalized bool) ([]byte, error) {
+	if finalized {
+		if body, ok := s.cache.Get(path); ok {
+			return body, nil
 		}
 	}
-	var data []interface{}
-	err = decoder.Decode(&data)
+	body, err := s.client.GetBytes(path)
 	if err != nil {
-		logger.LogError(err)
-		return 0, err
+		return nil, err
 	}
-	for _, obj := range data {
-		wg.Add(1)
-		go func(obj interface{}) {
-			defer wg.Done()
+	if finalized && json.Valid(body) {
+		s.cache.Put(path, body)
+	}
+	return body, nil
+}
 
-			_, ok := obj.(map[string]interface{})
-			if !ok {
-				return
-			}
-			counter.Increment()
-		}(obj)
+func (s *Service) fetchJSON(path string, finalized bool, v any) error {
+	body, err := s.fetchBody(path, finalized)
+	if err != nil {
+		return err
 	}
-	wg.Wait()
-	return counter.Value(), nil
+	return json.Unmarshal(body, v)
 }
 
-// Increment increments the counter by 1
-func (c *Counter) Increment() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.value++
+func (s *Service) isFinalizedSlot(slot int64) bool {
+	finalizedSlot := atomic.LoadInt64(&s.finalizedSlot)
+	return finalizedSlot > 0 && slot <= finalizedSlot
 }
 
-// Value returns the current value of the counter
-func (c *Counter) Value() int {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	return c.value
+func (s *Service) isFinalizedEpoch(epoch int64) bool {
+	finalizedSlot := atomic.LoadInt64(&s.finalizedSlot)
+	return finalizedSlot > 0 && epoch <= getEpochNumber(finalizedSlot)
 }
 
 /*
@@ -383,6 +498,12 @@ func getEpochNumber(slotNumber int64) int64 {
 	return epochNumber
 }
 
+// epochUnixTime returns the time of the first slot of an epoch
+func epochUnixTime(epoch int64) int64 {
+	secondsPerSlot, _ := strconv.ParseInt(os.Getenv("SECONDS_PER_SLOT"), 10, 64)
+	return GenesisUnixTime + epoch*spec.SlotsPerEpoch*secondsPerSlot
+}
+
 /*
 This method determines the starting slot number in in that epoch from the supplied slot number
 */
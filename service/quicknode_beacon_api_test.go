@@ -0,0 +1,250 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-beacon-chain-indexer/db"
+	"go-beacon-chain-indexer/model"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+headersNode serves the finalized header at the given slot and a header for every even slot up to it, odd
+slots are missed. The slot failing holds, when there is one, is answered with a 500. It records the slots whose
+header was asked for
+*/
+func headersNode(t *testing.T, finalizedSlot int64, failing *int64) (*httptest.Server, func() []int64) {
+	t.Helper()
+	var mutex sync.Mutex
+	var requested []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/eth/v1/beacon/headers/")
+		slot, err := strconv.ParseInt(id, 10, 64)
+		if id == "finalized" {
+			slot = finalizedSlot
+		} else if err != nil || slot > finalizedSlot {
+			http.NotFound(w, r)
+			return
+		} else {
+			mutex.Lock()
+			requested = append(requested, slot)
+			mutex.Unlock()
+		}
+		if failing != nil && atomic.LoadInt64(failing) == slot {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if slot%2 == 1 {
+			http.NotFound(w, r)
+			return
+		}
+		var header model.BeaconChainData
+		header.Data.Root = fmt.Sprintf("0x%064x", slot)
+		header.Data.Canonical = true
+		header.Data.Header.Message = model.MessageData{Slot: strconv.FormatInt(slot, 10), ProposerIndex: "1",
+			ParentRoot: fmt.Sprintf("0x%064x", slot-2), StateRoot: fmt.Sprintf("0x%064x", 0), BodyRoot: fmt.Sprintf("0x%064x", 0)}
+		header.Data.Header.Signature = "0x" + strings.Repeat("00", 96)
+		json.NewEncoder(w).Encode(header)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []int64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+		first := requested
+		requested = nil
+		return first
+	}
+}
+
+func TestIndexEpochDataResumesAfterTheCursor(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	t.Setenv("EPOCH_COUNT", "1")
+	storage := db.NewMemory()
+	var missed []int64
+	for slot := int64(320); slot < 352; slot++ {
+		missed = append(missed, slot)
+	}
+	if _, err := storage.InsertEpochData(10, nil, missed); err != nil {
+		t.Fatal(err)
+	}
+
+	// The finalized slot is in the middle of epoch 12
+	server, requested := headersNode(t, 400, nil)
+	limiter := make(chan time.Time)
+	close(limiter)
+	s := &Service{db: storage, client: newTestClient(t, 1, server.URL), cache: NewResponseCache(1<<20, ""), limiter: limiter}
+	lowest := func(slots []int64) int64 {
+		lowest := slots[0]
+		for _, slot := range slots {
+			if slot < lowest {
+				lowest = slot
+			}
+		}
+		return lowest
+	}
+
+	if err := indexEpochData(s); err != nil {
+		t.Fatal(err)
+	}
+	// EPOCH_COUNT alone would start at epoch 12
+	if slots := requested(); len(slots) != 400-352+1 || lowest(slots) != 352 {
+		t.Errorf("expected slots 352 to 400 to be fetched after the cursor, got %v", slots)
+	}
+	if cursor, _ := storage.GetIndexingCursor(); cursor != 11 {
+		t.Errorf("expected the cursor at the last complete epoch 11, got %v", cursor)
+	}
+	if rows, _ := storage.GetIndexedData(384, 400); len(rows) != 9 {
+		t.Errorf("expected the finalized part of epoch 12 to be written, got %v rows", len(rows))
+	}
+
+	// The next run only fetches the partly written epoch again, the headers it got are cached by now
+	if err := indexEpochData(s); err != nil {
+		t.Fatal(err)
+	}
+	if slots := requested(); len(slots) == 0 || lowest(slots) < 384 {
+		t.Errorf("expected only slots of epoch 12 to be fetched again, got %v", slots)
+	}
+}
+
+func TestIndexEpochDataStopsAtAFailedEpoch(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	t.Setenv("EPOCH_COUNT", "1")
+	storage := db.NewMemory()
+	failing := int64(360)
+	server, requested := headersNode(t, 440, &failing)
+	limiter := make(chan time.Time)
+	close(limiter)
+	s := &Service{db: storage, client: newTestClient(t, 1, server.URL), cache: NewResponseCache(1<<20, ""), limiter: limiter}
+
+	// Epoch 10 is indexed and epoch 12 complete already, the way a repair leaves it
+	for _, epoch := range []int64{10, 12} {
+		fromSlot, toSlot := s.GetSlotRange(epoch)
+		fetched := s.fetchHeaders(slotRange(fromSlot, toSlot))
+		if _, err := storage.InsertEpochData(epoch, fetched.rows, fetched.missed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cursor, _ := storage.GetIndexingCursor(); cursor != 10 {
+		t.Fatalf("expected the cursor at epoch 10, got %v", cursor)
+	}
+	requested()
+
+	if err := indexEpochData(s); err != nil {
+		t.Fatal(err)
+	}
+	for _, slot := range requested() {
+		if slot > 383 {
+			t.Fatalf("expected indexing to stop at the failed epoch 11, slot %v was fetched", slot)
+		}
+	}
+	if cursor, _ := storage.GetIndexingCursor(); cursor != 10 {
+		t.Errorf("expected the cursor to stay before the failed epoch, got %v", cursor)
+	}
+
+	// The next run starts with the failed epoch again and moves the cursor over the complete epochs after it
+	atomic.StoreInt64(&failing, -1)
+	if err := indexEpochData(s); err != nil {
+		t.Fatal(err)
+	}
+	fetchedAgain := false
+	for _, slot := range requested() {
+		fetchedAgain = fetchedAgain || slot == 360
+	}
+	if !fetchedAgain {
+		t.Error("expected the failed slot to be fetched again")
+	}
+	if cursor, _ := storage.GetIndexingCursor(); cursor != 12 {
+		t.Errorf("expected the cursor at epoch 12, got %v", cursor)
+	}
+}
+
+/*
+chainNode serves a header for every slot of blocks, whose value is the slot of its parent, and 404 for any
+other slot
+*/
+func chainNode(t *testing.T, blocks map[int64]int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slot, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/eth/v1/beacon/headers/"), 10, 64)
+		parent, ok := blocks[slot]
+		if err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var header model.BeaconChainData
+		header.Data.Root = fmt.Sprintf("0x%064x", slot)
+		header.Data.Canonical = true
+		header.Data.Header.Message = model.MessageData{Slot: strconv.FormatInt(slot, 10), ProposerIndex: "1",
+			ParentRoot: fmt.Sprintf("0x%064x", parent), StateRoot: fmt.Sprintf("0x%064x", 0), BodyRoot: fmt.Sprintf("0x%064x", 0)}
+		header.Data.Header.Signature = "0x" + strings.Repeat("00", 96)
+		json.NewEncoder(w).Encode(header)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchHeadersOnlyRecordsConfirmedMissedSlots(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	// Slots 330, 340 and 351 of epoch 10 were missed, slot 351 is the finalized slot
+	chain := map[int64]int64{319: 318}
+	parent := int64(319)
+	for slot := int64(320); slot < 351; slot++ {
+		if slot != 330 && slot != 340 {
+			chain[slot], parent = parent, slot
+		}
+	}
+	// This node does not have the block of slot 335
+	lagging := make(map[int64]int64)
+	for slot, parent := range chain {
+		if slot != 335 {
+			lagging[slot] = parent
+		}
+	}
+	newService := func(urls ...string) *Service {
+		limiter := make(chan time.Time)
+		close(limiter)
+		return &Service{db: db.NewMemory(), client: newTestClient(t, 1, urls...), cache: NewResponseCache(1<<20, ""), limiter: limiter, finalizedSlot: 351}
+	}
+
+	// With one node the chain confirms 330 and 340, the block of 336 has 335 as parent and nothing follows 351 yet
+	s := newService(chainNode(t, lagging).URL)
+	fetched := s.fetchHeaders(slotRange(320, 351))
+	if len(fetched.rows) != 28 || !reflect.DeepEqual(fetched.missed, []int64{330, 340}) || !reflect.DeepEqual(fetched.failed, []int64{335, 351}) {
+		t.Errorf("unexpected rows %v, missed %v and failed %v", len(fetched.rows), fetched.missed, fetched.failed)
+	}
+	// The unconfirmed slots stay missing, so repair fetches them again
+	if _, err := s.db.InsertEpochData(10, fetched.rows, fetched.missed); err != nil {
+		t.Fatal(err)
+	}
+	if coverage, err := s.db.GetSlotCoverage(320, 351); err != nil || !reflect.DeepEqual(coverage.Missing, []int64{335, 351}) {
+		t.Errorf("expected slots 335 and 351 to be left for repair, got %+v: %v", coverage, err)
+	}
+	// Once the next block is finalized it confirms the last slot
+	chain[352] = 350
+	lagging[352] = 350
+	atomic.StoreInt64(&s.finalizedSlot, 352)
+	if fetched = s.fetchHeaders([]int64{335, 351}); !reflect.DeepEqual(fetched.missed, []int64{351}) || !reflect.DeepEqual(fetched.failed, []int64{335}) {
+		t.Errorf("expected repair to confirm slot 351 only, got missed %v and failed %v", fetched.missed, fetched.failed)
+	}
+
+	// A second node has the block of 335, and two nodes answering 404 confirm a slot on their own
+	s = newService(chainNode(t, lagging).URL, chainNode(t, chain).URL)
+	atomic.StoreInt64(&s.finalizedSlot, 351)
+	delete(chain, 352)
+	delete(lagging, 352)
+	fetched = s.fetchHeaders(slotRange(320, 351))
+	if len(fetched.rows) != 29 || !reflect.DeepEqual(fetched.missed, []int64{330, 340, 351}) || len(fetched.failed) != 0 {
+		t.Errorf("unexpected rows %v, missed %v and failed %v", len(fetched.rows), fetched.missed, fetched.failed)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/spec"
+	"sort"
+	"time"
+)
+
+// The slots whose coverage is read at once, a missing slot list never holds more than this
+const repairWindow = spec.SlotsPerHistoricalRoot
+
+// The most slots a report lists as still missing, the count covers the rest
+const maxReportedSlots = 1000
+
+/*
+RepairReport sums up the coverage of a slot range: slots with a header row, slots missed by their proposer and
+the missing slots that have neither. Repaired counts missing slots that were fetched again, still missing counts
+the ones that failed again or, on a dry run, were not fetched at all and lists the first maxReportedSlots of them
+*/
+type RepairReport struct {
+	FromSlot          int64   `json:"from_slot"`
+	ToSlot            int64   `json:"to_slot"`
+	Slots             int64   `json:"slots"`
+	Indexed           int64   `json:"indexed"`
+	Missed            int64   `json:"missed"`
+	Missing           int64   `json:"missing"`
+	Repaired          int64   `json:"repaired"`
+	StillMissingCount int64   `json:"still_missing_count"`
+	StillMissing      []int64 `json:"still_missing"`
+	Completeness      float64 `json:"completeness"`
+}
+
+// stillMissing counts slots that are still missing and lists them while the list is not full
+func (r *RepairReport) stillMissing(slots []int64) {
+	r.StillMissingCount += int64(len(slots))
+	if room := maxReportedSlots - len(r.StillMissing); room < len(slots) {
+		slots = slots[:room]
+	}
+	r.StillMissing = append(r.StillMissing, slots...)
+}
+
+/*
+This method finds the missing slots between the two slots, inclusive, and fetches them again. A negative slot
+defaults to the first or last indexed slot. Repaired slots are written per epoch with the missed slots found on
+the way, and with dryRun the missing slots are only reported
+*/
+func (s *Service) Repair(fromSlot int64, toSlot int64, dryRun bool) (*RepairReport, error) {
+	if fromSlot < 0 || toSlot < 0 {
+		firstIndexed, lastIndexed, err := s.db.GetIndexedSlotRange()
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		if fromSlot < 0 {
+			fromSlot = firstIndexed
+		}
+		if toSlot < 0 {
+			toSlot = lastIndexed
+		}
+	}
+	report := &RepairReport{FromSlot: fromSlot, ToSlot: toSlot, StillMissing: []int64{}}
+	if fromSlot < 0 || toSlot < fromSlot {
+		report.Completeness = 1
+		return report, nil
+	}
+
+	for windowStart := fromSlot; windowStart <= toSlot; windowStart += repairWindow {
+		windowEnd := windowStart + repairWindow - 1
+		if windowEnd > toSlot {
+			windowEnd = toSlot
+		}
+		coverage, err := s.db.GetSlotCoverage(windowStart, windowEnd)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		report.Indexed += coverage.Indexed
+		report.Missed += coverage.Missed
+		report.Missing += int64(len(coverage.Missing))
+		if dryRun {
+			report.stillMissing(coverage.Missing)
+			continue
+		}
+		stillMissing, err := s.repairSlots(coverage.Missing)
+		if err != nil {
+			return nil, err
+		}
+		report.Repaired += int64(len(coverage.Missing) - len(stillMissing))
+		report.stillMissing(stillMissing)
+	}
+	report.Slots = toSlot - fromSlot + 1
+	report.Completeness = float64(report.Slots-report.StillMissingCount) / float64(report.Slots)
+	return report, nil
+}
+
+/*
+This method fetches the missing slots again and writes what was found per epoch. The slots that failed again
+are returned
+*/
+func (s *Service) repairSlots(missing []int64) ([]int64, error) {
+	var stillMissing []int64
+	for _, epoch := range epochsOf(missing) {
+		fetched := s.fetchHeaders(epoch.slots)
+		stillMissing = append(stillMissing, fetched.failed...)
+		if len(fetched.rows) == 0 && len(fetched.missed) == 0 {
+			continue
+		}
+		counts, err := s.db.InsertEpochData(epoch.epoch, fetched.rows, fetched.missed)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		logger.LogInfo("Repaired", len(fetched.rows), "headers and", len(fetched.missed), "missed slots of epoch", epoch.epoch, "inserted", counts.Inserted, "updated", counts.Updated)
+	}
+	return stillMissing, nil
+}
+
+type epochSlots struct {
+	epoch int64
+	slots []int64
+}
+
+// epochsOf groups slots by their epoch, in epoch order
+func epochsOf(slots []int64) []epochSlots {
+	byEpoch := make(map[int64][]int64)
+	for _, slot := range slots {
+		byEpoch[getEpochNumber(slot)] = append(byEpoch[getEpochNumber(slot)], slot)
+	}
+	epochs := make([]epochSlots, 0, len(byEpoch))
+	for epoch, slots := range byEpoch {
+		epochs = append(epochs, epochSlots{epoch: epoch, slots: slots})
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i].epoch < epochs[j].epoch })
+	return epochs
+}
+
+/*
+This method returns the slots of the last epochs of the indexed range, up to the last indexed slot. The range
+never starts before the first indexed slot, so history that was never indexed or imported is not fetched
+*/
+func (s *Service) recentSlotRange(epochs int64) (int64, int64, error) {
+	firstIndexed, lastIndexed, err := s.db.GetIndexedSlotRange()
+	if err != nil {
+		logger.LogError(err)
+		return 0, 0, err
+	}
+	fromSlot := (getEpochNumber(lastIndexed) - epochs + 1) * spec.SlotsPerEpoch
+	if fromSlot < firstIndexed {
+		fromSlot = firstIndexed
+	}
+	return fromSlot, lastIndexed, nil
+}
+
+/*
+This method repairs the last REPAIR_WINDOW_EPOCHS epochs of the indexed range every REPAIR_INTERVAL_MINUTES in
+the background. Older history, like the one of era or snapshot imports, is only repaired by the repair command
+*/
+func (s *Service) StartRepairs() {
+	interval := time.Duration(getEnvInt("REPAIR_INTERVAL_MINUTES", 30)) * time.Minute
+	epochs := getEnvInt("REPAIR_WINDOW_EPOCHS", 225)
+	go func() {
+		for {
+			time.Sleep(interval)
+			fromSlot, toSlot, err := s.recentSlotRange(epochs)
+			if err != nil || toSlot < 0 {
+				continue
+			}
+			report, err := s.Repair(fromSlot, toSlot, false)
+			if err == nil && report.Missing > 0 {
+				logger.LogInfo(fmt.Sprintf("Repaired %v of %v missing slots, %.4f of slots %v to %v are indexed or missed",
+					report.Repaired, report.Missing, report.Completeness, report.FromSlot, report.ToSlot))
+			}
+		}
+	}()
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"go-beacon-chain-indexer/db"
+	"go-beacon-chain-indexer/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEpochsOf(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	epochs := epochsOf([]int64{70, 5, 64, 31, 1000})
+	expected := []epochSlots{{epoch: 0, slots: []int64{5, 31}}, {epoch: 2, slots: []int64{70, 64}}, {epoch: 31, slots: []int64{1000}}}
+	if !reflect.DeepEqual(epochs, expected) {
+		t.Errorf("expected %v, got %v", expected, epochs)
+	}
+	if epochs := epochsOf(nil); len(epochs) != 0 {
+		t.Errorf("expected no epochs, got %v", epochs)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	// Slots 330 and 340 of epochs 10 and 11 were missed
+	chain := map[int64]int64{319: 318}
+	parent := int64(319)
+	for slot := int64(320); slot <= 384; slot++ {
+		if slot != 330 && slot != 340 {
+			chain[slot], parent = parent, slot
+		}
+	}
+	// The node repairs are fetched from does not have the block of slot 335
+	lagging := make(map[int64]int64)
+	for slot, parent := range chain {
+		if slot != 335 {
+			lagging[slot] = parent
+		}
+	}
+	newService := func(storage db.Storage, url string) *Service {
+		limiter := make(chan time.Time)
+		close(limiter)
+		return &Service{db: storage, client: newTestClient(t, 1, url), cache: NewResponseCache(1<<20, ""), limiter: limiter, finalizedSlot: 384}
+	}
+
+	// Slots 325, 335 and 360 failed to be indexed and only 330 is stored as missed
+	storage := db.NewMemory()
+	fetched := newService(db.NewMemory(), chainNode(t, chain).URL).fetchHeaders(slotRange(320, 383))
+	var rows []model.BeaconChainData
+	for _, row := range fetched.rows {
+		if slot := row.Data.Header.Message.Slot; slot != "325" && slot != "335" && slot != "360" {
+			rows = append(rows, row)
+		}
+	}
+	if _, err := storage.InsertEpochData(10, rows, []int64{330}); err != nil {
+		t.Fatal(err)
+	}
+	s := newService(storage, chainNode(t, lagging).URL)
+
+	// A dry run reports the missing slots apart from the missed one and writes nothing
+	report, err := s.Repair(320, 383, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Slots != 64 || report.Indexed != 59 || report.Missed != 1 || report.Missing != 4 || report.Repaired != 0 ||
+		report.StillMissingCount != 4 || !reflect.DeepEqual(report.StillMissing, []int64{325, 335, 340, 360}) || report.Completeness != 60.0/64 {
+		t.Errorf("unexpected dry run %+v", report)
+	}
+	if coverage, _ := storage.GetSlotCoverage(320, 383); !reflect.DeepEqual(coverage.Missing, []int64{325, 335, 340, 360}) {
+		t.Errorf("expected a dry run to write nothing, got %+v", coverage)
+	}
+
+	// Slot 335 fails again since the chain does not confirm it was missed, the others are written per epoch
+	report, err = s.Repair(320, 383, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Missing != 4 || report.Repaired != 3 || report.StillMissingCount != 1 || !reflect.DeepEqual(report.StillMissing, []int64{335}) ||
+		report.Completeness != 63.0/64 {
+		t.Errorf("unexpected repair %+v", report)
+	}
+	if coverage, _ := storage.GetSlotCoverage(320, 383); coverage.Indexed != 61 || coverage.Missed != 2 || !reflect.DeepEqual(coverage.Missing, []int64{335}) {
+		t.Errorf("unexpected coverage after the repair %+v", coverage)
+	}
+	if missed, _ := storage.GetMissedSlots(320, 383); !reflect.DeepEqual(missed, []int64{330, 340}) {
+		t.Errorf("expected slots 330 and 340 missed, got %v", missed)
+	}
+	for _, slot := range []int64{325, 360} {
+		if rows, _ := storage.GetIndexedData(slot, slot); len(rows) != 1 || rows[0].Epoch != getEpochNumber(slot) {
+			t.Errorf("expected the header of slot %v to be written with its epoch, got %+v", slot, rows)
+		}
+	}
+}
+
+func TestRepairCapsStillMissing(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	server, _ := headersNode(t, 2500, nil)
+	limiter := make(chan time.Time)
+	close(limiter)
+	storage := db.NewMemory()
+	s := &Service{db: storage, client: newTestClient(t, 1, server.URL), cache: NewResponseCache(1<<20, ""), limiter: limiter, finalizedSlot: 2500}
+	for _, slot := range []int64{320, 2496} {
+		fetched := s.fetchHeaders([]int64{slot})
+		if _, err := storage.InsertEpochData(getEpochNumber(slot), fetched.rows, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := s.Repair(-1, -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FromSlot != 320 || report.ToSlot != 2496 || report.StillMissingCount != 2175 || len(report.StillMissing) != maxReportedSlots ||
+		report.StillMissing[0] != 321 || report.Completeness != 2.0/2177 {
+		t.Errorf("unexpected report of %v slots still missing, listing %v", report.StillMissingCount, len(report.StillMissing))
+	}
+
+	// The periodic repair only looks at the last epochs, and never before the first indexed slot
+	if fromSlot, toSlot, err := s.recentSlotRange(5); err != nil || fromSlot != 74*32 || toSlot != 2496 {
+		t.Errorf("expected slots %v to 2496, got %v to %v: %v", 74*32, fromSlot, toSlot, err)
+	}
+	if fromSlot, _, _ := s.recentSlotRange(225); fromSlot != 320 {
+		t.Errorf("expected the range to start at the first indexed slot, got %v", fromSlot)
+	}
+}
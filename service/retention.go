@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/db"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/spec"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const secondsPerDay = 86400
+
+/*
+This function reads the retention policy from the environment, relative to now. RETAIN_COMMITTEES_DAYS,
+RETAIN_ATTESTATIONS_DAYS and RETAIN_AGGREGATES_DAYS keep that many days of committee, attestation and
+aggregate rows, 0 or unset keeps them forever. COMPRESS_AFTER_DAYS lists hypertables as table:days
+*/
+func RetentionPolicyFromEnv(now time.Time) (db.RetentionPolicy, error) {
+	policy := db.RetentionPolicy{CompressAfter: make(map[string]int64)}
+	secondsPerEpoch := spec.SlotsPerEpoch * getEnvInt("SECONDS_PER_SLOT", 12)
+	currentEpoch := (now.Unix() - GenesisUnixTime) / secondsPerEpoch
+	beforeEpoch := func(days int64) int64 {
+		if days <= 0 || currentEpoch-days*secondsPerDay/secondsPerEpoch < 0 {
+			return 0
+		}
+		return currentEpoch - days*secondsPerDay/secondsPerEpoch
+	}
+	policy.CommitteesBeforeEpoch = beforeEpoch(getEnvInt("RETAIN_COMMITTEES_DAYS", 0))
+	policy.AttestationsBeforeEpoch = beforeEpoch(getEnvInt("RETAIN_ATTESTATIONS_DAYS", 0))
+	if days := getEnvInt("RETAIN_AGGREGATES_DAYS", 0); days > 0 {
+		policy.AggregatesBefore = now.Unix() - days*secondsPerDay
+	}
+
+	for _, entry := range strings.Split(os.Getenv("COMPRESS_AFTER_DAYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		table, value, ok := strings.Cut(entry, ":")
+		days, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !ok || err != nil || days <= 0 {
+			return policy, fmt.Errorf("COMPRESS_AFTER_DAYS entry %q is not table:days", entry)
+		}
+		policy.CompressAfter[strings.TrimSpace(table)] = days * secondsPerDay
+	}
+	return policy, nil
+}
+
+/*
+This method applies the retention policy of the environment once: compression policies are set and expired
+committee, attestation and aggregate rows deleted
+*/
+func (s *Service) Prune() (*db.RetentionReport, error) {
+	policy, err := RetentionPolicyFromEnv(time.Now())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return s.db.Prune(policy)
+}
+
+/*
+This method prunes now and then every PRUNE_INTERVAL_MINUTES in the background
+*/
+func (s *Service) StartPruning() {
+	interval := time.Duration(getEnvInt("PRUNE_INTERVAL_MINUTES", 60)) * time.Minute
+	go func() {
+		for {
+			report, err := s.Prune()
+			if err == nil {
+				logger.LogInfo("Pruned", report.Committees, "committees,", report.Attestations, "attestations,", report.EpochParticipation, "epoch participation rows and",
+					report.AggregateChunks, "aggregate chunks")
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyFromEnv(t *testing.T) {
+	t.Setenv("SECONDS_PER_SLOT", "12")
+	t.Setenv("RETAIN_COMMITTEES_DAYS", "1")
+	t.Setenv("RETAIN_ATTESTATIONS_DAYS", "")
+	t.Setenv("RETAIN_AGGREGATES_DAYS", "2")
+	t.Setenv("COMPRESS_AFTER_DAYS", "beacon_chain_data:30, epoch_participation:7")
+	// 1000 epochs after genesis
+	now := time.Unix(GenesisUnixTime+1000*384, 0)
+
+	policy, err := RetentionPolicyFromEnv(now)
+	if err != nil {
+		t.Fatalf("failed to read the policy: %v", err)
+	}
+	// A day is 225 epochs
+	if policy.CommitteesBeforeEpoch != 775 || policy.AttestationsBeforeEpoch != 0 || policy.AggregatesBefore != now.Unix()-2*86400 {
+		t.Errorf("unexpected cutoffs %+v", policy)
+	}
+	if policy.CompressAfter["beacon_chain_data"] != 30*86400 || policy.CompressAfter["epoch_participation"] != 7*86400 {
+		t.Errorf("unexpected compression %v", policy.CompressAfter)
+	}
+
+	t.Setenv("COMPRESS_AFTER_DAYS", "beacon_chain_data")
+	if _, err = RetentionPolicyFromEnv(now); err == nil {
+		t.Error("expected an entry without days to be rejected")
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/bls"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"go-beacon-chain-indexer/ssz"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrNoTrustedCheckpoint = errors.New("verifying signatures needs TRUSTED_CHECKPOINT_ROOT, the root of a finalized block taken from a source you trust")
+
+/*
+trustedRegistry holds the public keys of the validator registry in the state of the trusted checkpoint, in
+registry order, with the genesis validators root of that state
+*/
+type trustedRegistry struct {
+	slot                  uint64
+	genesisValidatorsRoot [32]byte
+	publicKeys            [][48]byte
+}
+
+/*
+This method checks the proposer signature of every row: the signature must verify against the proposer's
+public key over the signing root of the header, with the DOMAIN_BEACON_PROPOSER domain of the fork active
+at the slot. Public keys and the genesis validators root are read from the state of the trusted checkpoint,
+which is checked against the checkpoint root before anything is read from it, so a beacon node can not
+forge headers without the proposer's key. Validators never change their key and are never removed from the
+registry, so a recent checkpoint covers every earlier slot
+*/
+func (s *Service) verifySignatures(rows []model.BeaconChainData) ([]*IntegrityError, error) {
+	registry, err := s.trustedRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var integrityErrors []*IntegrityError
+	for _, row := range rows {
+		message := row.Data.Header.Message
+		slot, _ := strconv.ParseInt(message.Slot, 10, 64)
+		header, err := toSSZHeader(message)
+		if err != nil {
+			// Already reported by the root check
+			continue
+		}
+		if header.ProposerIndex >= uint64(len(registry.publicKeys)) {
+			integrityErrors = append(integrityErrors, &IntegrityError{Slot: slot, Field: "proposer_index",
+				Expected: fmt.Sprintf("a validator of the trusted checkpoint state of slot %v", registry.slot), Stored: message.ProposerIndex})
+			continue
+		}
+		_, fork, err := s.forkEntryAt(getEpochNumber(slot))
+		if err != nil {
+			return nil, err
+		}
+		forkVersion, err := decodeFixedHex(fork.CurrentVersion, 4)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fork version %q in the fork schedule", fork.CurrentVersion)
+		}
+		var version [4]byte
+		copy(version[:], forkVersion)
+		domain := spec.ComputeDomain(spec.DomainBeaconProposer, version, registry.genesisValidatorsRoot)
+		signingRoot := spec.ComputeSigningRoot(header.HashTreeRoot(), domain)
+
+		var signature [96]byte
+		decoded, err := decodeFixedHex(row.Data.Header.Signature, len(signature))
+		if err != nil {
+			integrityErrors = append(integrityErrors, &IntegrityError{Slot: slot, Field: "signature", Expected: "a 96 byte signature", Stored: row.Data.Header.Signature})
+			continue
+		}
+		copy(signature[:], decoded)
+		valid, err := bls.Verify(registry.publicKeys[header.ProposerIndex], signingRoot, signature)
+		if err != nil || !valid {
+			integrityErrors = append(integrityErrors, &IntegrityError{Slot: slot, Field: "signature", Expected: "a valid signature of validator " + message.ProposerIndex, Stored: row.Data.Header.Signature})
+		}
+	}
+	return integrityErrors, nil
+}
+
+/*
+This method loads the validator registry of the trusted checkpoint once. The header of the checkpoint block
+must hash to TRUSTED_CHECKPOINT_ROOT and the SSZ state of its slot to the state root of that header, so a
+beacon node can only serve the registry the checkpoint commits to. GENESIS_VALIDATORS_ROOT, when it is set,
+must match the genesis validators root of the state
+*/
+func (s *Service) trustedRegistry() (*trustedRegistry, error) {
+	s.trustedMutex.Lock()
+	defer s.trustedMutex.Unlock()
+	if s.trusted != nil {
+		return s.trusted, nil
+	}
+	checkpoint := os.Getenv("TRUSTED_CHECKPOINT_ROOT")
+	if checkpoint == "" {
+		return nil, ErrNoTrustedCheckpoint
+	}
+	checkpointRoot, err := decodeRoot(checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_CHECKPOINT_ROOT %q: %w", checkpoint, err)
+	}
+
+	var response struct {
+		Data struct {
+			Header model.HeaderData `json:"header"`
+		} `json:"data"`
+	}
+	err = s.client.GetJSON("/eth/v1/beacon/headers/"+encodeHex(checkpointRoot[:]), &response)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	header, err := toSSZHeader(response.Data.Header.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header for the trusted checkpoint: %w", err)
+	}
+	if header.HashTreeRoot() != checkpointRoot {
+		err = fmt.Errorf("the header served for the trusted checkpoint %s does not hash to it", checkpoint)
+		logger.LogError(err)
+		return nil, err
+	}
+
+	state, version, err := s.client.GetSSZ(fmt.Sprintf("/eth/v2/debug/beacon/states/%v", header.Slot))
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	fork, err := model.ParseForkVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("state of the trusted checkpoint: %w", err)
+	}
+	stateRoot, err := ssz.StateRoot(state, fork)
+	if err != nil {
+		return nil, fmt.Errorf("hashing the state of the trusted checkpoint: %w", err)
+	}
+	if stateRoot != header.StateRoot {
+		err = fmt.Errorf("the state served for slot %v does not hash to the state root of the trusted checkpoint", header.Slot)
+		logger.LogError(err)
+		return nil, err
+	}
+
+	registry := &trustedRegistry{slot: header.Slot}
+	if registry.genesisValidatorsRoot, err = ssz.StateGenesisValidatorsRoot(state); err != nil {
+		return nil, err
+	}
+	if pinned := os.Getenv("GENESIS_VALIDATORS_ROOT"); pinned != "" {
+		if root, err := decodeRoot(pinned); err != nil || root != registry.genesisValidatorsRoot {
+			return nil, fmt.Errorf("the trusted checkpoint is not on the chain of GENESIS_VALIDATORS_ROOT %s", pinned)
+		}
+	}
+	validators, err := ssz.NewValidatorRegistryReader(bytes.NewReader(state))
+	if err != nil {
+		return nil, err
+	}
+	registry.publicKeys = make([][48]byte, 0, validators.Count)
+	for {
+		validator, err := validators.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		registry.publicKeys = append(registry.publicKeys, validator.Pubkey)
+	}
+	logger.LogInfo("Loaded", len(registry.publicKeys), "public keys from the state of the trusted checkpoint at slot", registry.slot)
+	s.trusted = registry
+	return registry, nil
+}
+
+func decodeFixedHex(s string, size int) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", size, len(decoded))
+	}
+	return decoded, nil
+}
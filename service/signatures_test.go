@@ -0,0 +1,212 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/spec"
+	"go-beacon-chain-indexer/ssz"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+const (
+	mainnetGenesisBlockRoot       = "0x4d611d5b93fdab69013a7f0a2f961caca0c853f87cfe9595fe50038163079360"
+	mainnetGenesisValidatorsRoot  = "0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"
+	mainnetGenesisStateRoot       = "0x7e76880eb67bbdc86250aa578958e9d0675e64e714337855204fb5abaaf82c2b"
+	mainnetGenesisBodyRoot        = "0xccb62460692be0ec813b56be97f68a82cf57abc102e27bf49ebf4190ff22eedd"
+	mainnetGenesisValidatorsCount = 21063
+)
+
+func readMainnetGenesisState(t *testing.T) []byte {
+	t.Helper()
+	file, err := os.Open("../ssz/testdata/mainnet_genesis_state.ssz.zst")
+	if err != nil {
+		t.Fatalf("failed to open the genesis state: %v", err)
+	}
+	defer file.Close()
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer decoder.Close()
+	state, err := io.ReadAll(decoder)
+	if err != nil {
+		t.Fatalf("failed to read the genesis state: %v", err)
+	}
+	return state
+}
+
+/*
+checkpointNode serves the mainnet genesis block as the checkpoint, with the given header message and state,
+the way a beacon node answers /eth/v1/beacon/headers/{root} and /eth/v2/debug/beacon/states/{slot}
+*/
+func checkpointNode(t *testing.T, message model.MessageData, state []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/eth/v1/beacon/headers/" + mainnetGenesisBlockRoot:
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"root": mainnetGenesisBlockRoot, "header": model.HeaderData{Message: message, Signature: "0x" + strings.Repeat("00", 96)},
+			}})
+		case "/eth/v2/debug/beacon/states/" + message.Slot:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Eth-Consensus-Version", "phase0")
+			w.Write(state)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func genesisMessage() model.MessageData {
+	return model.MessageData{
+		Slot:          "0",
+		ProposerIndex: "0",
+		ParentRoot:    "0x" + strings.Repeat("00", 32),
+		StateRoot:     mainnetGenesisStateRoot,
+		BodyRoot:      mainnetGenesisBodyRoot,
+	}
+}
+
+func TestTrustedRegistry(t *testing.T) {
+	state := readMainnetGenesisState(t)
+	t.Setenv("TRUSTED_CHECKPOINT_ROOT", mainnetGenesisBlockRoot)
+	t.Setenv("GENESIS_VALIDATORS_ROOT", mainnetGenesisValidatorsRoot)
+
+	server := checkpointNode(t, genesisMessage(), state)
+	s := &Service{client: newTestClient(t, 1, server.URL)}
+	registry, err := s.trustedRegistry()
+	if err != nil {
+		t.Fatalf("failed to load the registry: %v", err)
+	}
+	if registry.slot != 0 || len(registry.publicKeys) != mainnetGenesisValidatorsCount ||
+		encodeHex(registry.genesisValidatorsRoot[:]) != mainnetGenesisValidatorsRoot {
+		t.Errorf("unexpected registry of slot %v with %v keys and genesis validators root %x",
+			registry.slot, len(registry.publicKeys), registry.genesisValidatorsRoot)
+	}
+	validators, err := ssz.StateValidators(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registry.publicKeys[1234] != validators[1234].Pubkey {
+		t.Error("the public keys are not in registry order")
+	}
+}
+
+func TestTrustedRegistryRejectsWhatTheCheckpointDoesNotCommitTo(t *testing.T) {
+	state := readMainnetGenesisState(t)
+	tampered := make([]byte, len(state))
+	copy(tampered, state)
+	// The public key of the last validator
+	tampered[len(tampered)-1-8*mainnetGenesisValidatorsCount-ssz.ValidatorSize+1] ^= 1
+
+	otherProposer := genesisMessage()
+	otherProposer.ProposerIndex = "1"
+
+	cases := []struct {
+		name       string
+		checkpoint string
+		pinned     string
+		message    model.MessageData
+		state      []byte
+	}{
+		{"header that does not hash to the checkpoint", mainnetGenesisBlockRoot, mainnetGenesisValidatorsRoot, otherProposer, state},
+		{"state that does not hash to the state root", mainnetGenesisBlockRoot, mainnetGenesisValidatorsRoot, genesisMessage(), tampered},
+		{"state of another chain", mainnetGenesisBlockRoot, "0x" + strings.Repeat("11", 32), genesisMessage(), state},
+		{"no checkpoint", "", mainnetGenesisValidatorsRoot, genesisMessage(), state},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_CHECKPOINT_ROOT", c.checkpoint)
+			t.Setenv("GENESIS_VALIDATORS_ROOT", c.pinned)
+			server := checkpointNode(t, c.message, c.state)
+			s := &Service{client: newTestClient(t, 1, server.URL)}
+			if registry, err := s.trustedRegistry(); err == nil {
+				t.Fatalf("expected the registry to be rejected, got %v keys", len(registry.publicKeys))
+			}
+			if s.trusted != nil {
+				t.Error("a rejected registry must not be cached")
+			}
+		})
+	}
+
+	t.Setenv("TRUSTED_CHECKPOINT_ROOT", "")
+	s := &Service{}
+	if _, err := s.verifySignatures(nil); !errors.Is(err, ErrNoTrustedCheckpoint) {
+		t.Errorf("expected verifying without a checkpoint to fail with ErrNoTrustedCheckpoint, got %v", err)
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	t.Setenv("SLOTS_PER_EPOCH", "32")
+	ikm := sha256.Sum256([]byte("proposer"))
+	secretKey := blst.KeyGen(ikm[:])
+	registry := &trustedRegistry{slot: 100}
+	registry.genesisValidatorsRoot, _ = decodeRoot(mainnetGenesisValidatorsRoot)
+	var publicKey [48]byte
+	copy(publicKey[:], new(blst.P1Affine).From(secretKey).Compress())
+	registry.publicKeys = [][48]byte{{}, publicKey}
+
+	s := &Service{
+		trusted: registry,
+		forks: []ForkScheduleEntry{
+			{PreviousVersion: "0x00000000", CurrentVersion: "0x00000000", Epoch: "0"},
+			{PreviousVersion: "0x00000000", CurrentVersion: "0x01000000", Epoch: "2"},
+		},
+	}
+	signedRow := func(slot string, proposer string, version [4]byte) model.BeaconChainData {
+		message := model.MessageData{Slot: slot, ProposerIndex: proposer, ParentRoot: mainnetGenesisBlockRoot,
+			StateRoot: mainnetGenesisStateRoot, BodyRoot: mainnetGenesisBodyRoot}
+		header, err := toSSZHeader(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		domain := spec.ComputeDomain(spec.DomainBeaconProposer, version, registry.genesisValidatorsRoot)
+		signingRoot := spec.ComputeSigningRoot(header.HashTreeRoot(), domain)
+		signature := new(blst.P2Affine).Sign(secretKey, signingRoot[:], []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")).Compress()
+		return model.BeaconChainData{Data: model.SlotData{Header: model.HeaderData{Message: message, Signature: encodeHex(signature)}}}
+	}
+
+	phase0 := [4]byte{0, 0, 0, 0}
+	altair := [4]byte{1, 0, 0, 0}
+	rows := []model.BeaconChainData{
+		signedRow("33", "1", phase0),
+		signedRow("70", "1", altair),
+		// Signed with the domain of the wrong fork
+		signedRow("71", "1", phase0),
+		// Not a validator of the checkpoint state
+		signedRow("72", "2", altair),
+	}
+	forged := signedRow("73", "1", altair)
+	forged.Data.Header.Message.BodyRoot = mainnetGenesisStateRoot
+	rows = append(rows, forged)
+
+	integrityErrors, err := s.verifySignatures(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[int64]string)
+	for _, integrityError := range integrityErrors {
+		found[integrityError.Slot] = integrityError.Field
+	}
+	expected := map[int64]string{71: "signature", 72: "proposer_index", 73: "signature"}
+	if len(found) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, found)
+	}
+	for slot, field := range expected {
+		if found[slot] != field {
+			t.Errorf("expected slot %v to fail on %s, got %v", slot, field, found)
+		}
+	}
+}
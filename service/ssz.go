@@ -0,0 +1,153 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/ssz"
+	"io"
+	"os"
+	"strconv"
+)
+
+/*
+This method fetches the attestations included in the block of a slot. The SSZ encoded block is used
+when the node supports it, otherwise the JSON block decoded into the body type of its fork
+*/
+func (s *Service) fetchBlockAttestations(slot int64) ([]model.Attestation, error) {
+	if s.useSSZ {
+		attestations, err := s.fetchBlockAttestationsSSZ(slot)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			return attestations, err
+		}
+		if !errors.Is(err, ErrSSZUnsupported) && !errors.Is(err, ssz.ErrUnsupportedFork) {
+			logger.LogError(fmt.Errorf("falling back to json for block %v: %w", slot, err))
+		}
+	}
+	block, err := s.FetchBlock(slot)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, ErrNotFound
+	}
+	attestations := block.Message.Body.BlockAttestations()
+	for _, attestation := range attestations {
+		if ssz.IsElectraAttestationFork(block.Version) != (attestation.CommitteeBits != "") {
+			return nil, fmt.Errorf("attestation in slot %v does not match the %q layout", slot, block.Version)
+		}
+	}
+	return attestations, nil
+}
+
+func (s *Service) fetchBlockAttestationsSSZ(slot int64) ([]model.Attestation, error) {
+	body, version, err := s.fetchSSZ(fmt.Sprintf("/eth/v2/beacon/blocks/%v", slot), s.isFinalizedSlot(slot))
+	if err != nil {
+		return nil, err
+	}
+	fork, err := model.ParseForkVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ssz.ErrUnsupportedFork, err)
+	}
+	decoded, err := ssz.DecodeBlockAttestations(body, fork)
+	if err != nil {
+		return nil, err
+	}
+	attestations := make([]model.Attestation, 0, len(decoded))
+	for _, attestation := range decoded {
+		attestations = append(attestations, toModelAttestation(attestation))
+	}
+	return attestations, nil
+}
+
+/*
+This method counts the active_ongoing validators from the SSZ encoded head state. The state is streamed
+and only the validator registry is read, one validator at a time
+*/
+func (s *Service) fetchValidatorSetSizeSSZ() (int, error) {
+	slotsPerEpoch, _ := strconv.ParseUint(os.Getenv("SLOTS_PER_EPOCH"), 10, 64)
+	count := 0
+	err := s.client.Stream("/eth/v2/debug/beacon/states/head", "application/octet-stream", func(body io.Reader, _ string) error {
+		count = 0
+		registry, err := ssz.NewValidatorRegistryReader(body)
+		if err != nil {
+			return err
+		}
+		epoch := registry.Slot / slotsPerEpoch
+		for {
+			validator, err := registry.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if validator.IsActive(epoch) && validator.ExitEpoch == ssz.FarFutureEpoch {
+				count++
+			}
+		}
+	})
+	return count, err
+}
+
+/*
+This method returns an SSZ body and its fork version, serving finalized data from the response cache.
+Cached entries hold the version and the body separated by a zero byte
+*/
+func (s *Service) fetchSSZ(path string, finalized bool) ([]byte, string, error) {
+	key := "ssz:" + path
+	if finalized {
+		if entry, ok := s.cache.Get(key); ok {
+			if separator := bytes.IndexByte(entry, 0); separator > 0 {
+				return entry[separator+1:], string(entry[:separator]), nil
+			}
+		}
+	}
+	body, version, err := s.client.GetSSZ(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if finalized && version != "" {
+		entry := make([]byte, 0, len(version)+1+len(body))
+		entry = append(entry, version...)
+		entry = append(entry, 0)
+		entry = append(entry, body...)
+		s.cache.Put(key, entry)
+	}
+	return body, version, nil
+}
+
+func toModelAttestation(attestation ssz.Attestation) model.Attestation {
+	return model.Attestation{
+		AggregationBits: encodeHex(attestation.AggregationBits),
+		Details: model.AttestationDetails{
+			Slot:            strconv.FormatUint(attestation.Data.Slot, 10),
+			Index:           strconv.FormatUint(attestation.Data.Index, 10),
+			BeaconBlockRoot: encodeHex(attestation.Data.BeaconBlockRoot[:]),
+			Source: model.Epoch{
+				Epoch: strconv.FormatUint(attestation.Data.Source.Epoch, 10),
+				Root:  encodeHex(attestation.Data.Source.Root[:]),
+			},
+			Target: model.Epoch{
+				Epoch: strconv.FormatUint(attestation.Data.Target.Epoch, 10),
+				Root:  encodeHex(attestation.Data.Target.Root[:]),
+			},
+		},
+		Signature:     encodeHex(attestation.Signature[:]),
+		CommitteeBits: encodeOptionalHex(attestation.CommitteeBits),
+	}
+}
+
+func encodeHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+func encodeOptionalHex(b []byte) string {
+	if b == nil {
+		return ""
+	}
+	return encodeHex(b)
+}
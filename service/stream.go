@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/*
+This method hands the response body for the path to decode as a stream. Finalized responses are served
+from the response cache when present, and captured into it while streaming when they are not, as long
+as they fit into the cache at all
+*/
+func (s *Service) streamBody(path string, finalized bool, decode func(body io.Reader) error) error {
+	if finalized {
+		if body, ok := s.cache.Get(path); ok {
+			return decode(bytes.NewReader(body))
+		}
+	}
+	return s.client.Stream(path, "application/json", func(body io.Reader, _ string) error {
+		if !finalized {
+			return decode(body)
+		}
+		capture := &captureReader{reader: body, limit: s.cache.maxBytes}
+		err := decode(capture)
+		if err != nil {
+			return err
+		}
+		// Drain whatever the decoder did not need so the captured body is complete
+		_, err = io.Copy(io.Discard, capture)
+		if err != nil {
+			return err
+		}
+		if !capture.overflow {
+			s.cache.Put(path, capture.buffer.Bytes())
+		}
+		return nil
+	})
+}
+
+/*
+captureReader copies everything read through it into a buffer, giving up on the copy once it grows
+past the limit so a huge body never ends up in memory twice
+*/
+type captureReader struct {
+	reader   io.Reader
+	buffer   bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if n > 0 && !c.overflow {
+		if int64(c.buffer.Len()+n) > c.limit {
+			c.overflow = true
+			c.buffer = bytes.Buffer{}
+		} else {
+			c.buffer.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+/*
+This function walks a beacon API response of the form {"data": [...], ...} and calls fn once per array
+element with the decoder positioned on it. fn is expected to decode exactly one value
+*/
+func decodeDataArray(body io.Reader, fn func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(body)
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if token != "data" {
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = expectDelim(dec, '[')
+		if err != nil {
+			return err
+		}
+		for dec.More() {
+			err = fn(dec)
+			if err != nil {
+				return err
+			}
+		}
+		return expectDelim(dec, ']')
+	}
+	return fmt.Errorf("response has no data field")
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v in response but found %v", delim, token)
+	}
+	return nil
+}
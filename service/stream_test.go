@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDecodeDataArray(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		body     string
+		expected []int
+		fails    bool
+	}{
+		{"elements", `{"data":[1,2,3]}`, []int{1, 2, 3}, false},
+		{"fields around data", `{"execution_optimistic":false,"meta":{"a":[1]},"data":[4,5],"finalized":true}`, []int{4, 5}, false},
+		{"empty array", `{"data":[]}`, nil, false},
+		{"truncated element", `{"data":[1,2`, []int{1, 2}, true},
+		{"truncated after the bracket", `{"data":[`, nil, true},
+		{"missing closing bracket", `{"data":[1,2}`, []int{1, 2}, true},
+		{"data is not an array", `{"data":{"a":1}}`, nil, true},
+		{"no data field", `{"code":500,"message":"error"}`, nil, true},
+		{"not an object", `[1,2]`, nil, true},
+		{"malformed element", `{"data":[1,"x",3]}`, []int{1}, true},
+		{"empty body", ``, nil, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var values []int
+			err := decodeDataArray(strings.NewReader(test.body), func(dec *json.Decoder) error {
+				var value int
+				if err := dec.Decode(&value); err != nil {
+					return err
+				}
+				values = append(values, value)
+				return nil
+			})
+			if (err != nil) != test.fails {
+				t.Errorf("expected failure %v, got %v", test.fails, err)
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("expected %v decoded, got %v", test.expected, values)
+			}
+		})
+	}
+}
+
+func TestCaptureReaderGivesUpPastTheLimit(t *testing.T) {
+	capture := &captureReader{reader: strings.NewReader("0123456789"), limit: 10}
+	if body, _ := io.ReadAll(capture); string(body) != "0123456789" || capture.overflow || capture.buffer.String() != "0123456789" {
+		t.Errorf("expected a body at the limit to be captured, got %q", capture.buffer.String())
+	}
+	capture = &captureReader{reader: strings.NewReader("0123456789a"), limit: 10}
+	if body, _ := io.ReadAll(capture); string(body) != "0123456789a" || !capture.overflow || capture.buffer.Len() != 0 {
+		t.Errorf("expected the capture dropped past the limit, got %q", capture.buffer.String())
+	}
+}
+
+func TestStreamBodyReplaysTruncatedResponses(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first answer is cut off half way through the array
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte(`{"data":[1,2,`))
+			return
+		}
+		w.Write([]byte(`{"data":[1,2,3]}`))
+	}))
+	defer server.Close()
+	s := &Service{client: newTestClient(t, 3, server.URL), cache: NewResponseCache(1<<20, "")}
+
+	var values []int
+	decode := func(body io.Reader) error {
+		// Every attempt starts over, nothing of a failed attempt may be kept
+		values = nil
+		return decodeDataArray(body, func(dec *json.Decoder) error {
+			var value int
+			err := dec.Decode(&value)
+			values = append(values, value)
+			return err
+		})
+	}
+	if err := s.streamBody("/values", true, decode); err != nil {
+		t.Fatalf("expected the retry to succeed: %v", err)
+	}
+	if calls != 2 || !reflect.DeepEqual(values, []int{1, 2, 3}) {
+		t.Errorf("expected 3 values after 2 calls, got %v after %v", values, calls)
+	}
+	// Only the complete body was captured, and it is replayed from the cache
+	if body, ok := s.cache.Get("/values"); !ok || string(body) != `{"data":[1,2,3]}` {
+		t.Errorf("expected the complete body cached, got %q", body)
+	}
+	if err := s.streamBody("/values", true, decode); err != nil || calls != 2 || !reflect.DeepEqual(values, []int{1, 2, 3}) {
+		t.Errorf("expected the cached body to be decoded without a call, got %v after %v calls: %v", values, calls, err)
+	}
+}
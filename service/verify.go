@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"go-beacon-chain-indexer/ssz"
+	"strconv"
+	"strings"
+)
+
+var ErrIntegrity = errors.New("indexed data does not match the chain")
+
+/*
+IntegrityError reports indexed data that contradicts what the spec derives from the chain, such as a
+stored proposer that is not the one the spec selects for the slot
+*/
+type IntegrityError struct {
+	Slot     int64  `json:"slot"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Stored   string `json:"stored"`
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%v: slot %v has %s %s, expected %s", ErrIntegrity, e.Slot, e.Field, e.Stored, e.Expected)
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return ErrIntegrity
+}
+
+/*
+VerificationReport lists every integrity error found in a range of indexed slots
+*/
+type VerificationReport struct {
+	FromSlot int64             `json:"from_slot"`
+	ToSlot   int64             `json:"to_slot"`
+	Checked  int               `json:"checked"`
+	Errors   []*IntegrityError `json:"errors"`
+}
+
+/*
+This method verifies the indexed rows between the two slots. The stored root must be the hash tree root of
+the stored header, and the parent root of every canonical row must be the root of the canonical row before
+it. Missed slots have no row, so a link only breaks when a block is missing or was stored wrongly. The
+first row is linked to the last canonical row indexed before the range, if there is one. With signatures the
+proposer signature of every row is verified as well
+*/
+func (s *Service) VerifyRange(fromSlot int64, toSlot int64, signatures bool) (*VerificationReport, error) {
+	report := &VerificationReport{FromSlot: fromSlot, ToSlot: toSlot, Errors: []*IntegrityError{}}
+	previousRoot, err := s.db.GetCanonicalRootBefore(fromSlot)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.GetIndexedData(fromSlot, toSlot)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		report.Checked++
+		slot, _ := strconv.ParseInt(row.Data.Header.Message.Slot, 10, 64)
+		header, err := toSSZHeader(row.Data.Header.Message)
+		if err != nil {
+			report.Errors = append(report.Errors, &IntegrityError{Slot: slot, Field: "header", Expected: "a decodable header", Stored: err.Error()})
+			continue
+		}
+		root := header.HashTreeRoot()
+		if computed := encodeHex(root[:]); !strings.EqualFold(computed, row.Data.Root) {
+			report.Errors = append(report.Errors, &IntegrityError{Slot: slot, Field: "root", Expected: computed, Stored: row.Data.Root})
+		}
+		if !row.Data.Canonical {
+			continue
+		}
+		if previousRoot != "" && !strings.EqualFold(previousRoot, row.Data.Header.Message.ParentRoot) {
+			report.Errors = append(report.Errors, &IntegrityError{Slot: slot, Field: "parent_root", Expected: previousRoot, Stored: row.Data.Header.Message.ParentRoot})
+		}
+		previousRoot = row.Data.Root
+	}
+	if signatures {
+		signatureErrors, err := s.verifySignatures(rows)
+		if err != nil {
+			return nil, err
+		}
+		report.Errors = append(report.Errors, signatureErrors...)
+	}
+	for _, integrityErr := range report.Errors {
+		logger.LogError(integrityErr)
+	}
+	return report, nil
+}
+
+func toSSZHeader(message model.MessageData) (*ssz.BeaconBlockHeader, error) {
+	var header ssz.BeaconBlockHeader
+	var err error
+	header.Slot, err = strconv.ParseUint(message.Slot, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slot %q", message.Slot)
+	}
+	header.ProposerIndex, err = strconv.ParseUint(message.ProposerIndex, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proposer index %q", message.ProposerIndex)
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		root  *[32]byte
+	}{
+		{"parent_root", message.ParentRoot, &header.ParentRoot},
+		{"state_root", message.StateRoot, &header.StateRoot},
+		{"body_root", message.BodyRoot, &header.BodyRoot},
+	} {
+		*field.root, err = decodeRoot(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", field.name, field.value)
+		}
+	}
+	return &header, nil
+}
+
+func decodeRoot(s string) ([32]byte, error) {
+	var root [32]byte
+	decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return root, err
+	}
+	if len(decoded) != len(root) {
+		return root, errors.New("root is not 32 bytes")
+	}
+	copy(root[:], decoded)
+	return root, nil
+}
+
+// IndexedSlotRange returns the lowest and highest indexed slot, both -1 when nothing is indexed
+func (s *Service) IndexedSlotRange() (int64, int64, error) {
+	return s.db.GetIndexedSlotRange()
+}
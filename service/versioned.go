@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-beacon-chain-indexer/logger"
+	"go-beacon-chain-indexer/model"
+	"io"
+)
+
+/*
+This method fetches the full block of a slot from /eth/v2/beacon/blocks, decoded into the body type of the
+fork it was produced in. A missed slot is returned as a nil block without an error
+*/
+func (s *Service) FetchBlock(slot int64) (*model.SignedBeaconBlock, error) {
+	var block *model.SignedBeaconBlock
+	err := s.streamBody(fmt.Sprintf("/eth/v2/beacon/blocks/%v", slot), s.isFinalizedSlot(slot), func(body io.Reader) error {
+		var err error
+		block, err = model.DecodeSignedBeaconBlock(body)
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return block, nil
+}
+
+/*
+This method fetches a full state from /eth/v2/debug/beacon/states, decoded into the state type of its fork.
+States are large and change every slot, so they are never cached
+*/
+func (s *Service) FetchState(stateID string) (*model.VersionedBeaconState, error) {
+	var state *model.VersionedBeaconState
+	err := s.client.Stream(fmt.Sprintf("/eth/v2/debug/beacon/states/%s", stateID), "application/json", func(body io.Reader, _ string) error {
+		var err error
+		state, err = model.DecodeBeaconState(body)
+		return err
+	})
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,70 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+type Committee struct {
+	Slot       uint64
+	Index      uint64
+	Validators []uint64
+}
+
+/*
+This function is get_seed from the spec. randaoMix must be the mix of epoch - MinSeedLookahead - 1, which
+is what get_randao_mix returns for epoch + EpochsPerHistoricalVector - MinSeedLookahead - 1
+*/
+func GetSeed(randaoMix [32]byte, epoch uint64, domain DomainType) [32]byte {
+	buf := make([]byte, 4+8+32)
+	copy(buf, domain[:])
+	binary.LittleEndian.PutUint64(buf[4:], epoch)
+	copy(buf[12:], randaoMix[:])
+	return sha256.Sum256(buf)
+}
+
+// SeedEpoch returns the epoch whose randao mix seeds the shuffling of the given epoch
+func SeedEpoch(epoch uint64) uint64 {
+	if epoch < MinSeedLookahead+1 {
+		// get_randao_mix wraps around the historical vector, which early on still holds the genesis mix
+		return epoch + EpochsPerHistoricalVector - MinSeedLookahead - 1
+	}
+	return epoch - MinSeedLookahead - 1
+}
+
+// CommitteeCountPerSlot is get_committee_count_per_slot from the spec
+func CommitteeCountPerSlot(activeValidatorCount uint64) uint64 {
+	count := activeValidatorCount / SlotsPerEpoch / TargetCommitteeSize
+	if count > MaxCommitteesPerSlot {
+		count = MaxCommitteesPerSlot
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
+/*
+This function computes every beacon committee of an epoch from the active validator indices (in registry
+order) and the attester seed of the epoch, which is what get_beacon_committee returns for each slot and index
+*/
+func ComputeBeaconCommittees(activeIndices []uint64, seed [32]byte, epoch uint64) []Committee {
+	shuffled := make([]uint64, len(activeIndices))
+	copy(shuffled, activeIndices)
+	ShuffleList(shuffled, seed)
+
+	committeesPerSlot := CommitteeCountPerSlot(uint64(len(activeIndices)))
+	count := committeesPerSlot * SlotsPerEpoch
+	total := uint64(len(shuffled))
+	committees := make([]Committee, 0, count)
+	for k := uint64(0); k < count; k++ {
+		start := total * k / count
+		end := total * (k + 1) / count
+		committees = append(committees, Committee{
+			Slot:       epoch*SlotsPerEpoch + k/committeesPerSlot,
+			Index:      k % committeesPerSlot,
+			Validators: shuffled[start:end],
+		})
+	}
+	return committees
+}
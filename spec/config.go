@@ -0,0 +1,42 @@
+package spec
+
+/*
+Mainnet preset values used by the consensus spec functions in this package
+*/
+const (
+	SlotsPerEpoch             = 32
+	SlotsPerHistoricalRoot    = 8192
+	ShuffleRoundCount         = 90
+	TargetCommitteeSize       = 128
+	MaxCommitteesPerSlot      = 64
+	MinSeedLookahead          = 1
+	EpochsPerHistoricalVector = 65536
+
+	MaxEffectiveBalance        = 32_000_000_000
+	MaxEffectiveBalanceElectra = 2048_000_000_000
+	FarFutureEpoch             = ^uint64(0)
+)
+
+type DomainType [4]byte
+
+var (
+	DomainBeaconProposer = DomainType{0x00, 0x00, 0x00, 0x00}
+	DomainBeaconAttester = DomainType{0x01, 0x00, 0x00, 0x00}
+)
+
+/*
+MainnetForkEpochs holds the activation epoch of every mainnet fork in fork order, phase0 being first. Era
+files carry no fork information, the fork of a block follows from its slot
+*/
+var MainnetForkEpochs = []uint64{0, 74240, 144896, 194048, 269568, 364032, 411392}
+
+// ForkPosition returns the position in MainnetForkEpochs of the fork active at the epoch
+func ForkPosition(epoch uint64) int {
+	position := 0
+	for i, forkEpoch := range MainnetForkEpochs {
+		if forkEpoch <= epoch {
+			position = i
+		}
+	}
+	return position
+}
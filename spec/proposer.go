@@ -0,0 +1,70 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrNoActiveValidators = errors.New("no active validators to pick a proposer from")
+
+/*
+This function is compute_proposer_index from the spec. Candidates are drawn in shuffled order and accepted
+with a probability proportional to their effective balance. Before electra one random byte is drawn per
+candidate against MAX_EFFECTIVE_BALANCE, from electra on two bytes are drawn against MAX_EFFECTIVE_BALANCE_ELECTRA.
+effectiveBalances is indexed by validator index
+*/
+func ComputeProposerIndex(activeIndices []uint64, effectiveBalances []uint64, seed [32]byte, electra bool) (uint64, error) {
+	total := uint64(len(activeIndices))
+	if total == 0 {
+		return 0, ErrNoActiveValidators
+	}
+	buf := make([]byte, 32+8)
+	copy(buf, seed[:])
+	var random [32]byte
+	for i := uint64(0); ; i++ {
+		candidate := activeIndices[ComputeShuffledIndex(i%total, total, seed)]
+		if candidate >= uint64(len(effectiveBalances)) {
+			return 0, errors.New("proposer candidate is missing from the effective balances")
+		}
+		balance := effectiveBalances[candidate]
+		if electra {
+			if i%16 == 0 {
+				binary.LittleEndian.PutUint64(buf[32:], i/16)
+				random = sha256.Sum256(buf)
+			}
+			offset := i % 16 * 2
+			value := uint64(binary.LittleEndian.Uint16(random[offset : offset+2]))
+			if balance*0xffff >= MaxEffectiveBalanceElectra*value {
+				return candidate, nil
+			}
+			continue
+		}
+		if i%32 == 0 {
+			binary.LittleEndian.PutUint64(buf[32:], i/32)
+			random = sha256.Sum256(buf)
+		}
+		if balance*0xff >= MaxEffectiveBalance*uint64(random[i%32]) {
+			return candidate, nil
+		}
+	}
+}
+
+/*
+This function returns the proposer of every slot of an epoch, as get_beacon_proposer_index returns it for
+each slot: the seed of a slot is the epoch's proposer seed hashed with the slot number
+*/
+func ComputeProposers(activeIndices []uint64, effectiveBalances []uint64, epochSeed [32]byte, epoch uint64, electra bool) ([]uint64, error) {
+	proposers := make([]uint64, 0, SlotsPerEpoch)
+	buf := make([]byte, 32+8)
+	copy(buf, epochSeed[:])
+	for slot := epoch * SlotsPerEpoch; slot < (epoch+1)*SlotsPerEpoch; slot++ {
+		binary.LittleEndian.PutUint64(buf[32:], slot)
+		proposer, err := ComputeProposerIndex(activeIndices, effectiveBalances, sha256.Sum256(buf), electra)
+		if err != nil {
+			return nil, err
+		}
+		proposers = append(proposers, proposer)
+	}
+	return proposers, nil
+}
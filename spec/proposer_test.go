@@ -0,0 +1,79 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+func TestProposerWithFullBalanceIsFirstCandidate(t *testing.T) {
+	seed := sha256.Sum256([]byte("proposer"))
+	active := []uint64{3, 5, 8, 13, 21, 34, 55}
+	balances := make([]uint64, 56)
+	for _, index := range active {
+		balances[index] = MaxEffectiveBalance
+	}
+	// Before electra a validator at MAX_EFFECTIVE_BALANCE passes any random byte
+	expected := active[ComputeShuffledIndex(0, uint64(len(active)), seed)]
+	proposer, err := ComputeProposerIndex(active, balances, seed, false)
+	if err != nil {
+		t.Fatalf("failed to compute proposer: %v", err)
+	}
+	if proposer != expected {
+		t.Errorf("expected proposer %d, got %d", expected, proposer)
+	}
+}
+
+func TestProposerSkipsZeroBalanceCandidates(t *testing.T) {
+	seed := sha256.Sum256([]byte("proposer"))
+	active := []uint64{0, 1, 2, 3}
+	first := active[ComputeShuffledIndex(0, 4, seed)]
+	for _, electra := range []bool{false, true} {
+		balances := []uint64{MaxEffectiveBalanceElectra, MaxEffectiveBalanceElectra, MaxEffectiveBalanceElectra, MaxEffectiveBalanceElectra}
+		balances[first] = 0
+		proposer, err := ComputeProposerIndex(active, balances, seed, electra)
+		if err != nil {
+			t.Fatalf("failed to compute proposer: %v", err)
+		}
+		if proposer == first {
+			t.Errorf("electra %v: validator %d has no balance and must not propose", electra, first)
+		}
+	}
+}
+
+func TestElectraProposerUsesTwoRandomBytes(t *testing.T) {
+	active := []uint64{0, 1, 2, 3, 4, 5, 6, 7}
+	balances := make([]uint64, len(active))
+	for i := range balances {
+		balances[i] = MaxEffectiveBalance
+	}
+	// A 32 ETH validator only passes a 16 bit draw at or below 0xffff/64. Look for a seed whose first
+	// accepted draw is within the first hash but not the first draw, and walk the spec loop by hand
+	for n := 0; n < 10000; n++ {
+		seed := sha256.Sum256([]byte{byte(n), byte(n >> 8)})
+		buf := make([]byte, 40)
+		copy(buf, seed[:])
+		random := sha256.Sum256(buf)
+		accepted := -1
+		for i := 0; i < 16; i++ {
+			value := uint64(binary.LittleEndian.Uint16(random[i*2:]))
+			if MaxEffectiveBalance*0xffff >= MaxEffectiveBalanceElectra*value {
+				accepted = i
+				break
+			}
+		}
+		if accepted < 1 {
+			continue
+		}
+		expected := active[ComputeShuffledIndex(uint64(accepted)%8, 8, seed)]
+		proposer, err := ComputeProposerIndex(active, balances, seed, true)
+		if err != nil {
+			t.Fatalf("failed to compute proposer: %v", err)
+		}
+		if proposer != expected {
+			t.Errorf("expected proposer %d from draw %d, got %d", expected, accepted, proposer)
+		}
+		return
+	}
+	t.Fatal("no seed found with an accepted draw in the first hash")
+}
@@ -0,0 +1,75 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+/*
+This function is compute_shuffled_index from the spec: the swap-or-not shuffle applied to a single index.
+It costs ShuffleRoundCount hashes per call, ShuffleList should be used to shuffle a whole list
+*/
+func ComputeShuffledIndex(index uint64, indexCount uint64, seed [32]byte) uint64 {
+	if indexCount == 0 || index >= indexCount {
+		return index
+	}
+	buf := make([]byte, 32+1+4)
+	copy(buf, seed[:])
+	for round := uint64(0); round < ShuffleRoundCount; round++ {
+		pivot := roundPivot(seed, round, indexCount)
+		flip := (pivot + indexCount - index) % indexCount
+		position := index
+		if flip > position {
+			position = flip
+		}
+		buf[32] = byte(round)
+		binary.LittleEndian.PutUint32(buf[33:], uint32(position/256))
+		source := sha256.Sum256(buf)
+		if (source[(position%256)/8]>>(position%8))&1 == 1 {
+			index = flip
+		}
+	}
+	return index
+}
+
+/*
+This function shuffles a whole list in place so that afterwards list[i] holds what used to be at
+list[ComputeShuffledIndex(i)]. Every round of the shuffle is a set of pairwise swaps, so instead of
+tracing each index through all rounds the rounds are applied to the list in reverse order, hashing
+each block of 256 positions only once per round
+*/
+func ShuffleList(list []uint64, seed [32]byte) {
+	count := uint64(len(list))
+	if count < 2 {
+		return
+	}
+	buf := make([]byte, 32+1+4)
+	copy(buf, seed[:])
+	sources := make([][32]byte, (count+255)/256)
+	for round := int(ShuffleRoundCount) - 1; round >= 0; round-- {
+		pivot := roundPivot(seed, uint64(round), count)
+		buf[32] = byte(round)
+		for block := range sources {
+			binary.LittleEndian.PutUint32(buf[33:], uint32(block))
+			sources[block] = sha256.Sum256(buf)
+		}
+		for i := uint64(0); i < count; i++ {
+			flip := (pivot + count - i) % count
+			// Every pair is visited twice, only swap it from its lower position
+			if i >= flip {
+				continue
+			}
+			if (sources[flip/256][(flip%256)/8]>>(flip%8))&1 == 1 {
+				list[i], list[flip] = list[flip], list[i]
+			}
+		}
+	}
+}
+
+func roundPivot(seed [32]byte, round uint64, indexCount uint64) uint64 {
+	buf := make([]byte, 33)
+	copy(buf, seed[:])
+	buf[32] = byte(round)
+	hash := sha256.Sum256(buf)
+	return binary.LittleEndian.Uint64(hash[:8]) % indexCount
+}
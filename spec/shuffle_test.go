@@ -0,0 +1,193 @@
+package spec
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+/*
+shuffleVector mirrors a mapping.yaml case of the consensus-spec-tests shuffling suite. The vectors in
+testdata/shuffling_mainnet.json are not the official files: they use the inputs of the upstream generator
+(seed = sha256 of the little endian uint32 case number, mainnet round count) and their mappings were computed
+with two independent clients, protolambda/zrnt v0.34.1 and prysm v5.0.0, which agree on every index.
+TestConsensusSpecShuffleVectors checks the official files when a consensus-spec-tests release is extracted
+*/
+type shuffleVector struct {
+	Seed    string   `json:"seed"`
+	Count   uint64   `json:"count"`
+	Mapping []uint64 `json:"mapping"`
+}
+
+func loadShuffleVectors(t *testing.T) []shuffleVector {
+	body, err := os.ReadFile("testdata/shuffling_mainnet.json")
+	if err != nil {
+		t.Fatalf("failed to read shuffling vectors: %v", err)
+	}
+	var vectors []shuffleVector
+	err = json.Unmarshal(body, &vectors)
+	if err != nil {
+		t.Fatalf("failed to decode shuffling vectors: %v", err)
+	}
+	return vectors
+}
+
+func decodeSeed(t *testing.T, s string) [32]byte {
+	var seed [32]byte
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) != 32 {
+		t.Fatalf("invalid seed %s", s)
+	}
+	copy(seed[:], b)
+	return seed
+}
+
+func TestComputeShuffledIndexVectors(t *testing.T) {
+	for _, vector := range loadShuffleVectors(t) {
+		seed := decodeSeed(t, vector.Seed)
+		for i, expected := range vector.Mapping {
+			got := ComputeShuffledIndex(uint64(i), vector.Count, seed)
+			if got != expected {
+				t.Fatalf("seed %s count %d: index %d shuffled to %d, expected %d", vector.Seed, vector.Count, i, got, expected)
+			}
+		}
+	}
+}
+
+func TestShuffleListVectors(t *testing.T) {
+	for _, vector := range loadShuffleVectors(t) {
+		seed := decodeSeed(t, vector.Seed)
+		list := make([]uint64, vector.Count)
+		for i := range list {
+			list[i] = uint64(i)
+		}
+		ShuffleList(list, seed)
+		for i, expected := range vector.Mapping {
+			if list[i] != expected {
+				t.Fatalf("seed %s count %d: position %d holds %d, expected %d", vector.Seed, vector.Count, i, list[i], expected)
+			}
+		}
+	}
+}
+
+func TestComputeBeaconCommittees(t *testing.T) {
+	seed := GetSeed([32]byte{0x42}, 10, DomainBeaconAttester)
+	active := make([]uint64, 10000)
+	for i := range active {
+		// Registry order with gaps, as inactive validators are skipped
+		active[i] = uint64(i * 2)
+	}
+	committees := ComputeBeaconCommittees(active, seed, 10)
+
+	committeesPerSlot := CommitteeCountPerSlot(uint64(len(active)))
+	if committeesPerSlot != 2 {
+		t.Fatalf("expected 2 committees per slot, got %d", committeesPerSlot)
+	}
+	if len(committees) != int(committeesPerSlot*SlotsPerEpoch) {
+		t.Fatalf("expected %d committees, got %d", committeesPerSlot*SlotsPerEpoch, len(committees))
+	}
+	seen := make(map[uint64]bool)
+	position := 0
+	for k, committee := range committees {
+		if committee.Slot != 10*SlotsPerEpoch+uint64(k)/committeesPerSlot || committee.Index != uint64(k)%committeesPerSlot {
+			t.Fatalf("committee %d has slot %d and index %d", k, committee.Slot, committee.Index)
+		}
+		for _, validator := range committee.Validators {
+			// compute_committee picks indices[compute_shuffled_index(i)] for every position i
+			expected := active[ComputeShuffledIndex(uint64(position), uint64(len(active)), seed)]
+			if validator != expected {
+				t.Fatalf("position %d holds validator %d, expected %d", position, validator, expected)
+			}
+			if seen[validator] {
+				t.Fatalf("validator %d is in more than one committee", validator)
+			}
+			seen[validator] = true
+			position++
+		}
+	}
+	if len(seen) != len(active) {
+		t.Errorf("expected every active validator in a committee, got %d of %d", len(seen), len(active))
+	}
+}
+
+var (
+	yamlSeed    = regexp.MustCompile(`seed:\s*'?(0x[0-9a-fA-F]{64})'?`)
+	yamlCount   = regexp.MustCompile(`count:\s*(\d+)`)
+	yamlMapping = regexp.MustCompile(`mapping:\s*\[([^\]]*)\]`)
+)
+
+// parseMappingYAML reads the seed, count and flow style mapping list of a mapping.yaml file
+func parseMappingYAML(body string) (shuffleVector, error) {
+	var vector shuffleVector
+	seed, count, mapping := yamlSeed.FindStringSubmatch(body), yamlCount.FindStringSubmatch(body), yamlMapping.FindStringSubmatch(body)
+	if seed == nil || count == nil || mapping == nil {
+		return vector, fmt.Errorf("not a shuffling mapping: %q", body)
+	}
+	vector.Seed = seed[1]
+	vector.Count, _ = strconv.ParseUint(count[1], 10, 64)
+	for _, field := range strings.FieldsFunc(mapping[1], func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		index, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return vector, fmt.Errorf("invalid mapping entry %q", field)
+		}
+		vector.Mapping = append(vector.Mapping, index)
+	}
+	return vector, nil
+}
+
+/*
+This test runs the official mainnet shuffling vectors of a consensus-spec-tests release extracted to
+CONSENSUS_SPEC_TESTS_DIR, the directory holding its tests folder, and is skipped without one
+*/
+func TestConsensusSpecShuffleVectors(t *testing.T) {
+	dir := os.Getenv("CONSENSUS_SPEC_TESTS_DIR")
+	if dir == "" {
+		t.Skip("CONSENSUS_SPEC_TESTS_DIR is not set")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "tests", "mainnet", "phase0", "shuffling", "core", "shuffle", "pyspec_tests", "*", "mapping.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no shuffling vectors under %s: %v", dir, err)
+	}
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		vector, err := parseMappingYAML(string(body))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if uint64(len(vector.Mapping)) != vector.Count {
+			t.Fatalf("%s: %d mapping entries for count %d", file, len(vector.Mapping), vector.Count)
+		}
+		seed := decodeSeed(t, vector.Seed)
+		list := make([]uint64, vector.Count)
+		for i := range list {
+			list[i] = uint64(i)
+		}
+		ShuffleList(list, seed)
+		for i, expected := range vector.Mapping {
+			if got := ComputeShuffledIndex(uint64(i), vector.Count, seed); got != expected || list[i] != expected {
+				t.Fatalf("%s: index %d shuffled to %d and %d, expected %d", file, i, got, list[i], expected)
+			}
+		}
+	}
+}
+
+func TestParseMappingYAML(t *testing.T) {
+	vector, err := parseMappingYAML("{seed: '0x" + strings.Repeat("ab", 32) + "', count: 3,\n  mapping: [2, 0,\n    1]}\n")
+	if err != nil || vector.Count != 3 || len(vector.Mapping) != 3 || vector.Mapping[0] != 2 || vector.Mapping[2] != 1 {
+		t.Errorf("unexpected vector %+v: %v", vector, err)
+	}
+	empty, err := parseMappingYAML("seed: '0x" + strings.Repeat("00", 32) + "'\ncount: 0\nmapping: []\n")
+	if err != nil || empty.Count != 0 || len(empty.Mapping) != 0 {
+		t.Errorf("unexpected vector %+v: %v", empty, err)
+	}
+}
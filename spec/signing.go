@@ -0,0 +1,34 @@
+package spec
+
+import "crypto/sha256"
+
+// MainnetGenesisValidatorsRoot is the genesis_validators_root of mainnet
+var MainnetGenesisValidatorsRoot = [32]byte{
+	0x4b, 0x36, 0x3d, 0xb9, 0x4e, 0x28, 0x61, 0x20, 0xd7, 0x6e, 0xb9, 0x05, 0x34, 0x0f, 0xdd, 0x4e,
+	0x54, 0xbf, 0xe9, 0xf0, 0x6b, 0xf3, 0x3f, 0xf6, 0xcf, 0x5a, 0xd2, 0x7f, 0x51, 0x1b, 0xfe, 0x95,
+}
+
+/*
+This function is compute_domain from the spec: the domain type followed by the first 28 bytes of the
+fork data root, which binds a signature to one fork of one chain
+*/
+func ComputeDomain(domainType DomainType, forkVersion [4]byte, genesisValidatorsRoot [32]byte) [32]byte {
+	// hash_tree_root(ForkData) with the version padded to a full chunk
+	var forkData [64]byte
+	copy(forkData[:4], forkVersion[:])
+	copy(forkData[32:], genesisValidatorsRoot[:])
+	forkDataRoot := sha256.Sum256(forkData[:])
+
+	var domain [32]byte
+	copy(domain[:4], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// ComputeSigningRoot is compute_signing_root from the spec for an object whose hash tree root is known
+func ComputeSigningRoot(objectRoot [32]byte, domain [32]byte) [32]byte {
+	var signingData [64]byte
+	copy(signingData[:32], objectRoot[:])
+	copy(signingData[32:], domain[:])
+	return sha256.Sum256(signingData[:])
+}
@@ -0,0 +1,34 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestComputeDomainAndSigningRoot(t *testing.T) {
+	// The first four bytes of the fork data root are the fork digest, which names the gossip topics of a fork
+	// on mainnet (/eth2/b5303f2a/beacon_block/ssz_snappy for phase0)
+	for version, digest := range []string{"b5303f2a", "afcaaba0", "4a26c58b", "bba4da96", "6a95a1a9", "ad532ceb"} {
+		forkVersion := [4]byte{byte(version), 0x00, 0x00, 0x00}
+		domain := ComputeDomain(DomainBeaconProposer, forkVersion, MainnetGenesisValidatorsRoot)
+		if got := hex.EncodeToString(domain[4:8]); got != digest {
+			t.Errorf("fork version %d has digest %s, expected %s", version, got, digest)
+		}
+		// hash_tree_root(ForkData) is the hash of the padded version chunk and the genesis validators root
+		forkDataRoot := sha256.Sum256(append(append(forkVersion[:], make([]byte, 28)...), MainnetGenesisValidatorsRoot[:]...))
+		if string(domain[:4]) != string(DomainBeaconProposer[:]) || string(domain[4:]) != string(forkDataRoot[:28]) {
+			t.Errorf("unexpected domain %x for fork version %d", domain, version)
+		}
+	}
+
+	// hash_tree_root(SigningData) is the hash of the object root and the domain
+	domain := ComputeDomain(DomainBeaconProposer, [4]byte{0x05, 0x00, 0x00, 0x00}, MainnetGenesisValidatorsRoot)
+	var objectRoot [32]byte
+	for i := range objectRoot {
+		objectRoot[i] = byte(i)
+	}
+	if signingRoot := ComputeSigningRoot(objectRoot, domain); signingRoot != sha256.Sum256(append(objectRoot[:], domain[:]...)) {
+		t.Errorf("unexpected signing root %x", signingRoot)
+	}
+}
@@ -0,0 +1,135 @@
+package ssz
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/model"
+)
+
+const (
+	signedBlockFixedSize = BytesPerLengthOffset + 96
+	// slot, proposer_index, parent_root and state_root precede the body offset
+	blockBodyOffsetPosition = 8 + 8 + 32 + 32
+	// randao_reveal, eth1_data and graffiti precede the variable fields of the body in every fork
+	bodyAttestationsOffsetPosition = 96 + 72 + 32 + 2*BytesPerLengthOffset
+	attestationDataSize            = 8 + 8 + 32 + 40 + 40
+	attestationFixedSize           = BytesPerLengthOffset + attestationDataSize + 96
+	// electra appends committee_bits, a Bitvector[MAX_COMMITTEES_PER_SLOT]
+	committeeBitsSize           = 64 / 8
+	electraAttestationFixedSize = attestationFixedSize + committeeBitsSize
+)
+
+/*
+This function reports whether a fork uses the EIP-7549 attestation layout, where the committee index
+moved out of the attestation data into committee_bits
+*/
+func IsElectraAttestationFork(fork model.ForkVersion) bool {
+	return fork.AtLeast(model.Electra)
+}
+
+/*
+This function extracts the attestations of an SSZ encoded SignedBeaconBlock. The position of the
+attestation list is the same in every fork, only the attestation layout changed with electra
+*/
+func DecodeBlockAttestations(block []byte, fork model.ForkVersion) ([]Attestation, error) {
+	if _, err := model.ParseForkVersion(string(fork)); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFork, fork)
+	}
+	body, err := blockBody(block)
+	if err != nil {
+		return nil, err
+	}
+	start, err := readOffset(body, bodyAttestationsOffsetPosition)
+	if err != nil {
+		return nil, err
+	}
+	end, err := readOffset(body, bodyAttestationsOffsetPosition+BytesPerLengthOffset)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("%w: attestation list ends before it starts", ErrInvalidOffset)
+	}
+	elements, err := splitVariableList(body[start:end])
+	if err != nil {
+		return nil, err
+	}
+	attestations := make([]Attestation, 0, len(elements))
+	for _, element := range elements {
+		attestation, err := decodeAttestation(element, IsElectraAttestationFork(fork))
+		if err != nil {
+			return nil, err
+		}
+		attestations = append(attestations, attestation)
+	}
+	return attestations, nil
+}
+
+func blockBody(block []byte) ([]byte, error) {
+	if len(block) < signedBlockFixedSize {
+		return nil, ErrTooShort
+	}
+	messageOffset, err := readOffset(block, 0)
+	if err != nil {
+		return nil, err
+	}
+	message := block[messageOffset:]
+	bodyOffset, err := readOffset(message, blockBodyOffsetPosition)
+	if err != nil {
+		return nil, err
+	}
+	return message[bodyOffset:], nil
+}
+
+func decodeAttestation(b []byte, electra bool) (Attestation, error) {
+	var attestation Attestation
+	fixedSize := attestationFixedSize
+	if electra {
+		fixedSize = electraAttestationFixedSize
+	}
+	if len(b) < fixedSize {
+		return attestation, ErrTooShort
+	}
+	bitsOffset, err := readOffset(b, 0)
+	if err != nil {
+		return attestation, err
+	}
+	if bitsOffset != fixedSize {
+		return attestation, fmt.Errorf("%w: aggregation bits at %d", ErrInvalidOffset, bitsOffset)
+	}
+	attestation.Data, err = decodeAttestationData(b[BytesPerLengthOffset : BytesPerLengthOffset+attestationDataSize])
+	if err != nil {
+		return attestation, err
+	}
+	copy(attestation.Signature[:], b[BytesPerLengthOffset+attestationDataSize:attestationFixedSize])
+	if electra {
+		attestation.CommitteeBits = b[attestationFixedSize:electraAttestationFixedSize]
+	}
+	attestation.AggregationBits = b[bitsOffset:]
+	return attestation, nil
+}
+
+func decodeAttestationData(b []byte) (AttestationData, error) {
+	var data AttestationData
+	var err error
+	if data.Slot, err = readUint64(b, 0); err != nil {
+		return data, err
+	}
+	if data.Index, err = readUint64(b, 8); err != nil {
+		return data, err
+	}
+	copy(data.BeaconBlockRoot[:], b[16:48])
+	if data.Source.Epoch, err = readUint64(b, 48); err != nil {
+		return data, err
+	}
+	copy(data.Source.Root[:], b[56:88])
+	if data.Target.Epoch, err = readUint64(b, 88); err != nil {
+		return data, err
+	}
+	copy(data.Target.Root[:], b[96:128])
+	return data, nil
+}
+
+// BlockSlot returns the slot of an SSZ encoded SignedBeaconBlock, which is at the same position in every fork
+func BlockSlot(block []byte) (uint64, error) {
+	return readUint64(block, signedBlockFixedSize)
+}
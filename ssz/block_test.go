@@ -0,0 +1,131 @@
+package ssz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-beacon-chain-indexer/model"
+	"testing"
+)
+
+func offset(n int) []byte {
+	b := make([]byte, BytesPerLengthOffset)
+	binary.LittleEndian.PutUint32(b, uint32(n))
+	return b
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	encoded := make([]byte, 8)
+	binary.LittleEndian.PutUint64(encoded, v)
+	return append(b, encoded...)
+}
+
+func encodeAttestation(a Attestation, electra bool) []byte {
+	fixedSize := attestationFixedSize
+	if electra {
+		fixedSize = electraAttestationFixedSize
+	}
+	b := offset(fixedSize)
+	b = appendUint64(b, a.Data.Slot)
+	b = appendUint64(b, a.Data.Index)
+	b = append(b, a.Data.BeaconBlockRoot[:]...)
+	b = appendUint64(b, a.Data.Source.Epoch)
+	b = append(b, a.Data.Source.Root[:]...)
+	b = appendUint64(b, a.Data.Target.Epoch)
+	b = append(b, a.Data.Target.Root[:]...)
+	b = append(b, a.Signature[:]...)
+	if electra {
+		b = append(b, a.CommitteeBits...)
+	}
+	return append(b, a.AggregationBits...)
+}
+
+/*
+This function lays out a SignedBeaconBlock whose body only has attestations. The fields after the
+attestation list differ per fork but are never read, so a phase0 shaped body is enough
+*/
+func encodeBlock(attestations []Attestation, electra bool) []byte {
+	var list []byte
+	var elements [][]byte
+	for _, a := range attestations {
+		elements = append(elements, encodeAttestation(a, electra))
+	}
+	position := len(elements) * BytesPerLengthOffset
+	for _, element := range elements {
+		list = append(list, offset(position)...)
+		position += len(element)
+	}
+	for _, element := range elements {
+		list = append(list, element...)
+	}
+
+	bodyFixedSize := 96 + 72 + 32 + 5*BytesPerLengthOffset
+	body := make([]byte, 96+72+32)
+	body = append(body, offset(bodyFixedSize)...)
+	body = append(body, offset(bodyFixedSize)...)
+	body = append(body, offset(bodyFixedSize)...)
+	body = append(body, offset(bodyFixedSize+len(list))...)
+	body = append(body, offset(bodyFixedSize+len(list))...)
+	body = append(body, list...)
+
+	message := appendUint64(nil, 321)
+	message = appendUint64(message, 1234)
+	message = append(message, make([]byte, 64)...)
+	message = append(message, offset(len(message)+BytesPerLengthOffset)...)
+	message = append(message, body...)
+
+	block := offset(signedBlockFixedSize)
+	block = append(block, make([]byte, 96)...)
+	return append(block, message...)
+}
+
+func testAttestations(electra bool) []Attestation {
+	first := Attestation{AggregationBits: []byte{0x23}}
+	first.Data.Slot = 320
+	first.Data.Index = 1
+	first.Data.Target.Epoch = 10
+	first.Data.Target.Root[0] = 0xaa
+	first.Signature[95] = 0x01
+	second := Attestation{AggregationBits: []byte{0xff, 0x01}}
+	second.Data.Slot = 320
+	second.Data.Source.Epoch = 9
+	if electra {
+		first.Data.Index = 0
+		first.CommitteeBits = []byte{0x02, 0, 0, 0, 0, 0, 0, 0}
+		second.CommitteeBits = []byte{0x03, 0, 0, 0, 0, 0, 0, 0}
+	}
+	return []Attestation{first, second}
+}
+
+func TestDecodeBlockAttestations(t *testing.T) {
+	for _, fork := range []model.ForkVersion{model.Deneb, model.Electra} {
+		electra := IsElectraAttestationFork(fork)
+		expected := testAttestations(electra)
+		decoded, err := DecodeBlockAttestations(encodeBlock(expected, electra), fork)
+		if err != nil {
+			t.Fatalf("%s: failed to decode block: %v", fork, err)
+		}
+		if len(decoded) != len(expected) {
+			t.Fatalf("%s: expected %d attestations, got %d", fork, len(expected), len(decoded))
+		}
+		for i := range expected {
+			if decoded[i].Data != expected[i].Data || decoded[i].Signature != expected[i].Signature {
+				t.Errorf("%s: attestation %d decoded as %+v", fork, i, decoded[i].Data)
+			}
+			if !bytes.Equal(decoded[i].AggregationBits, expected[i].AggregationBits) || !bytes.Equal(decoded[i].CommitteeBits, expected[i].CommitteeBits) {
+				t.Errorf("%s: attestation %d has bits %x and committee bits %x", fork, i, decoded[i].AggregationBits, decoded[i].CommitteeBits)
+			}
+		}
+	}
+}
+
+func TestDecodeBlockAttestationsWrongLayout(t *testing.T) {
+	// An electra block read with the deneb layout must fail instead of returning shifted fields
+	_, err := DecodeBlockAttestations(encodeBlock(testAttestations(true), true), model.Deneb)
+	if err == nil {
+		t.Errorf("expected the electra layout to be rejected as deneb")
+	}
+	_, err = DecodeBlockAttestations(nil, model.ForkVersion("gloas"))
+	if err == nil {
+		t.Errorf("expected an unknown fork to be rejected")
+	}
+}
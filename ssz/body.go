@@ -0,0 +1,181 @@
+package ssz
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/model"
+)
+
+// Mainnet preset limits of the block body lists
+const (
+	maxValidatorsPerCommittee  = 2048
+	maxCommitteesPerSlot       = 64
+	maxProposerSlashings       = 16
+	maxAttesterSlashings       = 2
+	maxAttestations            = 128
+	maxDeposits                = 16
+	maxVoluntaryExits          = 16
+	syncCommitteeSize          = 512
+	maxBytesPerTransaction     = 1 << 30
+	maxTransactionsPerPayload  = 1 << 20
+	maxExtraDataBytes          = 32
+	maxWithdrawalsPerPayload   = 16
+	maxBLSToExecutionChanges   = 16
+	maxBlobCommitmentsPerBlock = 4096
+	depositContractTreeDepth   = 32
+
+	maxAttesterSlashingsElectra     = 1
+	maxAttestationsElectra          = 8
+	maxDepositRequestsPerPayload    = 8192
+	maxWithdrawalRequestsPerPayload = 16
+	maxConsolidationRequests        = 2
+)
+
+var (
+	bytes20  = bytesVector{size: 20}
+	bytes32  = bytesVector{size: 32}
+	bytes48  = bytesVector{size: 48}
+	bytes96  = bytesVector{size: 96}
+	bytes256 = bytesVector{size: 256}
+	uint64T  = uint64Type{}
+
+	checkpointType      = container{fields: []sszType{uint64T, bytes32}}
+	attestationDataType = container{fields: []sszType{uint64T, uint64T, bytes32, checkpointType, checkpointType}}
+	eth1DataType        = container{fields: []sszType{bytes32, uint64T, bytes32}}
+	headerType          = container{fields: []sszType{uint64T, uint64T, bytes32, bytes32, bytes32}}
+	signedHeaderType    = container{fields: []sszType{headerType, bytes96}}
+	proposerSlashing    = container{fields: []sszType{signedHeaderType, signedHeaderType}}
+	depositType         = container{fields: []sszType{
+		vector{elem: bytes32, length: depositContractTreeDepth + 1},
+		container{fields: []sszType{bytes48, bytes32, uint64T, bytes96}},
+	}}
+	signedVoluntaryExit = container{fields: []sszType{container{fields: []sszType{uint64T, uint64T}}, bytes96}}
+	syncAggregateType   = container{fields: []sszType{bitvector{bits: syncCommitteeSize}, bytes96}}
+	signedBLSChange     = container{fields: []sszType{container{fields: []sszType{uint64T, bytes48, bytes20}}, bytes96}}
+	withdrawalType      = container{fields: []sszType{uint64T, uint64T, bytes20, uint64T}}
+
+	phase0Attestation  = container{fields: []sszType{bitlistType{limit: maxValidatorsPerCommittee}, attestationDataType, bytes96}}
+	electraAttestation = container{fields: []sszType{
+		bitlistType{limit: maxValidatorsPerCommittee * maxCommitteesPerSlot}, attestationDataType, bytes96, bitvector{bits: maxCommitteesPerSlot},
+	}}
+	phase0AttesterSlashing  = attesterSlashing(maxValidatorsPerCommittee)
+	electraAttesterSlashing = attesterSlashing(maxValidatorsPerCommittee * maxCommitteesPerSlot)
+
+	executionRequestsType = container{fields: []sszType{
+		list{elem: container{fields: []sszType{bytes48, bytes32, uint64T, bytes96, uint64T}}, limit: maxDepositRequestsPerPayload},
+		list{elem: container{fields: []sszType{bytes20, bytes48, uint64T}}, limit: maxWithdrawalRequestsPerPayload},
+		list{elem: container{fields: []sszType{bytes20, bytes48, bytes48}}, limit: maxConsolidationRequests},
+	}}
+)
+
+func attesterSlashing(maxIndices int) container {
+	indexed := container{fields: []sszType{list{elem: uint64T, limit: maxIndices}, attestationDataType, bytes96}}
+	return container{fields: []sszType{indexed, indexed}}
+}
+
+/*
+This function returns the execution payload of bellatrix with the fields later forks appended to it
+*/
+func executionPayload(fork model.ForkVersion) container {
+	fields := []sszType{
+		bytes32, bytes20, bytes32, bytes32, bytes256, bytes32, // parent_hash to prev_randao
+		uint64T, uint64T, uint64T, uint64T, // block_number, gas_limit, gas_used, timestamp
+		byteList{limit: maxExtraDataBytes}, bytes32, bytes32, // extra_data, base_fee_per_gas, block_hash
+		list{elem: byteList{limit: maxBytesPerTransaction}, limit: maxTransactionsPerPayload},
+	}
+	if fork.AtLeast(model.Capella) {
+		fields = append(fields, list{elem: withdrawalType, limit: maxWithdrawalsPerPayload})
+	}
+	if fork.AtLeast(model.Deneb) {
+		fields = append(fields, uint64T, uint64T) // blob_gas_used, excess_blob_gas
+	}
+	return container{fields: fields}
+}
+
+/*
+This function returns the BeaconBlockBody type of a fork. Every fork appends its fields to the body of the
+one before, electra also lowered the attestation limits and changed the attestation layout
+*/
+func blockBodyType(fork model.ForkVersion) (container, error) {
+	slashings, attestations := phase0AttesterSlashing, phase0Attestation
+	maxSlashings, maxAttestationCount := maxAttesterSlashings, maxAttestations
+	if IsElectraAttestationFork(fork) {
+		slashings, attestations = electraAttesterSlashing, electraAttestation
+		maxSlashings, maxAttestationCount = maxAttesterSlashingsElectra, maxAttestationsElectra
+	}
+	fields := []sszType{
+		bytes96, eth1DataType, bytes32,
+		list{elem: proposerSlashing, limit: maxProposerSlashings},
+		list{elem: slashings, limit: maxSlashings},
+		list{elem: attestations, limit: maxAttestationCount},
+		list{elem: depositType, limit: maxDeposits},
+		list{elem: signedVoluntaryExit, limit: maxVoluntaryExits},
+	}
+	switch fork {
+	case model.Phase0:
+		return container{fields: fields}, nil
+	case model.Altair:
+		return container{fields: append(fields, syncAggregateType)}, nil
+	case model.Bellatrix:
+		return container{fields: append(fields, syncAggregateType, executionPayload(fork))}, nil
+	case model.Capella:
+		return container{fields: append(fields, syncAggregateType, executionPayload(fork),
+			list{elem: signedBLSChange, limit: maxBLSToExecutionChanges})}, nil
+	case model.Deneb:
+		return container{fields: append(fields, syncAggregateType, executionPayload(fork),
+			list{elem: signedBLSChange, limit: maxBLSToExecutionChanges},
+			list{elem: bytes48, limit: maxBlobCommitmentsPerBlock})}, nil
+	case model.Electra, model.Fulu:
+		return container{fields: append(fields, syncAggregateType, executionPayload(model.Deneb),
+			list{elem: signedBLSChange, limit: maxBLSToExecutionChanges},
+			list{elem: bytes48, limit: maxBlobCommitmentsPerBlock},
+			executionRequestsType)}, nil
+	default:
+		return container{}, fmt.Errorf("%w: %q", ErrUnsupportedFork, fork)
+	}
+}
+
+/*
+This function decodes the header of an SSZ encoded SignedBeaconBlock of the given fork, hashing the body
+to get the body root, and returns it with the proposer signature. The hash tree root of the returned
+header is the block root
+*/
+func DecodeSignedBlockHeader(block []byte, fork model.ForkVersion) (BeaconBlockHeader, [96]byte, error) {
+	var header BeaconBlockHeader
+	var signature [96]byte
+	bodyType, err := blockBodyType(fork)
+	if err != nil {
+		return header, signature, err
+	}
+	if len(block) < signedBlockFixedSize {
+		return header, signature, ErrTooShort
+	}
+	messageOffset, err := readOffset(block, 0)
+	if err != nil {
+		return header, signature, err
+	}
+	if messageOffset != signedBlockFixedSize {
+		return header, signature, fmt.Errorf("%w: block message at %d", ErrInvalidOffset, messageOffset)
+	}
+	copy(signature[:], block[BytesPerLengthOffset:signedBlockFixedSize])
+	message := block[messageOffset:]
+	if header.Slot, err = readUint64(message, 0); err != nil {
+		return header, signature, err
+	}
+	if header.ProposerIndex, err = readUint64(message, 8); err != nil {
+		return header, signature, err
+	}
+	copy(header.ParentRoot[:], message[16:48])
+	copy(header.StateRoot[:], message[48:80])
+	bodyOffset, err := readOffset(message, blockBodyOffsetPosition)
+	if err != nil {
+		return header, signature, err
+	}
+	if bodyOffset != blockBodyOffsetPosition+BytesPerLengthOffset {
+		return header, signature, fmt.Errorf("%w: block body at %d", ErrInvalidOffset, bodyOffset)
+	}
+	header.BodyRoot, err = bodyType.hashTreeRoot(message[bodyOffset:])
+	if err != nil {
+		return header, signature, fmt.Errorf("hashing %s block body: %w", fork, err)
+	}
+	return header, signature, nil
+}
@@ -0,0 +1,50 @@
+package ssz
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"go-beacon-chain-indexer/model"
+	"os"
+	"testing"
+)
+
+/*
+The blocks in testdata/blocks are synthetic, not mainnet blocks: all of them are for slot 12345 and proposer
+678 and fill every field of the body of their fork. The expected roots are the ones zrnt v0.34.1 computes for
+them, prysm v5.0.0 computes the same roots for every fork up to deneb, which is the last one it has. Real
+blocks of every fork are checked by TestMainnetVersionedBlocks in the service package
+*/
+func TestDecodeSignedBlockHeader(t *testing.T) {
+	body, err := os.ReadFile("testdata/blocks/roots.json")
+	if err != nil {
+		t.Fatalf("failed to read roots: %v", err)
+	}
+	var roots map[string]struct {
+		BodyRoot  string `json:"body_root"`
+		BlockRoot string `json:"block_root"`
+	}
+	if err = json.Unmarshal(body, &roots); err != nil {
+		t.Fatalf("failed to decode roots: %v", err)
+	}
+	for fork, expected := range roots {
+		block, err := os.ReadFile("testdata/blocks/" + fork + ".ssz")
+		if err != nil {
+			t.Fatalf("failed to read %s block: %v", fork, err)
+		}
+		header, _, err := DecodeSignedBlockHeader(block, model.ForkVersion(fork))
+		if err != nil {
+			t.Errorf("%s: %v", fork, err)
+			continue
+		}
+		if header.Slot != 12345 || header.ProposerIndex != 678 {
+			t.Errorf("%s: unexpected slot %d and proposer %d", fork, header.Slot, header.ProposerIndex)
+		}
+		if got := "0x" + hex.EncodeToString(header.BodyRoot[:]); got != expected.BodyRoot {
+			t.Errorf("%s: body root %s, expected %s", fork, got, expected.BodyRoot)
+		}
+		root := header.HashTreeRoot()
+		if got := "0x" + hex.EncodeToString(root[:]); got != expected.BlockRoot {
+			t.Errorf("%s: block root %s, expected %s", fork, got, expected.BlockRoot)
+		}
+	}
+}
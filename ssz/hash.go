@@ -0,0 +1,79 @@
+package ssz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+/*
+BeaconBlockHeader is the header a block root commits to: the block root is the hash tree root of the header
+*/
+type BeaconBlockHeader struct {
+	Slot          uint64
+	ProposerIndex uint64
+	ParentRoot    [32]byte
+	StateRoot     [32]byte
+	BodyRoot      [32]byte
+}
+
+// HashTreeRoot returns the root of the header, which is the block root
+func (h *BeaconBlockHeader) HashTreeRoot() [32]byte {
+	return Merkleize([][32]byte{
+		uint64Chunk(h.Slot),
+		uint64Chunk(h.ProposerIndex),
+		h.ParentRoot,
+		h.StateRoot,
+		h.BodyRoot,
+	})
+}
+
+/*
+This function merkleizes the chunks as the SSZ spec does, padding them with zero chunks up to the next
+power of two. An empty list of chunks merkleizes to the zero chunk
+*/
+func Merkleize(chunks [][32]byte) [32]byte {
+	return merkleizeLimit(chunks, len(chunks))
+}
+
+/*
+This function merkleizes the chunks into a tree sized for limit chunks. Missing chunks are zero, so only
+the zero hash of each depth has to be computed for the padding
+*/
+func merkleizeLimit(chunks [][32]byte, limit int) [32]byte {
+	depth := 0
+	for 1<<uint(depth) < limit {
+		depth++
+	}
+	layer := make([][32]byte, len(chunks))
+	copy(layer, chunks)
+	var zero [32]byte
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zero)
+		}
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = HashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+		zero = HashPair(zero, zero)
+	}
+	if len(layer) == 0 {
+		return zero
+	}
+	return layer[0]
+}
+
+// HashPair returns the hash of two concatenated chunks, a node of the merkle tree
+func HashPair(a [32]byte, b [32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], a[:])
+	copy(buf[32:], b[:])
+	return sha256.Sum256(buf[:])
+}
+
+func uint64Chunk(v uint64) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:], v)
+	return chunk
+}
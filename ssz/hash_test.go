@@ -0,0 +1,41 @@
+package ssz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeTestRoot(t *testing.T, s string) [32]byte {
+	t.Helper()
+	var root [32]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(root) {
+		t.Fatalf("invalid root %s", s)
+	}
+	copy(root[:], b)
+	return root
+}
+
+func TestBeaconBlockHeaderHashTreeRoot(t *testing.T) {
+	// The five fields of an empty header pad to eight zero chunks, so its root is the zero hash of depth 3
+	var zeroHash [32]byte
+	for depth := 0; depth < 3; depth++ {
+		zeroHash = sha256.Sum256(append(zeroHash[:], zeroHash[:]...))
+	}
+	var empty BeaconBlockHeader
+	if root := empty.HashTreeRoot(); root != zeroHash {
+		t.Errorf("unexpected root of the empty header: %x", root)
+	}
+
+	// The genesis block of mainnet, as served by /eth/v1/beacon/headers/0 of any mainnet beacon node: the state
+	// root is the root of the genesis state and the body root the root of an empty phase0 body
+	genesis := BeaconBlockHeader{
+		StateRoot: decodeTestRoot(t, "7e76880eb67bbdc86250aa578958e9d0675e64e714337855204fb5abaaf82c2b"),
+		BodyRoot:  decodeTestRoot(t, "ccb62460692be0ec813b56be97f68a82cf57abc102e27bf49ebf4190ff22eedd"),
+	}
+	root := genesis.HashTreeRoot()
+	if got := hex.EncodeToString(root[:]); got != "4d611d5b93fdab69013a7f0a2f961caca0c853f87cfe9595fe50038163079360" {
+		t.Errorf("unexpected root of the mainnet genesis block: %s", got)
+	}
+}
@@ -0,0 +1,283 @@
+package ssz
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+/*
+sszType describes how one SSZ type is laid out and merkleized, enough to compute the hash tree root of an
+encoded value without decoding it into Go types. fixedSize is 0 for variable size types
+*/
+type sszType interface {
+	fixedSize() int
+	hashTreeRoot(b []byte) ([32]byte, error)
+}
+
+type uint64Type struct{}
+
+func (uint64Type) fixedSize() int { return 8 }
+
+func (uint64Type) hashTreeRoot(b []byte) ([32]byte, error) {
+	var chunk [32]byte
+	if len(b) != 8 {
+		return chunk, ErrTooShort
+	}
+	copy(chunk[:], b)
+	return chunk, nil
+}
+
+// byteType is a uint8 or a boolean
+type byteType struct{}
+
+func (byteType) fixedSize() int { return 1 }
+
+func (byteType) hashTreeRoot(b []byte) ([32]byte, error) {
+	var chunk [32]byte
+	if len(b) != 1 {
+		return chunk, ErrTooShort
+	}
+	chunk[0] = b[0]
+	return chunk, nil
+}
+
+// basicSize returns the size of a basic type, whose values are packed into chunks, and 0 for any other type
+func basicSize(t sszType) int {
+	switch t.(type) {
+	case uint64Type, byteType:
+		return t.fixedSize()
+	default:
+		return 0
+	}
+}
+
+// bytesVector is a fixed number of bytes: roots, keys, signatures, addresses and uint256 values
+type bytesVector struct {
+	size int
+}
+
+func (t bytesVector) fixedSize() int { return t.size }
+
+func (t bytesVector) hashTreeRoot(b []byte) ([32]byte, error) {
+	if len(b) != t.size {
+		return [32]byte{}, fmt.Errorf("%w: expected %d bytes, got %d", ErrTooShort, t.size, len(b))
+	}
+	chunks := pack(b)
+	return merkleizeLimit(chunks, len(chunks)), nil
+}
+
+type byteList struct {
+	limit int
+}
+
+func (byteList) fixedSize() int { return 0 }
+
+func (t byteList) hashTreeRoot(b []byte) ([32]byte, error) {
+	if len(b) > t.limit {
+		return [32]byte{}, fmt.Errorf("ssz: byte list of %d bytes exceeds its limit of %d", len(b), t.limit)
+	}
+	return mixInLength(merkleizeLimit(pack(b), (t.limit+31)/32), uint64(len(b))), nil
+}
+
+type bitvector struct {
+	bits int
+}
+
+func (t bitvector) fixedSize() int { return (t.bits + 7) / 8 }
+
+func (t bitvector) hashTreeRoot(b []byte) ([32]byte, error) {
+	if len(b) != t.fixedSize() {
+		return [32]byte{}, ErrTooShort
+	}
+	return merkleizeLimit(pack(b), (t.bits+255)/256), nil
+}
+
+type bitlistType struct {
+	limit int
+}
+
+func (bitlistType) fixedSize() int { return 0 }
+
+func (t bitlistType) hashTreeRoot(b []byte) ([32]byte, error) {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		return [32]byte{}, fmt.Errorf("ssz: bitlist without a delimiter bit")
+	}
+	// The delimiter is the highest set bit of the last byte, it is not part of the data
+	length := (len(b)-1)*8 + bits.Len8(b[len(b)-1]) - 1
+	if length > t.limit {
+		return [32]byte{}, fmt.Errorf("ssz: bitlist of %d bits exceeds its limit of %d", length, t.limit)
+	}
+	data := make([]byte, len(b))
+	copy(data, b)
+	data[len(data)-1] &^= 1 << uint(length%8)
+	if length%8 == 0 {
+		data = data[:len(data)-1]
+	}
+	return mixInLength(merkleizeLimit(pack(data), (t.limit+255)/256), uint64(length)), nil
+}
+
+// vector is a fixed number of elements, basic ones are packed into chunks like the elements of a list
+type vector struct {
+	elem   sszType
+	length int
+}
+
+func (t vector) fixedSize() int { return t.elem.fixedSize() * t.length }
+
+func (t vector) hashTreeRoot(b []byte) ([32]byte, error) {
+	if len(b) != t.fixedSize() {
+		return [32]byte{}, ErrTooShort
+	}
+	if size := basicSize(t.elem); size > 0 {
+		return merkleizeLimit(pack(b), (t.length*size+31)/32), nil
+	}
+	size := t.elem.fixedSize()
+	roots := make([][32]byte, 0, t.length)
+	for i := 0; i < t.length; i++ {
+		root, err := t.elem.hashTreeRoot(b[i*size : (i+1)*size])
+		if err != nil {
+			return [32]byte{}, err
+		}
+		roots = append(roots, root)
+	}
+	return merkleizeLimit(roots, t.length), nil
+}
+
+type list struct {
+	elem  sszType
+	limit int
+}
+
+func (list) fixedSize() int { return 0 }
+
+func (t list) hashTreeRoot(b []byte) ([32]byte, error) {
+	if size := basicSize(t.elem); size > 0 {
+		if len(b)%size != 0 || len(b)/size > t.limit {
+			return [32]byte{}, fmt.Errorf("ssz: invalid list of %d bytes of %d byte values", len(b), size)
+		}
+		return mixInLength(merkleizeLimit(pack(b), (t.limit*size+31)/32), uint64(len(b)/size)), nil
+	}
+	elements, err := t.elements(b)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if len(elements) > t.limit {
+		return [32]byte{}, fmt.Errorf("ssz: list of %d elements exceeds its limit of %d", len(elements), t.limit)
+	}
+	roots := make([][32]byte, 0, len(elements))
+	for _, element := range elements {
+		root, err := t.elem.hashTreeRoot(element)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		roots = append(roots, root)
+	}
+	return mixInLength(merkleizeLimit(roots, t.limit), uint64(len(elements))), nil
+}
+
+func (t list) elements(b []byte) ([][]byte, error) {
+	size := t.elem.fixedSize()
+	if size == 0 {
+		return splitVariableList(b)
+	}
+	if len(b)%size != 0 {
+		return nil, fmt.Errorf("%w: list of %d bytes does not divide into elements of %d", ErrInvalidOffset, len(b), size)
+	}
+	elements := make([][]byte, 0, len(b)/size)
+	for i := 0; i < len(b); i += size {
+		elements = append(elements, b[i:i+size])
+	}
+	return elements, nil
+}
+
+type container struct {
+	fields []sszType
+}
+
+func (t container) fixedSize() int {
+	size := 0
+	for _, field := range t.fields {
+		fieldSize := field.fixedSize()
+		if fieldSize == 0 {
+			return 0
+		}
+		size += fieldSize
+	}
+	return size
+}
+
+func (t container) hashTreeRoot(b []byte) ([32]byte, error) {
+	values, err := t.split(b)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	roots := make([][32]byte, 0, len(t.fields))
+	for i, field := range t.fields {
+		root, err := field.hashTreeRoot(values[i])
+		if err != nil {
+			return [32]byte{}, err
+		}
+		roots = append(roots, root)
+	}
+	return merkleizeLimit(roots, len(roots)), nil
+}
+
+/*
+This method splits an encoded container into the encoding of each of its fields. Fixed size fields are
+laid out in order, variable size ones are replaced by an offset and appended after the fixed part
+*/
+func (t container) split(b []byte) ([][]byte, error) {
+	values := make([][]byte, len(t.fields))
+	var variable []int
+	var offsets []int
+	position := 0
+	for i, field := range t.fields {
+		size := field.fixedSize()
+		if size == 0 {
+			size = BytesPerLengthOffset
+			offset, err := readOffset(b, position)
+			if err != nil {
+				return nil, err
+			}
+			variable = append(variable, i)
+			offsets = append(offsets, offset)
+		} else {
+			if position+size > len(b) {
+				return nil, ErrTooShort
+			}
+			values[i] = b[position : position+size]
+		}
+		position += size
+	}
+	if len(variable) == 0 && position != len(b) {
+		return nil, fmt.Errorf("%w: container of %d bytes has %d trailing bytes", ErrInvalidOffset, len(b), len(b)-position)
+	}
+	for j, i := range variable {
+		start := offsets[j]
+		end := len(b)
+		if j+1 < len(offsets) {
+			end = offsets[j+1]
+		}
+		if (j == 0 && start != position) || start > end || end > len(b) {
+			return nil, fmt.Errorf("%w: field %d spans %d to %d", ErrInvalidOffset, i, start, end)
+		}
+		values[i] = b[start:end]
+	}
+	return values, nil
+}
+
+// pack splits bytes into 32 byte chunks, zero padding the last one
+func pack(b []byte) [][32]byte {
+	chunks := make([][32]byte, (len(b)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*32:])
+	}
+	return chunks
+}
+
+func mixInLength(root [32]byte, length uint64) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:], length)
+	return HashPair(root, chunk)
+}
@@ -0,0 +1,92 @@
+package ssz
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	BytesPerLengthOffset = 4
+	FarFutureEpoch       = ^uint64(0)
+)
+
+var (
+	ErrTooShort        = errors.New("ssz: input is too short")
+	ErrInvalidOffset   = errors.New("ssz: invalid offset")
+	ErrUnsupportedFork = errors.New("ssz: fork is not supported")
+)
+
+type Checkpoint struct {
+	Epoch uint64
+	Root  [32]byte
+}
+
+type AttestationData struct {
+	Slot            uint64
+	Index           uint64
+	BeaconBlockRoot [32]byte
+	Source          Checkpoint
+	Target          Checkpoint
+}
+
+type Attestation struct {
+	AggregationBits []byte
+	Data            AttestationData
+	Signature       [96]byte
+	// Only present from electra on (EIP-7549), nil before
+	CommitteeBits []byte
+}
+
+func readUint64(b []byte, pos int) (uint64, error) {
+	if pos < 0 || pos+8 > len(b) {
+		return 0, ErrTooShort
+	}
+	return binary.LittleEndian.Uint64(b[pos : pos+8]), nil
+}
+
+func readOffset(b []byte, pos int) (int, error) {
+	if pos < 0 || pos+BytesPerLengthOffset > len(b) {
+		return 0, ErrTooShort
+	}
+	offset := int(binary.LittleEndian.Uint32(b[pos : pos+BytesPerLengthOffset]))
+	if offset > len(b) {
+		return 0, fmt.Errorf("%w: %d is past the end of %d bytes", ErrInvalidOffset, offset, len(b))
+	}
+	return offset, nil
+}
+
+/*
+This function splits a list of variable size elements into its elements. Such a list starts with one
+offset per element, so the first offset also tells how many elements there are
+*/
+func splitVariableList(b []byte) ([][]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	first, err := readOffset(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	if first%BytesPerLengthOffset != 0 || first == 0 {
+		return nil, fmt.Errorf("%w: first offset %d", ErrInvalidOffset, first)
+	}
+	count := first / BytesPerLengthOffset
+	elements := make([][]byte, count)
+	start := first
+	for i := 0; i < count; i++ {
+		end := len(b)
+		if i+1 < count {
+			end, err = readOffset(b, (i+1)*BytesPerLengthOffset)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("%w: offsets are not increasing", ErrInvalidOffset)
+		}
+		elements[i] = b[start:end]
+		start = end
+	}
+	return elements, nil
+}
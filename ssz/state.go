@@ -0,0 +1,213 @@
+package ssz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	ValidatorSize = 48 + 32 + 8 + 1 + 4*8
+
+	stateSlotPosition = 8 + 32
+	// Every field of BeaconState up to the balances is laid out the same way in every fork:
+	// genesis_time, genesis_validators_root, slot, fork, latest_block_header, block_roots,
+	// state_roots, historical_roots (offset), eth1_data, eth1_data_votes (offset), eth1_deposit_index
+	stateValidatorsOffsetPosition = 8 + 32 + 8 + 16 + 112 + 8192*32 + 8192*32 + BytesPerLengthOffset + 72 + BytesPerLengthOffset + 8
+	stateBalancesOffsetPosition   = stateValidatorsOffsetPosition + BytesPerLengthOffset
+	stateBlockRootsPosition       = 8 + 32 + 8 + 16 + 112
+	stateRandaoMixesPosition      = stateBalancesOffsetPosition + BytesPerLengthOffset
+
+	slotsPerEpoch             = 32
+	slotsPerHistoricalRoot    = 8192
+	epochsPerHistoricalVector = 65536
+)
+
+type Validator struct {
+	Pubkey                     [48]byte
+	WithdrawalCredentials      [32]byte
+	EffectiveBalance           uint64
+	Slashed                    bool
+	ActivationEligibilityEpoch uint64
+	ActivationEpoch            uint64
+	ExitEpoch                  uint64
+	WithdrawableEpoch          uint64
+}
+
+// IsActive reports whether the validator is active in the given epoch
+func (v *Validator) IsActive(epoch uint64) bool {
+	return v.ActivationEpoch <= epoch && epoch < v.ExitEpoch
+}
+
+func StateSlot(state []byte) (uint64, error) {
+	return readUint64(state, stateSlotPosition)
+}
+
+// StateGenesisValidatorsRoot returns the genesis validators root of an SSZ encoded BeaconState
+func StateGenesisValidatorsRoot(state []byte) ([32]byte, error) {
+	var root [32]byte
+	if len(state) < stateSlotPosition {
+		return root, ErrTooShort
+	}
+	copy(root[:], state[8:stateSlotPosition])
+	return root, nil
+}
+
+/*
+This function returns the validator registry of an SSZ encoded BeaconState
+*/
+func StateValidators(state []byte) ([]Validator, error) {
+	registry, err := NewValidatorRegistryReader(bytes.NewReader(state))
+	if err != nil {
+		return nil, err
+	}
+	validators := make([]Validator, 0, registry.Count)
+	for {
+		v, err := registry.Next()
+		if err == io.EOF {
+			return validators, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, *v)
+	}
+}
+
+/*
+ValidatorRegistryReader walks the validator registry of an SSZ encoded BeaconState as it is read from
+a stream. Only one validator is held in memory at a time and nothing after the registry is ever read
+*/
+type ValidatorRegistryReader struct {
+	Slot  uint64
+	Count int
+
+	reader *bufio.Reader
+	record []byte
+	read   int
+}
+
+func NewValidatorRegistryReader(r io.Reader) (*ValidatorRegistryReader, error) {
+	reader := bufio.NewReaderSize(r, 1<<16)
+	prefix := make([]byte, stateBalancesOffsetPosition+BytesPerLengthOffset)
+	_, err := io.ReadFull(reader, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("reading state prefix: %w", err)
+	}
+	return newValidatorRegistryReader(reader, prefix)
+}
+
+/*
+StateHistory holds the block roots and randao mixes of a BeaconState, the recent history the state keeps
+of the chain it was built on
+*/
+type StateHistory struct {
+	Slot                  uint64
+	GenesisValidatorsRoot [32]byte
+	blockRoots            []byte
+	randaoMixes           []byte
+}
+
+/*
+This function reads the block roots and randao mixes of an SSZ encoded BeaconState as it is read from a
+stream, and returns them with a reader for the validator registry that follows. Both histories are part of
+the fixed size prefix of the state, so they are read before the registry
+*/
+func NewStateHistoryReader(r io.Reader) (*StateHistory, *ValidatorRegistryReader, error) {
+	reader := bufio.NewReaderSize(r, 1<<16)
+	prefix := make([]byte, stateRandaoMixesPosition+epochsPerHistoricalVector*32)
+	_, err := io.ReadFull(reader, prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading state prefix: %w", err)
+	}
+	history := &StateHistory{
+		Slot:        binary.LittleEndian.Uint64(prefix[stateSlotPosition:]),
+		blockRoots:  prefix[stateBlockRootsPosition : stateBlockRootsPosition+slotsPerHistoricalRoot*32],
+		randaoMixes: prefix[stateRandaoMixesPosition:],
+	}
+	copy(history.GenesisValidatorsRoot[:], prefix[8:stateSlotPosition])
+	registry, err := newValidatorRegistryReader(reader, prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return history, registry, nil
+}
+
+/*
+This method is get_block_root_at_slot from the spec: the root of the block at the slot, or of the last block
+before it when the slot was missed. Only the SLOTS_PER_HISTORICAL_ROOT slots before the state are kept
+*/
+func (h *StateHistory) BlockRoot(slot uint64) ([32]byte, error) {
+	var root [32]byte
+	if slot >= h.Slot || slot+slotsPerHistoricalRoot < h.Slot {
+		return root, fmt.Errorf("ssz: the state at slot %d has no block root for slot %d", h.Slot, slot)
+	}
+	position := (slot % slotsPerHistoricalRoot) * 32
+	copy(root[:], h.blockRoots[position:position+32])
+	return root, nil
+}
+
+/*
+This method returns the randao mix of a past epoch, which no longer changes once the epoch is over. Only the
+EPOCHS_PER_HISTORICAL_VECTOR epochs up to the state are kept. Epochs past the state are the entries the
+seeds of the first epochs wrap around to, which still hold the genesis mix until the vector fills up
+*/
+func (h *StateHistory) RandaoMix(epoch uint64) ([32]byte, error) {
+	var mix [32]byte
+	stateEpoch := h.Slot / slotsPerEpoch
+	neverWritten := epoch > stateEpoch && epoch < epochsPerHistoricalVector
+	if !neverWritten && (epoch > stateEpoch || epoch+epochsPerHistoricalVector <= stateEpoch) {
+		return mix, fmt.Errorf("ssz: the state at slot %d has no randao mix for epoch %d", h.Slot, epoch)
+	}
+	position := (epoch % epochsPerHistoricalVector) * 32
+	copy(mix[:], h.randaoMixes[position:position+32])
+	return mix, nil
+}
+
+func newValidatorRegistryReader(reader *bufio.Reader, prefix []byte) (*ValidatorRegistryReader, error) {
+	start := int(binary.LittleEndian.Uint32(prefix[stateValidatorsOffsetPosition:]))
+	end := int(binary.LittleEndian.Uint32(prefix[stateBalancesOffsetPosition:]))
+	if start < len(prefix) || end < start || (end-start)%ValidatorSize != 0 {
+		return nil, fmt.Errorf("%w: validator registry spans %d to %d", ErrInvalidOffset, start, end)
+	}
+	_, err := reader.Discard(start - len(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("skipping to the validator registry: %w", err)
+	}
+	return &ValidatorRegistryReader{
+		Slot:   binary.LittleEndian.Uint64(prefix[stateSlotPosition:]),
+		Count:  (end - start) / ValidatorSize,
+		reader: reader,
+		record: make([]byte, ValidatorSize),
+	}, nil
+}
+
+// Next returns the next validator of the registry, or io.EOF once all of them have been read
+func (r *ValidatorRegistryReader) Next() (*Validator, error) {
+	if r.read == r.Count {
+		return nil, io.EOF
+	}
+	_, err := io.ReadFull(r.reader, r.record)
+	if err != nil {
+		return nil, fmt.Errorf("reading validator %d: %w", r.read, err)
+	}
+	r.read++
+	v := DecodeValidator(r.record)
+	return &v, nil
+}
+
+// DecodeValidator decodes a single Validator, b must be exactly ValidatorSize bytes long
+func DecodeValidator(b []byte) Validator {
+	var v Validator
+	copy(v.Pubkey[:], b[0:48])
+	copy(v.WithdrawalCredentials[:], b[48:80])
+	v.EffectiveBalance = binary.LittleEndian.Uint64(b[80:88])
+	v.Slashed = b[88] == 1
+	v.ActivationEligibilityEpoch = binary.LittleEndian.Uint64(b[89:97])
+	v.ActivationEpoch = binary.LittleEndian.Uint64(b[97:105])
+	v.ExitEpoch = binary.LittleEndian.Uint64(b[105:113])
+	v.WithdrawableEpoch = binary.LittleEndian.Uint64(b[113:121])
+	return v
+}
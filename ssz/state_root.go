@@ -0,0 +1,118 @@
+package ssz
+
+import (
+	"fmt"
+	"go-beacon-chain-indexer/model"
+)
+
+// Mainnet preset limits of the BeaconState lists and vectors
+const (
+	historicalRootsLimit           = 1 << 24
+	validatorRegistryLimit         = 1 << 40
+	eth1DataVotesLimit             = 64 * slotsPerEpoch
+	epochsPerSlashingsVector       = 8192
+	pendingDepositsLimit           = 1 << 27
+	pendingPartialWithdrawalsLimit = 1 << 27
+	pendingConsolidationsLimit     = 1 << 18
+	proposerLookaheadSlots         = 2 * slotsPerEpoch
+	justificationBitsLength        = 4
+	maxPendingAttestationsState    = maxAttestations * slotsPerEpoch
+)
+
+var (
+	forkType               = container{fields: []sszType{bytesVector{size: 4}, bytesVector{size: 4}, uint64T}}
+	validatorType          = container{fields: []sszType{bytes48, bytes32, uint64T, byteType{}, uint64T, uint64T, uint64T, uint64T}}
+	pendingAttestationType = container{fields: []sszType{bitlistType{limit: maxValidatorsPerCommittee}, attestationDataType, uint64T, uint64T}}
+	syncCommitteeType      = container{fields: []sszType{vector{elem: bytes48, length: syncCommitteeSize}, bytes48}}
+)
+
+/*
+This function returns the execution payload header of bellatrix with the fields later forks appended to it,
+the transactions and withdrawals are replaced by their roots
+*/
+func executionPayloadHeader(fork model.ForkVersion) container {
+	fields := []sszType{
+		bytes32, bytes20, bytes32, bytes32, bytes256, bytes32, // parent_hash to prev_randao
+		uint64T, uint64T, uint64T, uint64T, // block_number, gas_limit, gas_used, timestamp
+		byteList{limit: maxExtraDataBytes}, bytes32, bytes32, // extra_data, base_fee_per_gas, block_hash
+		bytes32, // transactions_root
+	}
+	if fork.AtLeast(model.Capella) {
+		fields = append(fields, bytes32) // withdrawals_root
+	}
+	if fork.AtLeast(model.Deneb) {
+		fields = append(fields, uint64T, uint64T) // blob_gas_used, excess_blob_gas
+	}
+	return container{fields: fields}
+}
+
+/*
+This function returns the BeaconState type of a fork. Altair replaced the pending attestations with
+participation flags, and every later fork appends its fields to the state of the one before
+*/
+func beaconStateType(fork model.ForkVersion) (container, error) {
+	if _, err := model.ParseForkVersion(string(fork)); err != nil {
+		return container{}, fmt.Errorf("%w: %q", ErrUnsupportedFork, fork)
+	}
+	fields := []sszType{
+		uint64T, bytes32, uint64T, forkType, // genesis_time, genesis_validators_root, slot, fork
+		headerType,
+		vector{elem: bytes32, length: slotsPerHistoricalRoot}, // block_roots
+		vector{elem: bytes32, length: slotsPerHistoricalRoot}, // state_roots
+		list{elem: bytes32, limit: historicalRootsLimit},
+		eth1DataType,
+		list{elem: eth1DataType, limit: eth1DataVotesLimit},
+		uint64T, // eth1_deposit_index
+		list{elem: validatorType, limit: validatorRegistryLimit},
+		list{elem: uint64T, limit: validatorRegistryLimit},       // balances
+		vector{elem: bytes32, length: epochsPerHistoricalVector}, // randao_mixes
+		vector{elem: uint64T, length: epochsPerSlashingsVector},  // slashings
+	}
+	if fork == model.Phase0 {
+		fields = append(fields,
+			list{elem: pendingAttestationType, limit: maxPendingAttestationsState},
+			list{elem: pendingAttestationType, limit: maxPendingAttestationsState})
+	} else {
+		fields = append(fields,
+			list{elem: byteType{}, limit: validatorRegistryLimit},
+			list{elem: byteType{}, limit: validatorRegistryLimit})
+	}
+	fields = append(fields, bitvector{bits: justificationBitsLength}, checkpointType, checkpointType, checkpointType)
+	if fork == model.Phase0 {
+		return container{fields: fields}, nil
+	}
+	// inactivity_scores, current_sync_committee and next_sync_committee
+	fields = append(fields, list{elem: uint64T, limit: validatorRegistryLimit}, syncCommitteeType, syncCommitteeType)
+	if fork.AtLeast(model.Bellatrix) {
+		fields = append(fields, executionPayloadHeader(fork))
+	}
+	if fork.AtLeast(model.Capella) {
+		// next_withdrawal_index, next_withdrawal_validator_index and historical_summaries
+		fields = append(fields, uint64T, uint64T, list{elem: container{fields: []sszType{bytes32, bytes32}}, limit: historicalRootsLimit})
+	}
+	if fork.AtLeast(model.Electra) {
+		fields = append(fields,
+			// deposit_requests_start_index, deposit_balance_to_consume, exit_balance_to_consume,
+			// earliest_exit_epoch, consolidation_balance_to_consume and earliest_consolidation_epoch
+			uint64T, uint64T, uint64T, uint64T, uint64T, uint64T,
+			list{elem: container{fields: []sszType{bytes48, bytes32, uint64T, bytes96, uint64T}}, limit: pendingDepositsLimit},
+			list{elem: container{fields: []sszType{uint64T, uint64T, uint64T}}, limit: pendingPartialWithdrawalsLimit},
+			list{elem: container{fields: []sszType{uint64T, uint64T}}, limit: pendingConsolidationsLimit})
+	}
+	if fork.AtLeast(model.Fulu) {
+		fields = append(fields, vector{elem: uint64T, length: proposerLookaheadSlots})
+	}
+	return container{fields: fields}, nil
+}
+
+/*
+This function returns the hash tree root of an SSZ encoded BeaconState of the given fork, the state root a
+block header commits to
+*/
+func StateRoot(state []byte, fork model.ForkVersion) ([32]byte, error) {
+	stateType, err := beaconStateType(fork)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return stateType.hashTreeRoot(state)
+}
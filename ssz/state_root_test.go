@@ -0,0 +1,180 @@
+package ssz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"go-beacon-chain-indexer/model"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+/*
+The mainnet genesis state, as stored in prysm v5.0.0 at beacon-chain/db/kv/testdata/mainnet.genesis.ssz. Its
+root is the state_root of the header of slot 0 served by /eth/v1/beacon/headers/0 of any mainnet beacon node
+*/
+func readGenesisState(t *testing.T) []byte {
+	t.Helper()
+	file, err := os.Open("testdata/mainnet_genesis_state.ssz.zst")
+	if err != nil {
+		t.Fatalf("failed to open the genesis state: %v", err)
+	}
+	defer file.Close()
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer decoder.Close()
+	state, err := io.ReadAll(decoder)
+	if err != nil {
+		t.Fatalf("failed to read the genesis state: %v", err)
+	}
+	return state
+}
+
+func TestMainnetGenesisStateRoot(t *testing.T) {
+	state := readGenesisState(t)
+	root, err := StateRoot(state, model.Phase0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := "0x" + hex.EncodeToString(root[:]); got != "0x7e76880eb67bbdc86250aa578958e9d0675e64e714337855204fb5abaaf82c2b" {
+		t.Errorf("unexpected root of the mainnet genesis state %s", got)
+	}
+	validators, err := StateValidators(state)
+	if err != nil || len(validators) != 21063 {
+		t.Errorf("expected the 21063 genesis validators, got %d: %v", len(validators), err)
+	}
+	if _, err = StateRoot(state, model.Altair); err == nil {
+		t.Errorf("expected a phase0 state to fail as an altair state")
+	}
+}
+
+/*
+encodeRandom encodes a value of the type with every list holding a few elements. Hashing it checks the state
+types field by field against other implementations, the expected roots of the states of every fork were
+computed by deserializing these encodings with protolambda/zrnt v0.34.1 and, up to deneb, prysm v5.0.0. Neither
+has fulu types, the fulu state is checked against real mainnet states by TestMainnetTrustedRegistry
+*/
+func encodeRandom(t sszType, random *rand.Rand) []byte {
+	switch t := t.(type) {
+	case uint64Type, bytesVector:
+		b := make([]byte, t.fixedSize())
+		random.Read(b)
+		return b
+	case byteType:
+		// Booleans only take 0 and 1
+		return []byte{byte(random.Intn(2))}
+	case byteList:
+		b := make([]byte, random.Intn(t.limit+1))
+		random.Read(b)
+		return b
+	case bitvector:
+		b := make([]byte, t.fixedSize())
+		random.Read(b)
+		if t.bits%8 != 0 {
+			b[len(b)-1] &= 1<<uint(t.bits%8) - 1
+		}
+		return b
+	case bitlistType:
+		length := random.Intn(20) + 1
+		b := make([]byte, length/8+1)
+		random.Read(b)
+		b[len(b)-1] &= 1<<uint(length%8) - 1
+		b[len(b)-1] |= 1 << uint(length%8)
+		return b
+	case vector:
+		var b []byte
+		for i := 0; i < t.length; i++ {
+			b = append(b, encodeRandom(t.elem, random)...)
+		}
+		return b
+	case list:
+		elements := make([][]byte, 3)
+		for i := range elements {
+			elements[i] = encodeRandom(t.elem, random)
+		}
+		return encodeParts(elements, t.elem.fixedSize() == 0)
+	case container:
+		fixed, variable := []byte{}, [][]byte{}
+		size := 0
+		for _, field := range t.fields {
+			if field.fixedSize() == 0 {
+				size += BytesPerLengthOffset
+			} else {
+				size += field.fixedSize()
+			}
+		}
+		var offsets []int
+		for _, field := range t.fields {
+			value := encodeRandom(field, random)
+			if field.fixedSize() != 0 {
+				fixed = append(fixed, value...)
+				continue
+			}
+			offsets = append(offsets, len(fixed))
+			fixed = append(fixed, 0, 0, 0, 0)
+			variable = append(variable, value)
+		}
+		position := size
+		for i, value := range variable {
+			binary.LittleEndian.PutUint32(fixed[offsets[i]:], uint32(position))
+			position += len(value)
+		}
+		return append(fixed, bytes.Join(variable, nil)...)
+	}
+	panic("unknown type")
+}
+
+// encodeParts concatenates the elements of a list, prefixed with their offsets when they are variable size
+func encodeParts(elements [][]byte, variable bool) []byte {
+	if !variable {
+		return bytes.Join(elements, nil)
+	}
+	var b []byte
+	position := len(elements) * BytesPerLengthOffset
+	for _, element := range elements {
+		offset := make([]byte, BytesPerLengthOffset)
+		binary.LittleEndian.PutUint32(offset, uint32(position))
+		b = append(b, offset...)
+		position += len(element)
+	}
+	return append(b, bytes.Join(elements, nil)...)
+}
+
+func randomState(t *testing.T, position int) (model.ForkVersion, []byte) {
+	fork, err := model.ForkAt(position)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateType, err := beaconStateType(fork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fork, encodeRandom(stateType, rand.New(rand.NewSource(int64(position))))
+}
+
+func TestStateRootOfEveryFork(t *testing.T) {
+	for position, expected := range []string{
+		"4340f024f82e2159eda94aa6d98a7ba9340c73aeaac8a0ece2536ef2b62b736c",
+		"b8245457135ddec0b942e0ee74c8a58433780646af2ffd3a5786da58b77c6caf",
+		"59fc0fa6241d8ef4fea199d91ee669172466e7a08ae7a29dba9845358f6b00ad",
+		"fc5ac96ce274498ea2e14433417a10361fee30ce2704f9226e191874c8f331b6",
+		"08f35edf5d6405ebeb25463a386fc80d8f86090c675eca22e8770c142958fc2a",
+		"a1481ac0e76822c286800cd6d01fb55e78ba001b94a2b2037cb4c9df4cc87a23",
+	} {
+		fork, state := randomState(t, position)
+		root, err := StateRoot(state, fork)
+		if err != nil {
+			t.Errorf("%s: %v", fork, err)
+			continue
+		}
+		if got := hex.EncodeToString(root[:]); got != expected {
+			t.Errorf("%s: state root %s, expected %s", fork, got, expected)
+		}
+	}
+}
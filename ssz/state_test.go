@@ -0,0 +1,50 @@
+package ssz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+/*
+The state is cut down to the prefix the reader needs followed by a registry of one validator, with the
+block root of every slot and the randao mix of every epoch set to its own number
+*/
+func TestStateHistoryReader(t *testing.T) {
+	const slot = 3 * slotsPerHistoricalRoot
+	state := make([]byte, stateRandaoMixesPosition+epochsPerHistoricalVector*32+ValidatorSize)
+	binary.LittleEndian.PutUint64(state[stateSlotPosition:], slot)
+	for i := uint64(0); i < slotsPerHistoricalRoot; i++ {
+		binary.LittleEndian.PutUint64(state[stateBlockRootsPosition+i*32:], 2*slotsPerHistoricalRoot+i)
+	}
+	for i := uint64(0); i < epochsPerHistoricalVector; i++ {
+		binary.LittleEndian.PutUint64(state[stateRandaoMixesPosition+i*32:], i)
+	}
+	registryStart := len(state) - ValidatorSize
+	binary.LittleEndian.PutUint32(state[stateValidatorsOffsetPosition:], uint32(registryStart))
+	binary.LittleEndian.PutUint32(state[stateBalancesOffsetPosition:], uint32(len(state)))
+	binary.LittleEndian.PutUint64(state[registryStart+97:], 7)
+
+	history, registry, err := NewStateHistoryReader(bytes.NewReader(state))
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	root, err := history.BlockRoot(slot - 1)
+	if err != nil || binary.LittleEndian.Uint64(root[:]) != slot-1 {
+		t.Errorf("unexpected block root %x of the slot before the state: %v", root, err)
+	}
+	if _, err = history.BlockRoot(slot); err == nil {
+		t.Error("expected no block root for the slot of the state")
+	}
+	if _, err = history.BlockRoot(slot - slotsPerHistoricalRoot - 1); err == nil {
+		t.Error("expected no block root for a slot the state no longer keeps")
+	}
+	mix, err := history.RandaoMix(slot/slotsPerEpoch - 2)
+	if err != nil || binary.LittleEndian.Uint64(mix[:]) != slot/slotsPerEpoch-2 {
+		t.Errorf("unexpected randao mix %x: %v", mix, err)
+	}
+	validator, err := registry.Next()
+	if err != nil || registry.Count != 1 || validator.ActivationEpoch != 7 {
+		t.Errorf("unexpected registry of %d validators, first %+v: %v", registry.Count, validator, err)
+	}
+}